@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/seanomeara96/gates/migrations"
+)
+
+const usage = `usage: migrate [-db path] <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    roll back the last n migrations (default 1)
+  status      list migrations and whether they have been applied
+  verify      check applied migrations against their embedded checksums
+`
+
+func run() error {
+	defaultPath := os.Getenv("DB_FILE_PATH")
+	if defaultPath == "" {
+		defaultPath = "main.db"
+	}
+
+	dbPath := flag.String("db", defaultPath, "path to the sqlite database file")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := sql.Open("sqlite3", *dbPath)
+	if err != nil {
+		return fmt.Errorf("open db (path=%s): %w", *dbPath, err)
+	}
+	defer db.Close()
+
+	m, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	switch cmd := flag.Arg(0); cmd {
+	case "up":
+		ran, err := m.Up()
+		for _, migration := range ran {
+			log.Printf("applied %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			log.Println("database is up to date")
+		}
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			steps, err = strconv.Atoi(flag.Arg(1))
+			if err != nil {
+				return fmt.Errorf("parse down steps %q: %w", flag.Arg(1), err)
+			}
+		}
+		reverted, err := m.Down(steps)
+		for _, migration := range reverted {
+			log.Printf("reverted %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.Applied {
+				fmt.Printf("%04d_%-30s applied %s\n", s.Version, s.Name, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("%04d_%-30s pending\n", s.Version, s.Name)
+			}
+		}
+	case "verify":
+		if err := m.Verify(); err != nil {
+			return err
+		}
+		log.Println("all applied migrations match")
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/seanomeara96/gates/config"
+	"github.com/seanomeara96/gates/handlers"
+)
+
+const usage = `usage: reconcile
+
+Checks every order still waiting on its payment against the payment provider
+and applies whatever webhooks were missed: paid orders move on and take their
+stock, orders whose checkout session closed unpaid are canceled. Reads the
+same .env as the server.
+`
+
+func run() error {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	h, err := handlers.DefaultHandler(cfg)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results, err := h.ReconcileUnsettledOrders(ctx)
+	changed := 0
+	for _, result := range results {
+		if result.Changed() {
+			changed++
+			log.Printf("order %d: %s -> %s (payment %s)", result.OrderID, result.From, result.To, result.PaymentStatus)
+		}
+	}
+	log.Printf("checked %d orders, %d changed", len(results), changed)
+	return err
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/seanomeara96/gates/models"
 	"github.com/spf13/viper"
 )
 
@@ -15,18 +16,35 @@ const (
 	Production  Environment = "production"
 )
 
+type PaymentProvider string
+
+const (
+	PaymentProviderStripe PaymentProvider = "stripe"
+	// PaymentProviderFake takes payments through a checkout page served by the
+	// app itself, so a purchase can be run end to end without Stripe keys.
+	PaymentProviderFake PaymentProvider = "fake"
+)
+
 type Config struct {
-	Port                 string      `mapstructure:"PORT"`
-	Domain               string      `mapstructure:"DOMAIN"`
-	Mode                 Environment `mapstructure:"MODE"`
-	DBPath               string      `mapstructure:"DB_FILE_PATH"`
-	CookieStoreSecretKey string      `mapstructure:"COOKIE_SECRET"`
-	AdminUserID          string      `mapstructure:"ADMIN_USER_ID"`
-	AdminUserPassword    string      `mapstructure:"ADMIN_USER_PASSWORD"`
-	JWTSecretKey         string      `mapstructure:"JWT_SECRET_KEY"`
-	StripeWebhookSecret  string      `mapstructure:"STRIPE_WEBHOOK_SECRET"`
-	StripeAPIKey         string      `mapstructure:"STRIPE_API_KEY"`
-	UseTempl             bool        `mapstructure:"USE_TEMPL"`
+	Port                 string          `mapstructure:"PORT"`
+	Domain               string          `mapstructure:"DOMAIN"`
+	Mode                 Environment     `mapstructure:"MODE"`
+	DBPath               string          `mapstructure:"DB_FILE_PATH"`
+	CookieStoreSecretKey string          `mapstructure:"COOKIE_SECRET"`
+	AdminUserID          string          `mapstructure:"ADMIN_USER_ID"`
+	AdminUserPassword    string          `mapstructure:"ADMIN_USER_PASSWORD"`
+	JWTSecretKey         string          `mapstructure:"JWT_SECRET_KEY"`
+	StripeWebhookSecret  string          `mapstructure:"STRIPE_WEBHOOK_SECRET"`
+	StripeAPIKey         string          `mapstructure:"STRIPE_API_KEY"`
+	UseTempl             bool            `mapstructure:"USE_TEMPL"`
+	PaymentProvider      PaymentProvider `mapstructure:"PAYMENT_PROVIDER"`
+	// MultiGateDiscountPercent is taken off every gate once a cart holds
+	// MultiGateDiscountMinGates of them. Left at 0 there is no discount.
+	MultiGateDiscountPercent  int `mapstructure:"MULTI_GATE_DISCOUNT_PERCENT"`
+	MultiGateDiscountMinGates int `mapstructure:"MULTI_GATE_DISCOUNT_MIN_GATES"`
+	// ClickAndCollectAddress is where customers can collect orders from,
+	// shown to them at checkout. Left empty click and collect is not offered.
+	ClickAndCollectAddress string `mapstructure:"CLICK_AND_COLLECT_ADDRESS"`
 }
 
 func Load() (*Config, error) {
@@ -37,6 +55,10 @@ func Load() (*Config, error) {
 	viper.AutomaticEnv()
 
 	viper.SetDefault("DB_FILE_PATH", "main.db")
+	viper.SetDefault("PAYMENT_PROVIDER", string(PaymentProviderStripe))
+	viper.SetDefault("MULTI_GATE_DISCOUNT_PERCENT", 0)
+	viper.SetDefault("MULTI_GATE_DISCOUNT_MIN_GATES", 2)
+	viper.SetDefault("CLICK_AND_COLLECT_ADDRESS", "")
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -68,11 +90,26 @@ func Load() (*Config, error) {
 	if config.AdminUserPassword == "" {
 		errs = append(errs, errors.New("env ADMIN_USER_PASSWORD not set"))
 	}
-	if config.StripeAPIKey == "" {
-		errs = append(errs, errors.New("env STRIPE_API_KEY not set"))
+	switch config.PaymentProvider {
+	case PaymentProviderStripe:
+		if config.StripeAPIKey == "" {
+			errs = append(errs, errors.New("env STRIPE_API_KEY not set"))
+		}
+		if config.StripeWebhookSecret == "" {
+			errs = append(errs, errors.New("env STRIPE_WEBHOOK_SECRET not set"))
+		}
+	case PaymentProviderFake:
+		if config.Mode != Development {
+			errs = append(errs, fmt.Errorf("env PAYMENT_PROVIDER %s is only allowed in %s mode", PaymentProviderFake, Development))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("env PAYMENT_PROVIDER must be one of %s or %s", PaymentProviderStripe, PaymentProviderFake))
+	}
+	if config.MultiGateDiscountPercent < 0 || config.MultiGateDiscountPercent >= 100 {
+		errs = append(errs, errors.New("env MULTI_GATE_DISCOUNT_PERCENT must be from 0 to 99"))
 	}
-	if config.StripeWebhookSecret == "" {
-		errs = append(errs, errors.New("env STRIPE_WEBHOOK_SECRET not set"))
+	if config.MultiGateDiscountMinGates < 2 {
+		errs = append(errs, errors.New("env MULTI_GATE_DISCOUNT_MIN_GATES must be at least 2"))
 	}
 	if config.CookieStoreSecretKey == "" {
 		errs = append(errs, errors.New("env COOKIE_SECRET not set"))
@@ -91,3 +128,8 @@ func Load() (*Config, error) {
 
 	return &config, nil
 }
+
+// MultiGateDiscount is the discount configured for carts with several gates.
+func (c *Config) MultiGateDiscount() models.MultiGateDiscount {
+	return models.MultiGateDiscount{MinGates: c.MultiGateDiscountMinGates, Percent: c.MultiGateDiscountPercent}
+}
@@ -0,0 +1,592 @@
+package handlers
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/repos"
+)
+
+// APIPrefix is where version 1 of the JSON API is served.
+const APIPrefix = "/api/v1"
+
+// cartTokenHeader carries the token of the cart an API request works on. The
+// token is the cart's id, as returned when the cart is created.
+const cartTokenHeader = "X-Cart-Token"
+
+// maxAPIProducts caps how many products one API request lists.
+const maxAPIProducts = 100
+
+// APIError is an error the API client can do something about. It is sent in
+// the error envelope with Status.
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func apiErrorf(status int, code, format string, args ...any) *APIError {
+	return &APIError{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// apiEnvelope is the body of every API response but the OpenAPI document:
+// data on success, error otherwise.
+type apiEnvelope struct {
+	Data  any       `json:"data,omitempty"`
+	Error *APIError `json:"error,omitempty"`
+}
+
+// APIHandleFunc handles an API request. What it returns is sent as data in
+// the envelope; an *APIError is sent as the error, any other error as an
+// internal one.
+type APIHandleFunc func(r *http.Request) (any, error)
+
+// APIParam is a path, query or header parameter of an API route.
+type APIParam struct {
+	Name        string
+	In          string // path, query or header
+	Type        string // string, integer or number
+	Enum        []string
+	Required    bool
+	Description string
+}
+
+// APIRoute is an API endpoint. Everything but Handle goes into its entry in
+// the OpenAPI document.
+type APIRoute struct {
+	Method   string
+	Path     string // after APIPrefix
+	Summary  string
+	Params   []APIParam
+	Request  any // zero value of the JSON body, nil if there is none
+	Response any // zero value of the data sent on success
+	Status   int // sent on success, http.StatusOK if not set
+	Handle   APIHandleFunc
+}
+
+// apiBundle is a bundle the builder found, with the key to add it to a cart.
+type apiBundle struct {
+	models.Bundle
+	Key      string  `json:"key"`
+	MinWidth float32 `json:"min_width"`
+	MaxWidth float32 `json:"max_width"`
+}
+
+type apiBuildResult struct {
+	Width        float32             `json:"width"`
+	MountingType models.MountingType `json:"mounting_type"`
+	Goal         models.BundleGoal   `json:"goal"`
+	Bundles      []apiBundle         `json:"bundles"`
+	Problem      string              `json:"problem,omitempty"` // why there are no bundles
+}
+
+// apiCart is a cart with its totals. Its id is the token for X-Cart-Token.
+type apiCart struct {
+	models.Cart
+	DiscountTotal models.Money `json:"discount_total"`
+	AmountDue     models.Money `json:"amount_due"`
+	// Total is AmountDue with the chosen shipping, and VAT the VAT included
+	// in it.
+	Total models.Money `json:"total"`
+	VAT   models.Money `json:"vat"`
+}
+
+type apiCartItemRequest struct {
+	Key string `json:"key"` // as in a bundle from the builder
+	Qty int    `json:"qty"` // 1 if not set
+}
+
+type apiCartItemQtyRequest struct {
+	Qty int `json:"qty"`
+}
+
+var (
+	productIDParam = APIParam{Name: "id", In: "path", Type: "integer", Required: true}
+	cartTokenParam = APIParam{Name: cartTokenHeader, In: "header", Type: "string", Required: true, Description: "The id of a cart made with POST /carts."}
+	cartItemParam  = APIParam{Name: "item_id", In: "path", Type: "string", Required: true}
+)
+
+// APIRoutes lists the endpoints of the JSON API.
+func (h *Handler) APIRoutes() []APIRoute {
+	return []APIRoute{
+		{
+			Method:  http.MethodGet,
+			Path:    "/products",
+			Summary: "List products",
+			Params: []APIParam{
+				{Name: "type", In: "query", Type: "string", Enum: enumValues(models.ProductTypeGate, models.ProductTypeExtension, models.ProductTypeBundle)},
+				{Name: "mounting_type", In: "query", Type: "string", Enum: enumValues(models.MountingTypes...)},
+				{Name: "color", In: "query", Type: "string"},
+				{Name: "max_width", In: "query", Type: "number", Description: "Only products narrower than this, in cm."},
+				{Name: "max_price", In: "query", Type: "string", Description: "Only products that cost at most this, e.g. 49.99."},
+				{Name: "min_stock", In: "query", Type: "integer", Description: "Only products with at least this many in stock."},
+				{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("At most %d, which is also the default.", maxAPIProducts)},
+			},
+			Response: []models.Product{},
+			Handle:   h.apiListProducts,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/products/{id}",
+			Summary:  "Get a product",
+			Params:   []APIParam{productIDParam},
+			Response: models.Product{},
+			Handle:   h.apiGetProduct,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/products/{id}/extensions",
+			Summary:  "List the extensions that fit a gate",
+			Params:   []APIParam{productIDParam},
+			Response: []models.Product{},
+			Handle:   h.apiGetCompatibleExtensions,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/bundles",
+			Summary: "Build bundles of a gate and extensions to fit an opening",
+			Params: []APIParam{
+				{Name: "width", In: "query", Type: "number", Required: true, Description: "Width of the opening in cm."},
+				{Name: "mounting_type", In: "query", Type: "string", Enum: enumValues(models.MountingTypes...), Description: "pressure_fit if not set."},
+				{Name: "goal", In: "query", Type: "string", Enum: enumValues(models.BundleGoals...), Description: "price if not set."},
+				{Name: "alternatives", In: "query", Type: "integer", Description: fmt.Sprintf("Bundles per gate, %d to %d. %d if not set.", 1, maxBundleAlternatives, defaultBundleAlternatives)},
+				{Name: cartTokenHeader, In: "header", Type: "string", Description: "Optional. The cart the bundles are for."},
+			},
+			Response: apiBuildResult{},
+			Handle:   h.apiBuildBundles,
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/carts",
+			Summary:  "Start a cart",
+			Response: apiCart{},
+			Status:   http.StatusCreated,
+			Handle:   h.apiCreateCart,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/cart",
+			Summary:  "Get the cart",
+			Params:   []APIParam{cartTokenParam},
+			Response: apiCart{},
+			Handle:   h.apiGetCart,
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/cart/items",
+			Summary:  "Add a bundle to the cart",
+			Params:   []APIParam{cartTokenParam},
+			Request:  apiCartItemRequest{},
+			Response: apiCart{},
+			Handle:   h.apiAddCartItem,
+		},
+		{
+			Method:   http.MethodDelete,
+			Path:     "/cart/items",
+			Summary:  "Empty the cart",
+			Params:   []APIParam{cartTokenParam},
+			Response: apiCart{},
+			Handle:   h.apiClearCart,
+		},
+		{
+			Method:   http.MethodPut,
+			Path:     "/cart/items/{item_id}",
+			Summary:  "Change how many of an item are in the cart",
+			Params:   []APIParam{cartTokenParam, cartItemParam},
+			Request:  apiCartItemQtyRequest{},
+			Response: apiCart{},
+			Handle:   h.apiSetCartItemQty,
+		},
+		{
+			Method:   http.MethodDelete,
+			Path:     "/cart/items/{item_id}",
+			Summary:  "Take an item out of the cart",
+			Params:   []APIParam{cartTokenParam, cartItemParam},
+			Response: apiCart{},
+			Handle:   h.apiRemoveCartItem,
+		},
+	}
+}
+
+// ServeAPI serves route, wrapping what it returns in the envelope.
+func (h *Handler) ServeAPI(route APIRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := route.Handle(r)
+
+		status := cmp.Or(route.Status, http.StatusOK)
+		var body apiEnvelope
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr):
+			status, body.Error = apiErr.Status, apiErr
+		case err != nil:
+			log.Printf("[ERROR] Failed %s request to %s. %v", r.Method, r.URL.Path, err)
+			status, body.Error = http.StatusInternalServerError, &APIError{Code: "internal", Message: "Something went wrong on our side."}
+		default:
+			body.Data = data
+		}
+		writeJSON(w, status, body)
+	}
+}
+
+// APINotFound answers requests for API endpoints that don't exist.
+func (h *Handler) APINotFound(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusNotFound, apiEnvelope{Error: apiErrorf(http.StatusNotFound, "not_found", "There is no %s %s.", r.Method, r.URL.Path)})
+}
+
+// ServeOpenAPI serves the OpenAPI document for the API.
+func (h *Handler) ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, OpenAPI(h.APIRoutes()))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("[ERROR] write json response: %v", err)
+	}
+}
+
+func (h *Handler) apiListProducts(r *http.Request) (any, error) {
+	q := r.URL.Query()
+	params := repos.ProductFilterParams{Color: q.Get("color"), Limit: maxAPIProducts}
+
+	if v := q.Get("type"); v != "" {
+		params.Type = models.ProductType(v)
+		if !validProductTypes[params.Type] {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "type must be gate, extension or bundle.")
+		}
+	}
+	if v := q.Get("mounting_type"); v != "" {
+		params.MountingType = models.MountingType(v)
+		if !params.MountingType.IsValid() {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "mounting_type must be pressure_fit or hardware_fit.")
+		}
+	}
+	if v := q.Get("max_width"); v != "" {
+		width, err := strconv.ParseFloat(v, 32)
+		if err != nil || !isOpeningWidth(width) {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "max_width must be a width in cm.")
+		}
+		params.MaxWidth = float32(width)
+	}
+	if v := q.Get("max_price"); v != "" {
+		price, err := models.ParseMoney(v, models.DefaultCurrency)
+		if err != nil || price.Amount <= 0 {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "max_price must be an amount like 49.99.")
+		}
+		params.Price = price
+	}
+	if v := q.Get("min_stock"); v != "" {
+		stock, err := strconv.Atoi(v)
+		if err != nil || stock < 0 {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "min_stock must be a whole number.")
+		}
+		params.InventoryLevel = stock
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAPIProducts {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "limit must be from 1 to %d.", maxAPIProducts)
+		}
+		params.Limit = limit
+	}
+
+	products, err := h.productCache.GetProducts(params)
+	if err != nil {
+		return nil, fmt.Errorf("api list products: %w", err)
+	}
+	return nonNil(products), nil
+}
+
+func (h *Handler) apiGetProduct(r *http.Request) (any, error) {
+	return h.apiProduct(r)
+}
+
+func (h *Handler) apiGetCompatibleExtensions(r *http.Request) (any, error) {
+	gate, err := h.apiProduct(r)
+	if err != nil {
+		return nil, err
+	}
+	if gate.Type != models.ProductTypeGate {
+		return nil, apiErrorf(http.StatusNotFound, "not_found", "Product %d is not a gate.", gate.Id)
+	}
+
+	extensions, err := h.productCache.GetCompatibleExtensionsByGateID(gate.Id)
+	if err != nil {
+		return nil, fmt.Errorf("api get compatible extensions (gateId=%d): %w", gate.Id, err)
+	}
+	return nonNil(extensions), nil
+}
+
+// apiProduct is the product in the id path parameter.
+func (h *Handler) apiProduct(r *http.Request) (models.Product, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return models.Product{}, apiErrorf(http.StatusNotFound, "not_found", "There is no product %q.", r.PathValue("id"))
+	}
+	product, err := h.productCache.GetProductByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Product{}, apiErrorf(http.StatusNotFound, "not_found", "There is no product %d.", id)
+	}
+	if err != nil {
+		return models.Product{}, fmt.Errorf("api get product (id=%d): %w", id, err)
+	}
+	return product, nil
+}
+
+func (h *Handler) apiBuildBundles(r *http.Request) (any, error) {
+	q := r.URL.Query()
+
+	width, err := strconv.ParseFloat(q.Get("width"), 32)
+	if err != nil || !isOpeningWidth(width) {
+		return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "width must be the width of the opening in cm.")
+	}
+	result := apiBuildResult{
+		Width:        float32(width),
+		MountingType: models.MountingTypePressureFit,
+		Goal:         models.BundleGoalPrice,
+		Bundles:      []apiBundle{},
+	}
+
+	if v := q.Get("mounting_type"); v != "" {
+		result.MountingType = models.MountingType(v)
+		if !result.MountingType.IsValid() {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "mounting_type must be pressure_fit or hardware_fit.")
+		}
+	}
+	if v := q.Get("goal"); v != "" {
+		result.Goal = models.BundleGoal(v)
+		if !result.Goal.IsValid() {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "goal must be price, pieces or fit.")
+		}
+	}
+	alternatives := defaultBundleAlternatives
+	if v := q.Get("alternatives"); v != "" {
+		alternatives, err = strconv.Atoi(v)
+		if err != nil || alternatives < 1 || alternatives > maxBundleAlternatives {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid_parameter", "alternatives must be from 1 to %d.", maxBundleAlternatives)
+		}
+	}
+
+	// The cart token is optional here. With it, the request counts towards
+	// the cart's conversion on the demand page.
+	cartID := r.Header.Get(cartTokenHeader)
+	if cartID != "" {
+		exists, err := h.cartRepo.CartExists(cartID)
+		if err != nil {
+			return nil, fmt.Errorf("api build bundles: %w", err)
+		}
+		if !exists {
+			cartID = ""
+		}
+	}
+
+	bundles, problem, err := h.buildBundles(cartID, result.MountingType, result.Width, result.Goal, alternatives)
+	if err != nil {
+		return nil, fmt.Errorf("api build bundles: %w", err)
+	}
+	for _, bundle := range bundles {
+		result.Bundles = append(result.Bundles, apiBundle{
+			Bundle:   bundle,
+			Key:      models.CartItemKey(bundle.Components),
+			MinWidth: bundle.MinWidth(),
+			MaxWidth: bundle.MaxWidth(),
+		})
+	}
+	result.Problem = problem
+	return result, nil
+}
+
+func (h *Handler) apiCreateCart(r *http.Request) (any, error) {
+	cart, err := h.newCart()
+	if err != nil {
+		return nil, fmt.Errorf("api create cart: %w", err)
+	}
+	return h.apiCartByID(cart.ID)
+}
+
+func (h *Handler) apiGetCart(r *http.Request) (any, error) {
+	cart, err := h.apiCartFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.reviewCart(&cart); err != nil {
+		return nil, fmt.Errorf("api get cart: %w", err)
+	}
+	return toAPICart(cart), nil
+}
+
+func (h *Handler) apiAddCartItem(r *http.Request) (any, error) {
+	cart, err := h.apiCartFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	var body apiCartItemRequest
+	if err := decodeAPIBody(r, &body); err != nil {
+		return nil, err
+	}
+	body.Qty = cmp.Or(body.Qty, 1)
+	if body.Qty < 1 || body.Qty > maxCartItemQty {
+		return nil, apiErrorf(http.StatusBadRequest, "invalid_body", "qty must be from 1 to %d.", maxCartItemQty)
+	}
+
+	components, err := h.cartItemKeyProducts(body.Key)
+	if errors.Is(err, errInvalidCartItem) {
+		return nil, apiErrorf(http.StatusBadRequest, "invalid_key", "key must be a gate followed by extensions that fit it, e.g. 1-1_5-4, or one product, e.g. 5-1 (%v).", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("api add cart item (cartID=%s): %w", cart.ID, err)
+	}
+	item := bundleCartItem(cart.ID, components)
+	item.Qty = body.Qty
+	if err := AddItemToCart(h.cartRepo, cart.ID, item); err != nil {
+		return nil, fmt.Errorf("api add cart item: %w", err)
+	}
+	h.markAddedToCart(cart.ID, item)
+
+	return h.apiCartByID(cart.ID)
+}
+
+func (h *Handler) apiSetCartItemQty(r *http.Request) (any, error) {
+	cart, itemID, err := h.apiCartItemFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	var body apiCartItemQtyRequest
+	if err := decodeAPIBody(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Qty < 1 {
+		return nil, apiErrorf(http.StatusBadRequest, "invalid_body", "qty must be at least 1. To take the item out, DELETE it.")
+	}
+	if body.Qty > maxCartItemQty {
+		return nil, apiErrorf(http.StatusBadRequest, "invalid_body", "qty must be at most %d.", maxCartItemQty)
+	}
+
+	if err := h.cartRepo.SetCartItemQty(cart.ID, itemID, body.Qty); err != nil {
+		return nil, fmt.Errorf("api set cart item qty: %w", err)
+	}
+	if err := h.cartRepo.SetLastUpdated(cart.ID); err != nil {
+		return nil, fmt.Errorf("api set cart item qty: %w", err)
+	}
+	return h.apiCartByID(cart.ID)
+}
+
+func (h *Handler) apiRemoveCartItem(r *http.Request) (any, error) {
+	cart, itemID, err := h.apiCartItemFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.cartRepo.RemoveCartItem(cart.ID, itemID); err != nil {
+		return nil, fmt.Errorf("api remove cart item: %w", err)
+	}
+	if err := h.cartRepo.SetLastUpdated(cart.ID); err != nil {
+		return nil, fmt.Errorf("api remove cart item: %w", err)
+	}
+	return h.apiCartByID(cart.ID)
+}
+
+func (h *Handler) apiClearCart(r *http.Request) (any, error) {
+	cart, err := h.apiCartFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.cartRepo.ClearCart(cart.ID); err != nil {
+		return nil, fmt.Errorf("api clear cart: %w", err)
+	}
+	if err := h.cartRepo.SetLastUpdated(cart.ID); err != nil {
+		return nil, fmt.Errorf("api clear cart: %w", err)
+	}
+	return h.apiCartByID(cart.ID)
+}
+
+// apiCartFromRequest is the cart whose token is in the X-Cart-Token header.
+func (h *Handler) apiCartFromRequest(r *http.Request) (models.Cart, error) {
+	token := r.Header.Get(cartTokenHeader)
+	if token == "" {
+		return models.Cart{}, apiErrorf(http.StatusUnauthorized, "missing_cart_token", "Send the cart's id in the %s header. Start a cart with POST %s/carts.", cartTokenHeader, APIPrefix)
+	}
+	cart, found, err := h.cartRepo.GetCartByID(token)
+	if err != nil {
+		return models.Cart{}, fmt.Errorf("api get cart: %w", err)
+	}
+	if !found {
+		return models.Cart{}, apiErrorf(http.StatusNotFound, "cart_not_found", "There is no cart with that token.")
+	}
+	return cart, nil
+}
+
+// apiCartItemFromRequest is the cart from the header and the id of the item
+// in the path, which has to be in it.
+func (h *Handler) apiCartItemFromRequest(r *http.Request) (models.Cart, string, error) {
+	cart, err := h.apiCartFromRequest(r)
+	if err != nil {
+		return models.Cart{}, "", err
+	}
+	itemID := r.PathValue("item_id")
+	exists, err := h.cartRepo.DoesCartItemExist(cart.ID, itemID)
+	if err != nil {
+		return models.Cart{}, "", fmt.Errorf("api get cart item (cartID=%s, itemID=%s): %w", cart.ID, itemID, err)
+	}
+	if !exists {
+		return models.Cart{}, "", apiErrorf(http.StatusNotFound, "item_not_found", "There is no item %q in the cart.", itemID)
+	}
+	return cart, itemID, nil
+}
+
+func (h *Handler) apiCartByID(cartID string) (apiCart, error) {
+	cart, found, err := h.cartRepo.GetCartByID(cartID)
+	if err != nil || !found {
+		return apiCart{}, fmt.Errorf("api get cart (cartID=%s, found=%t): %w", cartID, found, err)
+	}
+	if err := h.reviewCart(&cart); err != nil {
+		return apiCart{}, fmt.Errorf("api get cart (cartID=%s): %w", cartID, err)
+	}
+	return toAPICart(cart), nil
+}
+
+func toAPICart(cart models.Cart) apiCart {
+	cart.Items = nonNil(cart.Items)
+	cart.Discounts = nonNil(cart.Discounts)
+	cart.Warnings = nonNil(cart.Warnings)
+	return apiCart{Cart: cart, DiscountTotal: cart.DiscountTotal(), AmountDue: cart.AmountDue(), Total: cart.Total(), VAT: cart.VAT()}
+}
+
+// decodeAPIBody reads the JSON request body into v.
+func decodeAPIBody(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20)) // 1 MB limit
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return apiErrorf(http.StatusBadRequest, "invalid_body", "The body must be a JSON object: %v.", err)
+	}
+	return nil
+}
+
+func enumValues[T ~string](values ...T) []string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = string(v)
+	}
+	return strs
+}
+
+// nonNil makes sure an empty list is sent as [] rather than null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
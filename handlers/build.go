@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"database/sql"
 	"fmt"
+	"math"
 	"net/http"
-	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/seanomeara96/gates/models"
 	"github.com/seanomeara96/gates/repos"
@@ -13,105 +13,93 @@ import (
 	"github.com/seanomeara96/gates/views/partials"
 )
 
-func BuildPressureFitBundles(products *cache.CachedProductRepo, limit float32) ([]models.Bundle, error) {
+// How many bundles the builder offers per gate unless asked for more or
+// fewer, and the most it will offer.
+const (
+	defaultBundleAlternatives = 3
+	maxBundleAlternatives     = 5
+)
+
+// openingWidthProblem is shown for a width that is not a width at all.
+const openingWidthProblem = "Enter the width of the opening in cm."
+
+// isOpeningWidth reports whether width, as entered, could be the width of an
+// opening: a positive number of cm, not NaN or infinity.
+func isOpeningWidth(width float64) bool {
+	return width > 0 && !math.IsInf(width, 0) && !math.IsNaN(width)
+}
+
+// BuildBundles builds, for every gate of the mounting type that can fit
+// opening, up to n bundles of that gate and its extensions whose fit range
+// contains opening, best first by goal. Gates that cannot get there are left
+// out. Each mounting type is sized its own way, see models.Product.MinWidth.
+func BuildBundles(products *cache.CachedProductRepo, mountingType models.MountingType, opening float32, goal models.BundleGoal, n int) ([]models.Bundle, error) {
 	var bundles []models.Bundle
 
-	gates, err := products.GetProducts(repos.ProductFilterParams{MaxWidth: limit, Type: models.ProductTypeGate})
+	gates, err := products.GetProducts(repos.ProductFilterParams{Type: models.ProductTypeGate, MountingType: mountingType})
 	if err != nil {
-		return bundles, fmt.Errorf("build pressure fit bundles: failed to get gates (maxWidth=%v): %w", limit, err)
-	}
-	if len(gates) < 1 {
-		return bundles, nil
+		return bundles, fmt.Errorf("build bundles: failed to get gates (mountingType=%s, opening=%v): %w", mountingType, opening, err)
 	}
 
 	for _, gate := range gates {
-		compatibleExtensions, err := products.GetCompatibleExtensionsByGateID(gate.Id)
-		if err != nil {
-			return bundles, fmt.Errorf("build pressure fit bundles: failed to get compatible extensions (gateId=%d): %w", gate.Id, err)
+		if gate.MinWidth() > opening {
+			continue
 		}
 
-		bundle, err := BuildPressureFitBundle(limit, gate, compatibleExtensions)
+		compatibleExtensions, err := products.GetCompatibleExtensionsByGateID(gate.Id)
 		if err != nil {
-			return bundles, fmt.Errorf("build pressure fit bundles: failed to build bundle (limit=%v, gateId=%d): %w", limit, gate.Id, err)
+			return bundles, fmt.Errorf("build bundles: failed to get compatible extensions (gateId=%d): %w", gate.Id, err)
 		}
-		bundles = append(bundles, bundle)
+
+		bundles = append(bundles, models.BestBundles(gate, compatibleExtensions, opening, goal, n)...)
 	}
 
 	return bundles, nil
 }
 
-func BuildPressureFitBundle(limit float32, gate models.Product, extensions []models.Product) (models.Bundle, error) {
-	widthLimit := limit
-
-	var bundle = models.Bundle{}
-
-	// returning a single bundle
-	bundle.Qty = 1
-
-	//  add gate to the bundle. Ensure Qty is at least 1
-	if gate.Width > widthLimit {
-		return bundle, fmt.Errorf("build pressure fit bundle: gate too big (gateId=%d, gateWidth=%v, limit=%v): %w", gate.Id, gate.Width, widthLimit, fmt.Errorf("gate too big"))
+// NoBundlesProblem explains to the customer why no bundle of the mounting
+// type fits opening: either no gate can safely span it at all, or none can be
+// extended to exactly that width.
+func NoBundlesProblem(products *cache.CachedProductRepo, mountingType models.MountingType, opening float32) (string, error) {
+	gates, err := products.GetProducts(repos.ProductFilterParams{Type: models.ProductTypeGate, MountingType: mountingType})
+	if err != nil {
+		return "", fmt.Errorf("no bundles problem: failed to get gates (mountingType=%s): %w", mountingType, err)
 	}
-
-	if gate.Qty < 1 {
-		gate.Qty = 1
+	kind := strings.ToLower(mountingType.Label())
+	if len(gates) == 0 {
+		return fmt.Sprintf("We don't have any %s gates yet. Get in touch and we'll help.", kind), nil
 	}
 
-	bundle.Components = append(bundle.Components, gate)
-
-	widthLimit -= gate.Width
-
-	// sort extensions to ensure width descending
-	sort.Slice(extensions, func(i int, j int) bool {
-		return extensions[i].Width > extensions[j].Width
-	})
-
-	extensionIndex := 0
-	for widthLimit > 0 {
-
-		// we want to add one more extension if the width remaining > 0 but we've reached the last extension
-		var override bool = false
-		if extensionIndex >= len(extensions) {
-			extensionIndex--
-			override = true
-		}
-
-		extension := extensions[extensionIndex]
-		if extension.Width > widthLimit && !override {
-			//  extension too big, try next extension size down
-			extensionIndex++
-			continue
-		}
-
-		// check if extension already exists in the bundle and if so, increment the qty, else add it with a qty of 1
-		var existingExtension *models.Product
-		for ii := 1; ii < len(bundle.Components); ii++ {
-			var bundleExtension *models.Product = &bundle.Components[ii]
-
-			if bundleExtension.Id == extension.Id {
-				existingExtension = bundleExtension
-			}
-		}
+	if widest := widestSpan(gates); opening > widest {
+		return fmt.Sprintf("No %s gate can safely span %gcm. The widest opening we can cover is %gcm. Get in touch and we'll help.", kind, opening, widest), nil
+	}
+	return fmt.Sprintf("No %s gate can be extended to fit %gcm. Check the measurement, or get in touch and we'll help.", kind, opening), nil
+}
 
-		if existingExtension != nil {
-			existingExtension.Qty++
-			widthLimit -= existingExtension.Width
-		} else {
-			extension.Qty = 1
-			bundle.Components = append(bundle.Components, extension)
-			widthLimit -= extension.Width
-		}
+// widestSpan is the widest opening any of gates can safely span.
+func widestSpan(gates []models.Product) float32 {
+	var widest float32
+	for _, gate := range gates {
+		widest = max(widest, gate.Span())
 	}
-	bundle.ComputeMetaData()
-	return bundle, nil
+	return widest
 }
 
-func SaveRequestedBundleSize(db *sql.DB, desiredWidth float32) error {
-	_, err := db.Exec("INSERT INTO bundle_sizes (type, size) VALUES ('pressure fit', ?)", desiredWidth)
+// openingProblem is why the builder turns opening down before searching, or
+// "" when it doesn't: it is not a width, or no gate of the mounting type can
+// span it. Openings it turns down are not recorded as demand.
+func openingProblem(products *cache.CachedProductRepo, mountingType models.MountingType, opening float32) (string, error) {
+	if !isOpeningWidth(float64(opening)) {
+		return openingWidthProblem, nil
+	}
+	gates, err := products.GetProducts(repos.ProductFilterParams{Type: models.ProductTypeGate, MountingType: mountingType})
 	if err != nil {
-		return fmt.Errorf("save requested bundle size: insert bundle_sizes failed (type=%q, size=%v): %w", "pressure fit", desiredWidth, err)
+		return "", fmt.Errorf("opening problem: failed to get gates (mountingType=%s): %w", mountingType, err)
+	}
+	if len(gates) > 0 && opening > widestSpan(gates) {
+		return NoBundlesProblem(products, mountingType, opening)
 	}
-	return nil
+	return "", nil
 }
 
 func (h *Handler) BuildBundle(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
@@ -119,34 +107,45 @@ func (h *Handler) BuildBundle(cart models.Cart, w http.ResponseWriter, r *http.R
 		return fmt.Errorf("build endpoint: failed to parse form: %w", err)
 	}
 
-	desiredWidth, err := strconv.ParseFloat(r.Form.Get("desired-width"), 32)
-	if err != nil {
-		return fmt.Errorf("build endpoint: failed to parse desired width: %w", err)
+	mountingType := models.MountingType(r.Form.Get("mounting-type"))
+	if !mountingType.IsValid() {
+		mountingType = models.MountingTypePressureFit
 	}
 
-	/*
-		static max witdth for now
-		could consider adding a max width to the gates in the DB and enforcing the
-		constraint from there
-	*/
-	maxWidth := 220.00
-	if desiredWidth > maxWidth {
-		desiredWidth = maxWidth
+	goal := models.BundleGoal(r.Form.Get("goal"))
+	if !goal.IsValid() {
+		goal = models.BundleGoalPrice
 	}
 
-	if err := SaveRequestedBundleSize(h.db, float32(desiredWidth)); err != nil {
-		return fmt.Errorf("build endpoint: failed to save requested bundle size: %w", err)
+	alternatives := defaultBundleAlternatives
+	if raw := r.Form.Get("alternatives"); raw != "" {
+		var err error
+		alternatives, err = strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("build endpoint: failed to parse alternatives: %w", err)
+		}
+		alternatives = min(max(alternatives, 1), maxBundleAlternatives)
 	}
 
-	bundles, err := BuildPressureFitBundles(h.productCache, float32(desiredWidth))
-	if err != nil {
-		return fmt.Errorf("build endpoint: failed to build pressure fit bundles: %w", err)
+	var bundles []models.Bundle
+	problem := openingWidthProblem
+	desiredWidth, err := strconv.ParseFloat(strings.TrimSpace(r.Form.Get("desired-width")), 32)
+	if err != nil || !isOpeningWidth(desiredWidth) {
+		desiredWidth = 0
+	} else {
+		bundles, problem, err = h.buildBundles(cart.ID, mountingType, float32(desiredWidth), goal, alternatives)
+		if err != nil {
+			return fmt.Errorf("build endpoint: failed to build %s bundles: %w", mountingType, err)
+		}
 	}
 
 	if h.cfg.UseTempl {
 		props := partials.BuildResultsProps{
 			RequestedBundleSize: (desiredWidth),
+			MountingType:        mountingType,
+			Goal:                goal,
 			Bundles:             bundles,
+			Problem:             problem,
 			// /	Env:                 h.cfg.Mode,
 		}
 		return partials.BuildResults(props).Render(r.Context(), w)
@@ -154,7 +153,10 @@ func (h *Handler) BuildBundle(cart models.Cart, w http.ResponseWriter, r *http.R
 
 	data := map[string]any{
 		"RequestedBundleSize": float32(desiredWidth),
+		"MountingType":        mountingType,
+		"Goal":                goal,
 		"Bundles":             bundles,
+		"Problem":             problem,
 		"Env":                 h.cfg.Mode,
 	}
 
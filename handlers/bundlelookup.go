@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/seanomeara96/gates/config"
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/repos"
+)
+
+// bundleLookup tracks the precomputed bundles in the bundle_lookup table. The
+// builder is slow enough that working out every whole centimetre ahead of
+// time and looking the answer up beats searching on every request.
+type bundleLookup struct {
+	mu sync.Mutex // one precompute at a time
+	// generation goes up whenever the catalog changes, so a precompute that
+	// started before the change knows not to store what it found
+	generation atomic.Int64
+}
+
+// PrecomputeBundles works out the builder's bundles for every whole
+// centimetre each mounting type supports, for every goal, and replaces the
+// bundle lookup with them. It returns how many bundles were stored.
+func (h *Handler) PrecomputeBundles() (int, error) {
+	h.bundleLookup.mu.Lock()
+	defer h.bundleLookup.mu.Unlock()
+	generation := h.bundleLookup.generation.Load()
+
+	var rows []repos.BundleLookupRow
+	for _, mountingType := range models.MountingTypes {
+		gates, err := h.productCache.GetProducts(repos.ProductFilterParams{Type: models.ProductTypeGate, MountingType: mountingType})
+		if err != nil {
+			return 0, fmt.Errorf("precompute bundles: failed to get gates (mountingType=%s): %w", mountingType, err)
+		}
+		if len(gates) == 0 {
+			continue
+		}
+
+		// every width the builder takes, see openingProblem
+		narrowest, widest := float32(math.MaxFloat32), widestSpan(gates)
+		for _, gate := range gates {
+			narrowest = min(narrowest, gate.MinWidth())
+		}
+
+		for width := int(math.Ceil(float64(narrowest))); width <= int(widest); width++ {
+			for _, goal := range models.BundleGoals {
+				bundles, err := BuildBundles(h.productCache, mountingType, float32(width), goal, maxBundleAlternatives)
+				if err != nil {
+					return 0, fmt.Errorf("precompute bundles: %w", err)
+				}
+				for rank, bundle := range bundles {
+					rows = append(rows, repos.BundleLookupRow{
+						MountingType: mountingType,
+						Goal:         goal,
+						Width:        width,
+						Rank:         rank,
+						Key:          models.CartItemKey(bundle.Components),
+					})
+				}
+			}
+		}
+	}
+
+	if generation != h.bundleLookup.generation.Load() {
+		// the catalog changed while we were working, the precompute that
+		// change started will store the bundles instead
+		return 0, nil
+	}
+	if err := h.productRepo.ReplaceBundleLookup(rows); err != nil {
+		return 0, fmt.Errorf("precompute bundles: %w", err)
+	}
+	return len(rows), nil
+}
+
+// catalogChanged drops the bundle lookup, so the builder searches live until
+// it has been precomputed again in the background. The product cache is
+// flushed again too, as a request may have refilled it between the write's
+// own flush and the write itself.
+func (h *Handler) catalogChanged() {
+	h.bundleLookup.generation.Add(1)
+	h.productCache.Flush()
+	if err := h.productRepo.ClearBundleLookup(); err != nil {
+		log.Printf("[ERROR] catalog changed: %v", err)
+	}
+	go h.RefreshBundleLookup()
+}
+
+// RefreshBundleLookup precomputes the bundles and logs how it went.
+func (h *Handler) RefreshBundleLookup() {
+	n, err := h.PrecomputeBundles()
+	if err != nil {
+		log.Printf("[ERROR] refresh bundle lookup: %v", err)
+		return
+	}
+	if h.cfg.Mode == config.Development {
+		log.Printf("[INFO] bundle lookup refreshed with %d bundles", n)
+	}
+}
+
+// buildBundles finds the bundles for an opening, up to n per gate, best first
+// by goal. They come from the bundle lookup when the opening is a whole number
+// of centimetres and it has been precomputed, and are searched for live
+// otherwise. Bundles with a component that is out of stock right now are left
+// out, and gates that leaves with none are searched again without the
+// extensions that have run out. When there are none, problem tells the
+// customer why. The request is
+// recorded against cartID, which may be empty, for the demand page.
+func (h *Handler) buildBundles(cartID string, mountingType models.MountingType, opening float32, goal models.BundleGoal, n int) (bundles []models.Bundle, problem string, err error) {
+	problem, err = openingProblem(h.productCache, mountingType, opening)
+	if problem != "" || err != nil {
+		return nil, problem, err
+	}
+
+	bundles, found, err := h.lookupBundles(mountingType, opening, goal)
+	if err != nil {
+		return nil, "", err
+	}
+	if !found {
+		bundles, err = BuildBundles(h.productCache, mountingType, opening, goal, maxBundleAlternatives)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	inStock, err := h.inStockBundles(bundles)
+	if err != nil {
+		return nil, "", err
+	}
+	inStock, err = h.searchInStock(bundles, inStock, opening, goal)
+	if err != nil {
+		return nil, "", err
+	}
+	available := firstPerGate(inStock, n)
+
+	err = h.demandRepo.RecordBuildRequest(models.BuildRequest{
+		CartID:       cartID,
+		MountingType: mountingType,
+		Width:        opening,
+		Outcome:      buildOutcome(bundles, inStock),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case len(available) > 0:
+	case len(bundles) > 0:
+		problem = fmt.Sprintf("Every gate that fits %gcm is out of stock right now. Get in touch and we'll let you know when it's back.", opening)
+	default:
+		problem, err = NoBundlesProblem(h.productCache, mountingType, opening)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return available, problem, nil
+}
+
+// lookupBundles returns the precomputed bundles for an opening, with their
+// products as they are in the catalog now. found is false when there are
+// none, or they no longer match the catalog, and the bundles should be
+// searched for live.
+func (h *Handler) lookupBundles(mountingType models.MountingType, opening float32, goal models.BundleGoal) (bundles []models.Bundle, found bool, err error) {
+	width := int(opening)
+	if float32(width) != opening {
+		return nil, false, nil
+	}
+
+	keys, err := h.productRepo.GetBundleLookup(mountingType, goal, width)
+	if err != nil || len(keys) == 0 {
+		return nil, false, err
+	}
+
+	for _, key := range keys {
+		components, err := h.bundleComponents(key)
+		if err != nil {
+			return nil, false, nil
+		}
+		bundle := models.Bundle{Components: components}
+		bundle.ComputeMetaData()
+		if bundle.MountingType != mountingType || !bundle.Fits(opening) {
+			return nil, false, nil
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, true, nil
+}
+
+// inStockBundles drops the bundles that have a component without enough stock
+// left to sell, allowing for live reservations, unless it can be backordered.
+func (h *Handler) inStockBundles(bundles []models.Bundle) ([]models.Bundle, error) {
+	type stock struct {
+		available      int
+		allowBackorder bool
+	}
+	stocks := map[int]stock{}
+
+	var inStock []models.Bundle
+	for _, bundle := range bundles {
+		ok := true
+		for _, component := range bundle.Components {
+			s, seen := stocks[component.Id]
+			if !seen {
+				available, allowBackorder, err := h.stockRepo.AvailableQty(component.Id)
+				if err != nil {
+					return nil, fmt.Errorf("in stock bundles: %w", err)
+				}
+				s = stock{available, allowBackorder}
+				stocks[component.Id] = s
+			}
+			if !s.allowBackorder && s.available < component.Qty {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			inStock = append(inStock, bundle)
+		}
+	}
+	return inStock, nil
+}
+
+// searchInStock searches again, over only the extensions in stock, for the
+// gates whose bundles were all dropped for stock, and adds what it finds to
+// inStock. The lookup and the search keep only the best few bundles per gate,
+// and those can all take an extension that has run out while others would
+// still do.
+func (h *Handler) searchInStock(bundles, inStock []models.Bundle, opening float32, goal models.BundleGoal) ([]models.Bundle, error) {
+	done := map[int]bool{}
+	for _, bundle := range inStock {
+		done[bundle.Components[0].Id] = true
+	}
+
+	for _, bundle := range bundles {
+		gate := bundle.Components[0]
+		if done[gate.Id] {
+			continue
+		}
+		done[gate.Id] = true
+
+		available, allowBackorder, err := h.stockRepo.AvailableQty(gate.Id)
+		if err != nil {
+			return nil, fmt.Errorf("search in stock: %w", err)
+		}
+		if !allowBackorder && available < 1 {
+			continue
+		}
+
+		extensions, err := h.productCache.GetCompatibleExtensionsByGateID(gate.Id)
+		if err != nil {
+			return nil, fmt.Errorf("search in stock: failed to get compatible extensions (gateId=%d): %w", gate.Id, err)
+		}
+		var stocked []models.Product
+		for _, extension := range extensions {
+			available, allowBackorder, err := h.stockRepo.AvailableQty(extension.Id)
+			if err != nil {
+				return nil, fmt.Errorf("search in stock: %w", err)
+			}
+			if allowBackorder || available > 0 {
+				stocked = append(stocked, extension)
+			}
+		}
+
+		found, err := h.inStockBundles(models.BestBundles(gate, stocked, opening, goal, maxBundleAlternatives))
+		if err != nil {
+			return nil, err
+		}
+		inStock = append(inStock, found...)
+	}
+	return inStock, nil
+}
+
+// firstPerGate keeps the first n bundles of each gate.
+func firstPerGate(bundles []models.Bundle, n int) []models.Bundle {
+	kept := make([]models.Bundle, 0, len(bundles))
+	perGate := map[int]int{}
+	for _, bundle := range bundles {
+		gateID := bundle.Components[0].Id
+		if perGate[gateID] < n {
+			kept = append(kept, bundle)
+			perGate[gateID]++
+		}
+	}
+	return kept
+}
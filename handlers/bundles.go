@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/repos"
+	"github.com/seanomeara96/gates/views/pages"
+	"github.com/seanomeara96/gates/views/partials"
+)
+
+// SaveBundle saves the bundle sent as a CartItemKey in the form and replies
+// with the link it can be shared at.
+func (h *Handler) SaveBundle(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("save bundle: failed to parse form: %w", err)
+	}
+
+	components, err := h.bundleComponents(r.Form.Get("key"))
+	if errors.Is(err, errInvalidCartItem) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("save bundle: %w", err)
+	}
+
+	bundle, err := h.productRepo.SaveBundle(models.Bundle{Components: components})
+	if err != nil {
+		return fmt.Errorf("save bundle: %w", err)
+	}
+
+	return partials.SavedBundleLink(h.bundleURL(bundle)).Render(r.Context(), w)
+}
+
+// GetBundlePage serves a saved bundle at its slug.
+func (h *Handler) GetBundlePage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	slug := r.PathValue("slug")
+	bundle, err := h.productRepo.GetBundleBySlug(slug)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, repos.ErrBundleComponentMissing) {
+		return h.NotFoundPage(w)
+	}
+	if err != nil {
+		return fmt.Errorf("get bundle page: failed to get bundle (slug=%s): %w", slug, err)
+	}
+
+	props := pages.BundlePageProps{
+		BaseProps: pages.BaseProps{
+			PageTitle:       bundle.Name,
+			MetaDescription: fmt.Sprintf("%s. Fits openings from %gcm to %gcm.", bundle.Name, bundle.MinWidth(), bundle.MaxWidth()),
+			Cart:            cart,
+			Env:             h.cfg.Mode,
+		},
+		Bundle: bundle,
+		URL:    h.bundleURL(bundle),
+	}
+	return pages.Bundle(props).Render(r.Context(), w)
+}
+
+// AddSavedBundleToCart adds the whole of a saved bundle to the cart.
+func (h *Handler) AddSavedBundleToCart(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	slug := r.PathValue("slug")
+	bundle, err := h.productRepo.GetBundleBySlug(slug)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, repos.ErrBundleComponentMissing) {
+		return h.NotFoundPage(w)
+	}
+	if err != nil {
+		return fmt.Errorf("add saved bundle to cart: failed to get bundle (slug=%s): %w", slug, err)
+	}
+
+	// the catalog may have changed since the bundle was saved
+	components, err := h.cartItemProducts(idsAndQtys(bundle.Components))
+	if errors.Is(err, errInvalidCartItem) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("add saved bundle to cart (slug=%s): %w", slug, err)
+	}
+
+	item := bundleCartItem(cart.ID, components)
+	if err := AddItemToCart(h.cartRepo, cart.ID, item); err != nil {
+		return fmt.Errorf("add saved bundle to cart: failed to add item (cartID=%s, slug=%s): %w", cart.ID, slug, err)
+	}
+	h.markAddedToCart(cart.ID, item)
+
+	cart, found, err := h.cartRepo.GetCartByID(cart.ID)
+	if err != nil || !found {
+		return fmt.Errorf("add saved bundle to cart: failed to retrieve updated cart (cartID=%s, found=%t): %w", cart.ID, found, err)
+	}
+	return partials.CartModal(cart).Render(r.Context(), w)
+}
+
+// errInvalidCartItem is returned for products that can't be sold together as
+// one cart item, or that don't exist, as opposed to failing to look them up.
+var errInvalidCartItem = errors.New("invalid cart item")
+
+// maxCartItemProducts caps how many different products one cart item is made
+// of, a gate and its extensions.
+const maxCartItemProducts = 10
+
+// maxCartItemQty caps how many of a cart item, or of a product in one, a cart
+// can hold, well short of what would overflow its price.
+const maxCartItemQty = 99
+
+// bundleComponents looks up the products in the CartItemKey of a gate and
+// its extensions. See cartItemProducts.
+func (h *Handler) bundleComponents(key string) ([]models.Product, error) {
+	components, err := h.cartItemKeyProducts(key)
+	if err != nil {
+		return nil, err
+	}
+	if components[0].Type != models.ProductTypeGate {
+		return nil, fmt.Errorf("%w: product %d is not a gate", errInvalidCartItem, components[0].Id)
+	}
+	return components, nil
+}
+
+// cartItemKeyProducts looks up the products in a CartItemKey. See
+// cartItemProducts.
+func (h *Handler) cartItemKeyProducts(key string) ([]models.Product, error) {
+	parsed, err := models.ParseCartItemKey(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCartItem, err)
+	}
+	return h.cartItemProducts(parsed)
+}
+
+// cartItemProducts looks up the products of a cart item from their ids and
+// quantities. Names, prices and the rest come from the catalog, never from
+// the client. An item is a gate or extension on its own, or a gate and
+// extensions that fit it, see models.Bundle.Validate.
+func (h *Handler) cartItemProducts(requested []models.Product) ([]models.Product, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("%w: no products", errInvalidCartItem)
+	}
+	if len(requested) > maxCartItemProducts {
+		return nil, fmt.Errorf("%w: more than %d products", errInvalidCartItem, maxCartItemProducts)
+	}
+
+	products := make([]models.Product, 0, len(requested))
+	for _, p := range requested {
+		if p.Qty > maxCartItemQty {
+			return nil, fmt.Errorf("%w: more than %d of product %d", errInvalidCartItem, maxCartItemQty, p.Id)
+		}
+		product, err := h.productRepo.GetProductByID(p.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: there is no product %d", errInvalidCartItem, p.Id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get product (productId=%d): %w", p.Id, err)
+		}
+		product.Qty = p.Qty
+		products = append(products, product)
+	}
+
+	if len(products) == 1 && products[0].Type == models.ProductTypeExtension {
+		if products[0].Qty != 1 {
+			return nil, fmt.Errorf("%w: an extension on its own comes one at a time", errInvalidCartItem)
+		}
+		return products, nil
+	}
+
+	gate := products[0]
+	var compatible []models.Product
+	if gate.Type == models.ProductTypeGate {
+		var err error
+		compatible, err = h.productCache.GetCompatibleExtensionsByGateID(gate.Id)
+		if err != nil {
+			return nil, fmt.Errorf("get compatible extensions (gateId=%d): %w", gate.Id, err)
+		}
+	}
+	if err := (models.Bundle{Components: products}).Validate(compatible); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCartItem, err)
+	}
+	return products, nil
+}
+
+// cartItemFromForm is the cart item asked for by an add to cart form: a saved
+// bundle by bundle_id, or products by product_id, each with a qty from 1 to
+// maxCartItemQty that is 1 if left out. A lone product's qty is how many of
+// it to add.
+func (h *Handler) cartItemFromForm(cartID string, form url.Values) (models.CartItem, error) {
+	var requested []models.Product
+
+	switch ids, qtys := form["product_id"], form["qty"]; {
+	case len(ids) > 0:
+		if len(qtys) > 0 && len(qtys) != len(ids) {
+			return models.CartItem{}, fmt.Errorf("%w: %d products but %d quantities", errInvalidCartItem, len(ids), len(qtys))
+		}
+		for i, raw := range ids {
+			p := models.Product{Qty: 1}
+			var err error
+			if p.Id, err = strconv.Atoi(raw); err != nil {
+				return models.CartItem{}, fmt.Errorf("%w: bad product id %q", errInvalidCartItem, raw)
+			}
+			if len(qtys) > 0 {
+				if p.Qty, err = strconv.Atoi(qtys[i]); err != nil || p.Qty < 1 || p.Qty > maxCartItemQty {
+					return models.CartItem{}, fmt.Errorf("%w: bad qty %q", errInvalidCartItem, qtys[i])
+				}
+			}
+			requested = append(requested, p)
+		}
+
+	case form.Get("bundle_id") != "":
+		id, err := strconv.Atoi(form.Get("bundle_id"))
+		if err != nil {
+			return models.CartItem{}, fmt.Errorf("%w: bad bundle id %q", errInvalidCartItem, form.Get("bundle_id"))
+		}
+		bundle, err := h.productRepo.GetBundleByID(id)
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, repos.ErrBundleComponentMissing) {
+			return models.CartItem{}, fmt.Errorf("%w: there is no saved bundle %d", errInvalidCartItem, id)
+		}
+		if err != nil {
+			return models.CartItem{}, fmt.Errorf("get saved bundle (id=%d): %w", id, err)
+		}
+		requested = idsAndQtys(bundle.Components)
+
+	default:
+		return models.CartItem{}, fmt.Errorf("%w: no product_id or bundle_id", errInvalidCartItem)
+	}
+
+	qty := 1
+	if len(requested) == 1 {
+		qty, requested[0].Qty = requested[0].Qty, 1
+	}
+	products, err := h.cartItemProducts(requested)
+	if err != nil {
+		return models.CartItem{}, err
+	}
+	item := bundleCartItem(cartID, products)
+	item.Qty = qty
+	return item, nil
+}
+
+// idsAndQtys strips products down to what cartItemProducts takes.
+func idsAndQtys(products []models.Product) []models.Product {
+	stripped := make([]models.Product, 0, len(products))
+	for _, p := range products {
+		stripped = append(stripped, models.Product{Id: p.Id, Qty: p.Qty})
+	}
+	return stripped
+}
+
+// bundleCartItem is a priced cart item of a gate and its extensions.
+func bundleCartItem(cartID string, products []models.Product) models.CartItem {
+	components := make([]models.CartItemComponent, 0, len(products))
+	for _, product := range products {
+		component := models.NewCartItemComponent(cartID)
+		component.Product = product
+		components = append(components, component)
+	}
+	item := models.NewCartItem(cartID, components)
+	item.SetPrice()
+	return item
+}
+
+func (h *Handler) bundleURL(bundle models.Bundle) string {
+	return h.cfg.Domain + "/bundles/" + bundle.Slug
+}
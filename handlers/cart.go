@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/gorilla/sessions"
 	"github.com/seanomeara96/gates/models"
@@ -11,6 +12,9 @@ import (
 )
 
 func (h *Handler) GetCartPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	if err := h.reviewCart(&cart); err != nil {
+		return fmt.Errorf("get cart page: %w", err)
+	}
 
 	if h.cfg.UseTempl {
 		props := pages.CartPageProps{
@@ -59,6 +63,10 @@ func (h *Handler) AdjustCartItemQty(cart models.Cart, w http.ResponseWriter, r *
 	}
 
 	if mode == "increment" {
+		if cartItem.Qty >= maxCartItemQty {
+			w.WriteHeader(http.StatusBadRequest)
+			return nil
+		}
 		if err := h.cartRepo.IncrementCartItem(cart.ID, cartItem.ID); err != nil {
 			return fmt.Errorf("cart item update: increment cart item (cart_id=%s, cart_item_id=%s): %w", cart.ID, cartItem.ID, err)
 		}
@@ -79,6 +87,9 @@ func (h *Handler) AdjustCartItemQty(cart models.Cart, w http.ResponseWriter, r *
 	if !found {
 		return fmt.Errorf("cart item update: retrieve updated cart (cart_id=%s): not found", cart.ID)
 	}
+	if err := h.reviewCart(&cart); err != nil {
+		return fmt.Errorf("cart item update: %w", err)
+	}
 	if err := h.rndr.Partial(w, "cart-main", cart); err != nil {
 		return fmt.Errorf("cart item update: render partial (cart-main) (cart_id=%s): %w", cart.ID, err)
 	}
@@ -110,6 +121,9 @@ func (h *Handler) RemoveItemFromCart(cart models.Cart, w http.ResponseWriter, r
 	if !found {
 		return fmt.Errorf("cart item remove: retrieve updated cart (cart_id=%s): not found", cart.ID)
 	}
+	if err := h.reviewCart(&cart); err != nil {
+		return fmt.Errorf("cart item remove: %w", err)
+	}
 
 	if err := h.rndr.Partial(w, "cart-main", cart); err != nil {
 		return fmt.Errorf("cart item delete: render partial (cart-main) (cart_id=%s): %w", cart.ID, err)
@@ -166,6 +180,48 @@ func (h *Handler) ClearItemsFromCart(cart models.Cart, w http.ResponseWriter, r
 	return nil
 }
 
+// checkCart adds warnings to the cart about what has changed in the catalog
+// since its items were added.
+func (h *Handler) checkCart(cart *models.Cart) error {
+	stock := map[int]models.StockLevel{}
+	for _, item := range cart.Items {
+		for _, component := range item.Components {
+			if _, checked := stock[component.Id]; checked || component.Unavailable {
+				continue
+			}
+			available, allowBackorder, err := h.stockRepo.AvailableQty(component.Id)
+			if err != nil {
+				return fmt.Errorf("check cart: get available qty (cart_id=%s, product_id=%d): %w", cart.ID, component.Id, err)
+			}
+			stock[component.Id] = models.StockLevel{Available: available, AllowBackorder: allowBackorder}
+		}
+	}
+	cart.Warnings = append(cart.Warnings, cart.Check(stock)...)
+	return nil
+}
+
+// reviewCart checks the cart for showing to the customer. A price change is
+// only news once, so having been shown the current prices become the
+// quoted ones. Likewise a promotion code that can no longer be used is taken
+// off once the customer has been told.
+func (h *Handler) reviewCart(cart *models.Cart) error {
+	if err := h.checkCart(cart); err != nil {
+		return err
+	}
+	if slices.ContainsFunc(cart.Warnings, models.CartWarning.PriceChanged) {
+		if err := h.cartRepo.QuoteCurrentPrices(cart.ID); err != nil {
+			return fmt.Errorf("review cart: %w", err)
+		}
+	}
+	if slices.ContainsFunc(cart.Warnings, func(w models.CartWarning) bool { return w.Kind == models.CartWarningPromoCode }) {
+		if err := h.promotionRepo.RemoveCartCode(cart.ID); err != nil {
+			return fmt.Errorf("review cart: %w", err)
+		}
+		cart.PromoCode = ""
+	}
+	return nil
+}
+
 func (h *Handler) newCart() (models.Cart, error) {
 	cart := models.NewCart()
 	if _, err := h.cartRepo.SaveCart(cart); err != nil {
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/views/pages"
+)
+
+// demandPeriods are the periods, in days, the demand page can look back
+// over. 0 is all time.
+var demandPeriods = []int{7, 30, 90, 365, 0}
+
+const defaultDemandPeriod = 30
+
+// GetDemandPage shows purchasing what widths the builder is asked for, which
+// of them it could not serve or served poorly, and how often a request ends
+// in a cart and an order. ?days= picks the period.
+func (h *Handler) GetDemandPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	days := defaultDemandPeriod
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		if d, err := strconv.Atoi(raw); err == nil && slices.Contains(demandPeriods, d) {
+			days = d
+		}
+	}
+
+	var since time.Time
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -days)
+	}
+	demand, err := h.demandRepo.GetBuilderDemand(since)
+	if err != nil {
+		return fmt.Errorf("demand page: %w", err)
+	}
+
+	props := pages.AdminDemandPageProps{
+		BaseProps: pages.BaseProps{
+			PageTitle: "Builder Demand",
+			Env:       h.cfg.Mode,
+			Cart:      cart,
+		},
+		Demand:  demand,
+		Days:    days,
+		Periods: demandPeriods,
+	}
+	return pages.AdminDemand(props).Render(r.Context(), w)
+}
+
+// buildOutcome is how well the builder served a request, from the bundles
+// that fit and those of them that are in stock.
+func buildOutcome(fits, inStock []models.Bundle) models.BuildOutcome {
+	switch {
+	case len(fits) == 0:
+		return models.BuildUnserved
+	case len(inStock) == 0:
+		return models.BuildOutOfStock
+	case len(inStock) < len(fits):
+		return models.BuildPartlyInStock
+	default:
+		return models.BuildServed
+	}
+}
+
+// markAddedToCart counts the cart's build requests that the bundle in item
+// fits as converted. Items that are not a gate and its extensions are not
+// from the builder and are skipped. Failing to record it is logged rather
+// than failing the add.
+func (h *Handler) markAddedToCart(cartID string, item models.CartItem) {
+	if len(item.Components) == 0 || item.Components[0].Type != models.ProductTypeGate {
+		return
+	}
+	products := make([]models.Product, 0, len(item.Components))
+	for _, component := range item.Components {
+		products = append(products, component.Product)
+	}
+
+	bundle := models.Bundle{Components: products}
+	bundle.ComputeMetaData()
+	if err := h.demandRepo.MarkAddedToCart(cartID, bundle.MountingType, bundle.MinWidth(), bundle.MaxWidth()); err != nil {
+		log.Printf("[ERROR] mark build requests added to cart (cartID=%s, itemID=%s): %v", cartID, item.ID, err)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/payments"
+	"github.com/seanomeara96/gates/views/pages"
+)
+
+// The fake checkout handlers are only routed when the fake payment provider
+// is configured, see router.DefaultRouter.
+
+func (h *Handler) GetFakeCheckoutPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	s, err := h.fakePayments.Session(r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, payments.ErrNotFound) {
+			return h.NotFoundPage(w)
+		}
+		return fmt.Errorf("fake checkout page: %w", err)
+	}
+	return h.renderFakeCheckout(cart, w, r, s, "")
+}
+
+func (h *Handler) PayFakeCheckout(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("fake checkout pay: parse form: %w", err)
+	}
+
+	customer := payments.Customer{
+		Name:  r.FormValue("name"),
+		Email: r.FormValue("email"),
+		Phone: r.FormValue("phone"),
+	}
+	// collected orders are not asked for an address
+	if r.FormValue("line1") != "" {
+		customer.Address = &payments.Address{
+			Line1:      r.FormValue("line1"),
+			Line2:      r.FormValue("line2"),
+			City:       r.FormValue("city"),
+			State:      r.FormValue("state"),
+			PostalCode: r.FormValue("postal_code"),
+			Country:    r.FormValue("country"),
+		}
+	}
+
+	s, err := h.fakePayments.Pay(r.Context(), id, customer)
+	if err != nil {
+		if errors.Is(err, payments.ErrNotFound) {
+			return h.NotFoundPage(w)
+		}
+		if errors.Is(err, payments.ErrSessionClosed) {
+			s, getErr := h.fakePayments.Session(id)
+			if getErr != nil {
+				return fmt.Errorf("fake checkout pay: %w", getErr)
+			}
+			return h.renderFakeCheckout(cart, w, r, s, "This checkout session can no longer be paid.")
+		}
+		if s.ID == "" {
+			return fmt.Errorf("fake checkout pay: %w", err)
+		}
+		// the payment went through but a webhook did not, which is what a
+		// real provider's retries are for. Log it and carry on to the order.
+		log.Printf("[ERROR] fake checkout pay: %v", err)
+	}
+
+	http.Redirect(w, r, s.Params.SuccessURL, http.StatusSeeOther)
+	return nil
+}
+
+func (h *Handler) CancelFakeCheckout(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	s, err := h.fakePayments.Cancel(id)
+	if err != nil {
+		if errors.Is(err, payments.ErrNotFound) {
+			return h.NotFoundPage(w)
+		}
+		if !errors.Is(err, payments.ErrSessionClosed) {
+			return fmt.Errorf("fake checkout cancel: %w", err)
+		}
+		s, err = h.fakePayments.Session(id)
+		if err != nil {
+			return fmt.Errorf("fake checkout cancel: %w", err)
+		}
+	}
+
+	// like a hosted page, canceling only sends the customer back. The order
+	// and its reservation are left to expire with the session.
+	http.Redirect(w, r, s.Params.CancelURL, http.StatusSeeOther)
+	return nil
+}
+
+// DeclineFakeCheckout fails a payment attempt so the payment_failed path can
+// be tried out. The customer stays on the checkout page and may pay after.
+func (h *Handler) DeclineFakeCheckout(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	s, err := h.fakePayments.Decline(r.Context(), id, "Your card was declined.")
+	if err != nil {
+		if errors.Is(err, payments.ErrNotFound) {
+			return h.NotFoundPage(w)
+		}
+		if errors.Is(err, payments.ErrSessionClosed) {
+			s, getErr := h.fakePayments.Session(id)
+			if getErr != nil {
+				return fmt.Errorf("fake checkout decline: %w", getErr)
+			}
+			return h.renderFakeCheckout(cart, w, r, s, "This checkout session can no longer be paid.")
+		}
+		if s.ID == "" {
+			return fmt.Errorf("fake checkout decline: %w", err)
+		}
+		log.Printf("[ERROR] fake checkout decline: %v", err)
+	}
+	return h.renderFakeCheckout(cart, w, r, s, "Your card was declined.")
+}
+
+// ExpireFakeCheckout closes the session as if it timed out, rather than
+// waiting for its expiry, and sends the customer back.
+func (h *Handler) ExpireFakeCheckout(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	s, err := h.fakePayments.Expire(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, payments.ErrNotFound) {
+			return h.NotFoundPage(w)
+		}
+		if errors.Is(err, payments.ErrSessionClosed) {
+			if s, err = h.fakePayments.Session(id); err != nil {
+				return fmt.Errorf("fake checkout expire: %w", err)
+			}
+		} else if s.ID == "" {
+			return fmt.Errorf("fake checkout expire: %w", err)
+		} else {
+			log.Printf("[ERROR] fake checkout expire: %v", err)
+		}
+	}
+
+	http.Redirect(w, r, s.Params.CancelURL, http.StatusSeeOther)
+	return nil
+}
+
+func (h *Handler) renderFakeCheckout(cart models.Cart, w http.ResponseWriter, r *http.Request, s payments.FakeSession, errMsg string) error {
+	props := pages.FakeCheckoutPageProps{
+		BaseProps: pages.BaseProps{
+			PageTitle:       "Test Checkout",
+			MetaDescription: "Test checkout for local development",
+			Cart:            cart,
+			Env:             h.cfg.Mode,
+		},
+		Session: s,
+		Error:   errMsg,
+	}
+	return pages.FakeCheckout(props).Render(r.Context(), w)
+}
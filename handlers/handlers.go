@@ -3,51 +3,68 @@ package handlers
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
 	"regexp"
-	"strconv"
+	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/seanomeara96/auth"
 	"github.com/seanomeara96/gates/config"
+	"github.com/seanomeara96/gates/migrations"
 	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/payments"
 	"github.com/seanomeara96/gates/render"
 	"github.com/seanomeara96/gates/repos"
 	"github.com/seanomeara96/gates/repos/cache"
 	"github.com/seanomeara96/gates/repos/sqlite"
 	"github.com/seanomeara96/gates/views/pages"
-	"github.com/stripe/stripe-go/v82"
-	"github.com/stripe/stripe-go/v82/checkout/session"
-	"github.com/stripe/stripe-go/v82/webhook"
 	"golang.org/x/time/rate"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type Handler struct {
-	db           *sql.DB
-	cfg          *config.Config
-	auth         *auth.Authenticator
-	orderRepo    *sqlite.OrderRepo
-	cartRepo     *sqlite.CartRepo
-	productRepo  *sqlite.ProductRepo
-	productCache *cache.CachedProductRepo
-	cookieStore  *sessions.CookieStore
-	emailRegex   *regexp.Regexp
-	rndr         *render.Render
+	db            *sql.DB
+	cfg           *config.Config
+	auth          *auth.Authenticator
+	orderRepo     *sqlite.OrderRepo
+	cartRepo      *sqlite.CartRepo
+	productRepo   *sqlite.ProductRepo
+	productCache  *cache.CachedProductRepo
+	stockRepo     *sqlite.StockRepo
+	webhookRepo   *sqlite.WebhookEventRepo
+	refundRepo    *sqlite.RefundRepo
+	demandRepo    *sqlite.DemandRepo
+	promotionRepo *sqlite.PromotionRepo
+	shippingRepo  *sqlite.ShippingRepo
+	taxRepo       *sqlite.TaxRepo
+	bundleLookup  bundleLookup
+	cookieStore   *sessions.CookieStore
+	emailRegex    *regexp.Regexp
+	rndr          *render.Render
+	stopSweeper   context.CancelFunc
+	// refunding holds the ids of orders a refund is being made on from the
+	// order view, so the provider's refund webhook can tell them apart from
+	// refunds made on the provider's side.
+	refunding sync.Map
+	payments  payments.Provider
+	// fakePayments is set when payments go through the built-in fake
+	// provider, which needs handlers for its hosted checkout page.
+	fakePayments *payments.Fake
 }
 
 type CustomHandleFunc func(cart models.Cart, w http.ResponseWriter, r *http.Request) error
 
 func (h *Handler) Close() {
+	if h.stopSweeper != nil {
+		h.stopSweeper()
+	}
 	if h.db != nil {
 		h.db.Close()
 	}
@@ -78,10 +95,13 @@ func DefaultHandler(cfg *config.Config) (*Handler, error) {
 	var h Handler
 	var err error
 
-	stripe.Key = cfg.StripeAPIKey
-
 	h.db = SqliteOpen(cfg.DBPath)
 	h.cfg = cfg
+
+	if err := migrations.Run(h.db); err != nil {
+		return nil, fmt.Errorf("default handler: run migrations: %w", err)
+	}
+
 	authConfig := auth.AuthConfig{
 		DB:           h.db,
 		JWTSecretKey: cfg.JWTSecretKey,
@@ -96,7 +116,18 @@ func DefaultHandler(cfg *config.Config) (*Handler, error) {
 	h.productCache = cache.NewCachedProductRepo(h.productRepo)
 
 	h.cartRepo = sqlite.NewCartRepo(h.db, h.productRepo)
+	h.cartRepo.SetMultiGateDiscount(cfg.MultiGateDiscount())
+	h.promotionRepo = sqlite.NewPromotionRepo(h.db)
+	h.cartRepo.SetPromotionRepo(h.promotionRepo)
+	h.shippingRepo = sqlite.NewShippingRepo(h.db)
+	h.cartRepo.SetShippingRepo(h.shippingRepo, cfg.ClickAndCollectAddress)
+	h.taxRepo = sqlite.NewTaxRepo(h.db)
+	h.cartRepo.SetTaxRepo(h.taxRepo)
 	h.orderRepo = sqlite.NewOrderRepo(h.db)
+	h.stockRepo = sqlite.NewStockRepo(h.db)
+	h.webhookRepo = sqlite.NewWebhookEventRepo(h.db)
+	h.refundRepo = sqlite.NewRefundRepo(h.db)
+	h.demandRepo = sqlite.NewDemandRepo(h.db)
 	h.cookieStore, err = configCookieStore(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("default handler: config cookie store: %w", err)
@@ -109,139 +140,22 @@ func DefaultHandler(cfg *config.Config) (*Handler, error) {
 
 	h.rndr = render.DefaultRender(cfg)
 
-	return &h, nil
-}
-
-func (h *Handler) StripeWebhook(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
-	const MaxBodyBytes = int64(65536)
-	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
-	payload, err := io.ReadAll(r.Body)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading request body: %v\n", err)
-		w.WriteHeader(http.StatusServiceUnavailable)
-		return nil
-	}
-
-	endpointSecret := h.cfg.StripeWebhookSecret
-	// Pass the request body and Stripe-Signature header to ConstructEvent, along
-	// with the webhook signing key.
-	event, err := webhook.ConstructEvent(
-		payload,
-		r.Header.Get("Stripe-Signature"),
-		endpointSecret,
-	)
-
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error verifying webhook signature: %v\n", err)
-		w.WriteHeader(http.StatusBadRequest) // Return a 400 error on a bad signature
-		return nil
-	}
-
-	// Unmarshal the event data into an appropriate struct depending on its Type
-	switch event.Type {
-	case "payment_intent.succeeded":
-		if event.Data == nil {
-			w.WriteHeader(http.StatusBadRequest) // Return a 400 error on a bad signature
-			log.Println("[WARNING] event data for payment_intent.succeeded is nil")
-			return nil
-		}
-
-		var paymentIntent stripe.PaymentIntent
-		err := json.Unmarshal(event.Data.Raw, &paymentIntent)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest) // Return a 400 error on a bad signature
-			return fmt.Errorf("stripe webhook: payment_intent.succeeded: unmarshal payment intent: %w", err)
-		}
-
-		_id, found := paymentIntent.Metadata["order_id"]
-		if !found {
-			return fmt.Errorf("stripe webhook: payment_intent.succeeded: missing order_id in payment intent metadata (payment_intent_id=%s)", paymentIntent.ID)
-		}
-
-		id, err := strconv.Atoi(_id)
-		if err != nil {
-			return fmt.Errorf("stripe webhook: payment_intent.succeeded: parse order_id %q (payment_intent_id=%s): %w", _id, paymentIntent.ID, err)
-		}
-
-		if err := h.orderRepo.UpdateStatus(id, models.OrderStatusProcessing); err != nil {
-			return fmt.Errorf("stripe webhook: payment_intent.succeeded: update order status to %s (order_id=%d, payment_intent_id=%s): %w", models.OrderStatusProcessing, id, paymentIntent.ID, err)
-		}
-
-	case "checkout.session.completed":
-		if event.Data == nil {
-			w.WriteHeader(http.StatusBadRequest)
-			log.Println("[WARNING] event data for checkout.session.completed  is nil")
-			return nil
-		}
-		var session stripe.CheckoutSession
-		err := json.Unmarshal(event.Data.Raw, &session)
+	switch cfg.PaymentProvider {
+	case config.PaymentProviderFake:
+		h.fakePayments, err = payments.NewFake(cfg.Domain, "")
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			return fmt.Errorf("stripe webhook: checkout.session.completed: unmarshal checkout session: %w", err)
-		}
-
-		_id, found := session.Metadata["order_id"]
-		if !found {
-			log.Println("[WARNING] order id not found on chckout session object")
-		}
-
-		id, err := strconv.Atoi(_id)
-		if err != nil {
-			return fmt.Errorf("stripe webhook: checkout.session.completed: parse order_id %q (session_id=%s): %w", _id, session.ID, err)
-		}
-
-		details := session.CustomerDetails
-		if details == nil {
-			log.Printf("[WARNING] customer details on checkout session is nil order %d", id)
-		} else if found {
-			order, err := h.orderRepo.GetOrderByID(id)
-			if err != nil {
-				return fmt.Errorf("stripe webhook: checkout.session.completed: get order by id (order_id=%d, session_id=%s): %w", id, session.ID, err)
-			}
-
-			if details.Name != "" {
-				order.CustomerName = sql.NullString{
-					String: details.Name,
-					Valid:  true,
-				}
-			}
-
-			if details.Email != "" {
-				order.CustomerEmail = sql.NullString{
-					String: details.Email,
-					Valid:  true,
-				}
-			}
-
-			if details.Phone != "" {
-				order.CustomerPhone = sql.NullString{
-					String: details.Phone,
-					Valid:  true,
-				}
-			}
-
-			b, err := json.Marshal(details.Address)
-			if err != nil {
-				log.Printf("[WARNING] could not marshal json for customer details shipping address order: %d", id)
-			} else {
-				order.ShippingAddress = sql.NullString{
-					String: string(b),
-					Valid:  true,
-				}
-			}
-
-			if err := h.orderRepo.UpdateOrder(order); err != nil {
-				return fmt.Errorf("stripe webhook: checkout.session.completed: update order with customer details (order_id=%d, session_id=%s): %w", id, session.ID, err)
-			}
-
+			return nil, fmt.Errorf("default handler: init fake payments: %w", err)
 		}
-
+		h.payments = h.fakePayments
 	default:
-		fmt.Fprintf(os.Stderr, "Unhandled event type: %s\n", event.Type)
+		h.payments = payments.NewStripe(cfg.StripeAPIKey, cfg.StripeWebhookSecret)
 	}
 
-	w.WriteHeader(http.StatusOK)
-	return nil
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	h.stopSweeper = stopSweeper
+	go h.sweepReservations(sweeperCtx)
+
+	return &h, nil
 }
 
 func (h *Handler) AdminLoginPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
@@ -342,96 +256,126 @@ func (h *Handler) GetContactPage(cart models.Cart, w http.ResponseWriter, r *htt
 
 func (h *Handler) GetCheckoutPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
 
+	// early check so most carts that changed since they were filled never
+	// create an order, the reservation below is what actually holds the
+	// stock. The cart page shows the customer what changed.
+	if err := h.checkCart(&cart); err != nil {
+		return fmt.Errorf("checkout: %w", err)
+	}
+	if len(cart.Warnings) > 0 {
+		http.Redirect(w, r, "/cart", http.StatusSeeOther)
+		return nil
+	}
+
 	// reset the prices in the cart object in case there has been some manipulation on the client side
-	cart.TotalValue = 0
+	cart.TotalValue = models.Money{}
 	for i := range cart.Items {
 		cartItem := &cart.Items[i]
-		cartItem.SalePrice = 0
+		cartItem.SalePrice = models.Money{}
 		for ii := range cartItem.Components {
 			component := &cartItem.Components[ii]
-			count, err := h.productRepo.CountProductByID(component.Id)
-			if err != nil {
-				return fmt.Errorf("checkout: count product by id %d: %w", component.Id, err)
-			}
-			insufficientStock := count < component.Qty
-			if insufficientStock {
-				return fmt.Errorf("checkout: insufficient stock (product_id=%d, required_qty=%d, available_qty=%d)", component.Id, component.Qty, count)
-			}
 			price, err := h.productRepo.GetProductPrice(component.Id)
 			if err != nil {
 				return fmt.Errorf("checkout: get product price (product_id=%d): %w", component.Id, err)
 			}
 			component.Price = price
-			cartItem.SalePrice += ((component.Price) * float32(component.Qty))
+			cartItem.SalePrice = cartItem.SalePrice.Add(component.Price.Mul(component.Qty))
 		}
-		cart.TotalValue += (cartItem.SalePrice * float32(cartItem.Qty))
+		cart.TotalValue = cart.TotalValue.Add(cartItem.SalePrice.Mul(cartItem.Qty))
 	}
-
-	if h.cfg.StripeAPIKey == "" {
-		if err := json.NewEncoder(w).Encode(cart); err != nil {
-			return fmt.Errorf("checkout: encode cart json: %w", err)
-		}
+	if err := h.cartRepo.ApplyDiscounts(&cart); err != nil {
+		return fmt.Errorf("checkout: %w", err)
+	}
+	if err := h.cartRepo.QuoteShipping(&cart); err != nil {
+		return fmt.Errorf("checkout: %w", err)
+	}
+	if cart.Shipping.Method == "" {
+		// nothing delivers this cart, the cart page says so
+		http.Redirect(w, r, "/cart", http.StatusSeeOther)
 		return nil
 	}
+	if err := h.cartRepo.ApplyVAT(&cart); err != nil {
+		return fmt.Errorf("checkout: %w", err)
+	}
 
-	// continue validating cart
-	lineItems := []*stripe.CheckoutSessionLineItemParams{}
+	lineItems := make([]payments.LineItem, 0, len(cart.Items))
 	for _, item := range cart.Items {
-		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
-			Quantity: stripe.Int64(int64(item.Qty)),
-			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-				UnitAmount: stripe.Int64(int64(item.SalePrice * 100)),
-				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-					Name: stripe.String(item.Name),
-					Images: func() (images []*string) {
-						for _, component := range item.Components {
-							images = append(images, stripe.String(component.Img))
-						}
-						return images
-					}(),
-				},
-				Currency: stripe.String("EUR"),
-			},
-		},
-		)
+		images := make([]string, 0, len(item.Components))
+		for _, component := range item.Components {
+			images = append(images, component.Img)
+		}
+		lineItems = append(lineItems, payments.LineItem{
+			Name:       item.Name,
+			UnitAmount: item.SalePrice.Amount,
+			Quantity:   int64(item.Qty),
+			Images:     images,
+		})
+	}
+
+	currency := cart.TotalValue.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+
+	var discountNames []string
+	for _, discount := range cart.Discounts {
+		if discount.Amount.Amount > 0 {
+			discountNames = append(discountNames, discount.Label)
+		}
 	}
 
 	id, err := h.orderRepo.New(cart)
+	if errors.Is(err, repos.ErrPromotionUnavailable) {
+		// used up since the cart was shown, the cart page will say so
+		http.Redirect(w, r, "/cart", http.StatusSeeOther)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("checkout: create new order: %w", err)
 	}
 
-	params := &stripe.CheckoutSessionParams{
-		ClientReferenceID: stripe.String(strconv.Itoa(id)),
-		LineItems:         lineItems,
-		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
-		SuccessURL:        stripe.String(h.cfg.Domain + fmt.Sprintf("/success?order_id=%d", id)),
-		CancelURL:         stripe.String(h.cfg.Domain + "/cart"),
-		ShippingAddressCollection: &stripe.CheckoutSessionShippingAddressCollectionParams{
-			AllowedCountries: []*string{stripe.String("IE")},
-		},
-		PhoneNumberCollection: &stripe.CheckoutSessionPhoneNumberCollectionParams{
-			Enabled: stripe.Bool(true),
-		},
-		Currency: stripe.String("EUR"),
-		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{
-			Description: stripe.String(fmt.Sprintf("Order: #%d", id)),
-			Metadata: map[string]string{
-				"order_id": strconv.Itoa(id),
-			},
-		},
-		Metadata: map[string]string{
-			"order_id": strconv.Itoa(id),
+	// the reservation lives exactly as long as the checkout session
+	expiresAt := time.Now().Add(checkoutSessionTTL)
+	if _, err := h.stockRepo.ReserveOrder(id, expiresAt); err != nil {
+		if statusErr := h.orderRepo.UpdateStatus(id, models.OrderStatusCanceled, models.OrderActorCheckout, "stock could not be reserved"); statusErr != nil {
+			log.Printf("[ERROR] checkout: cancel order %d after failed reservation: %v", id, statusErr)
+		}
+		if errors.Is(err, repos.ErrInsufficientStock) {
+			// sold since the check above, the cart page will say what
+			http.Redirect(w, r, "/cart", http.StatusSeeOther)
+			return nil
+		}
+		return fmt.Errorf("checkout: reserve stock (order_id=%d): %w", id, err)
+	}
+
+	s, err := h.payments.CreateSession(r.Context(), payments.SessionParams{
+		OrderID:          id,
+		Currency:         string(currency),
+		LineItems:        lineItems,
+		SuccessURL:       h.cfg.Domain + fmt.Sprintf("/success?order_id=%d", id),
+		CancelURL:        h.cfg.Domain + "/cart",
+		ExpiresAt:        expiresAt,
+		AllowedCountries: cart.Shipping.Countries,
+		Shipping: payments.ShippingOption{
+			Name:   cart.Shipping.Name,
+			Amount: cart.Shipping.Price.Amount,
 		},
-	}
-
-	s, err := session.New(params)
+		Discount:      cart.DiscountTotal().Amount,
+		DiscountName:  strings.Join(discountNames, ", "),
+		CustomerEmail: cart.CustomerEmail,
+	})
 	if err != nil {
-		return fmt.Errorf("checkout: create stripe checkout session (order_id=%d): %w", id, err)
+		if _, releaseErr := h.stockRepo.RestockOrder(id, models.StockMovementCancel); releaseErr != nil {
+			log.Printf("[ERROR] checkout: release reservation for order %d: %v", id, releaseErr)
+		}
+		if statusErr := h.orderRepo.UpdateStatus(id, models.OrderStatusCanceled, models.OrderActorCheckout, "checkout session could not be created"); statusErr != nil {
+			log.Printf("[ERROR] checkout: cancel order %d after %s error: %v", id, h.payments.Name(), statusErr)
+		}
+		return fmt.Errorf("checkout: create %s checkout session (order_id=%d): %w", h.payments.Name(), id, err)
 	}
 
 	if err := h.orderRepo.UpdateStripeRef(id, s.ID); err != nil {
-		return fmt.Errorf("checkout: update order with stripe ref (order_id=%d, session_id=%s): %w", id, s.ID, err)
+		return fmt.Errorf("checkout: update order with payment ref (order_id=%d, session_id=%s): %w", id, s.ID, err)
 	}
 
 	http.Redirect(w, r, s.URL, http.StatusSeeOther)
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"cmp"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+)
+
+// schemaEnums are the values of the string types the API only accepts some
+// values of.
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeFor[models.ProductType]():     enumValues(models.ProductTypeGate, models.ProductTypeExtension, models.ProductTypeBundle),
+	reflect.TypeFor[models.MountingType]():    enumValues(models.MountingTypes...),
+	reflect.TypeFor[models.BundleGoal]():      enumValues(models.BundleGoals...),
+	reflect.TypeFor[models.CartWarningKind](): enumValues(models.CartWarningKinds...),
+	reflect.TypeFor[models.ShippingMethod]():  enumValues(models.ShippingDelivery, models.ShippingCollect),
+	reflect.TypeFor[models.TaxClass]():        enumValues(models.TaxClasses...),
+}
+
+// OpenAPI describes routes as an OpenAPI 3 document. Request and response
+// schemas come from the Go types of APIRoute.Request and APIRoute.Response,
+// so the document stays in step with what the handlers send.
+func OpenAPI(routes []APIRoute) map[string]any {
+	schemas := schemaSet{byName: map[string]map[string]any{}, names: map[reflect.Type]string{}}
+	errorResponse := map[string]any{
+		"description": "Error",
+		"content": jsonContent(object(map[string]any{
+			"error": schemas.schema(reflect.TypeFor[APIError]()),
+		})),
+	}
+
+	paths := map[string]any{}
+	for _, route := range routes {
+		operation := map[string]any{"summary": route.Summary}
+
+		if len(route.Params) > 0 {
+			var params []map[string]any
+			for _, p := range route.Params {
+				schema := map[string]any{"type": p.Type}
+				if len(p.Enum) > 0 {
+					schema["enum"] = p.Enum
+				}
+				param := map[string]any{"name": p.Name, "in": p.In, "required": p.Required, "schema": schema}
+				if p.Description != "" {
+					param["description"] = p.Description
+				}
+				params = append(params, param)
+			}
+			operation["parameters"] = params
+		}
+
+		if route.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemas.schema(reflect.TypeOf(route.Request))),
+			}
+		}
+
+		status := cmp.Or(route.Status, http.StatusOK)
+		operation["responses"] = map[string]any{
+			strconv.Itoa(status): map[string]any{
+				"description": http.StatusText(status),
+				"content": jsonContent(object(map[string]any{
+					"data": schemas.schema(reflect.TypeOf(route.Response)),
+				})),
+			},
+			"default": errorResponse,
+		}
+
+		operations, _ := paths[route.Path].(map[string]any)
+		if operations == nil {
+			operations = map[string]any{}
+			paths[route.Path] = operations
+		}
+		operations[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Gates API",
+			"version": "1",
+		},
+		"servers":    []map[string]any{{"url": APIPrefix}},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas.byName},
+	}
+}
+
+// schemaSet builds JSON schemas from Go types, putting named structs in
+// components so they are only described once.
+type schemaSet struct {
+	byName map[string]map[string]any
+	names  map[reflect.Type]string
+}
+
+func (s *schemaSet) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if enum, ok := schemaEnums[t]; ok {
+		return map[string]any{"type": "string", "enum": enum}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return object(s.properties(t, map[string]any{}))
+		}
+		return s.ref(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+// ref describes a named struct in components and refers to it.
+func (s *schemaSet) ref(t reflect.Type) map[string]any {
+	name, ok := s.names[t]
+	if !ok {
+		name = schemaName(t)
+		if _, taken := s.byName[name]; taken {
+			pkg := path.Base(t.PkgPath())
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+		s.names[t] = name
+		s.byName[name] = object(s.properties(t, map[string]any{}))
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// properties adds the fields encoding/json sends for struct t to props,
+// including those of embedded structs.
+func (s *schemaSet) properties(t reflect.Type, props map[string]any) map[string]any {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.properties(field.Type, props)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		props[cmp.Or(name, field.Name)] = s.schema(field.Type)
+	}
+	return props
+}
+
+// schemaName is what t is called in the document. The api prefix of the
+// handlers' own types is dropped, apiCart is a Cart to the client.
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	for _, prefix := range []string{"API", "api"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok && rest != "" {
+			return rest
+		}
+	}
+	return name
+}
+
+func object(properties map[string]any) map[string]any {
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
@@ -1,13 +1,120 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/views/partials"
 )
 
+// maxStatusNoteLength caps the free text an admin can attach to a status change.
+const maxStatusNoteLength = 500
+
+// adminActor is how admin changes are attributed in the order status history.
+func (h *Handler) adminActor() string {
+	return "admin:" + h.cfg.AdminUserID
+}
+
+// changeOrderStatus moves an order through the transition graph and applies
+// any stock side effects of the new status.
+func (h *Handler) changeOrderStatus(orderID int, status models.OrderStatus, actor, note string) error {
+	if err := h.orderRepo.UpdateStatus(orderID, status, actor, note); err != nil {
+		return err
+	}
+	return h.restockForStatus(orderID, status)
+}
+
+func (h *Handler) GetOrderView(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("get order view: parse order id from path: %w", err)
+	}
+	return h.renderOrderView(w, r, id, "")
+}
+
+func (h *Handler) renderOrderView(w http.ResponseWriter, r *http.Request, id int, errMsg string) error {
+	props, err := h.orderViewProps(id)
+	if err != nil {
+		return fmt.Errorf("render order view: %w", err)
+	}
+	props.Error = errMsg
+	return partials.OrderViewModal(props).Render(r.Context(), w)
+}
+
+func (h *Handler) orderViewProps(id int) (partials.OrderViewProps, error) {
+	order, err := h.orderRepo.GetOrderByID(id)
+	if err != nil {
+		return partials.OrderViewProps{}, fmt.Errorf("get order (id %d): %w", id, err)
+	}
+	items, err := h.orderRepo.GetOrderItems(id)
+	if err != nil {
+		return partials.OrderViewProps{}, fmt.Errorf("get order items (id %d): %w", id, err)
+	}
+	history, err := h.orderRepo.GetStatusHistory(id)
+	if err != nil {
+		return partials.OrderViewProps{}, fmt.Errorf("get status history (id %d): %w", id, err)
+	}
+	discounts, err := h.orderRepo.GetOrderDiscounts(id)
+	if err != nil {
+		return partials.OrderViewProps{}, fmt.Errorf("get order discounts (id %d): %w", id, err)
+	}
+	refunds, err := h.refundRepo.GetByOrderID(id)
+	if err != nil {
+		return partials.OrderViewProps{}, fmt.Errorf("get refunds (id %d): %w", id, err)
+	}
+
+	return partials.OrderViewProps{
+		Order:      *order,
+		Items:      items,
+		Discounts:  discounts,
+		History:    history,
+		Refunds:    refunds,
+		Refundable: models.RefundableComponents(items, discounts, refunds),
+	}, nil
+}
+
+// UpdateOrderStatusFromView handles the status form in the order view modal.
+// It re-renders the modal and refreshes the dashboard row's badge and select.
+func (h *Handler) UpdateOrderStatusFromView(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("update order status from view: parse order id from path: %w", err)
+	}
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("update order status from view: parse form (id %d): %w", id, err)
+	}
+
+	status := models.OrderStatus(r.FormValue("status"))
+	note := strings.TrimSpace(r.FormValue("note"))
+	if len(note) > maxStatusNoteLength {
+		return h.renderOrderView(w, r, id, fmt.Sprintf("Notes must be %d characters or fewer", maxStatusNoteLength))
+	}
+
+	if err := h.changeOrderStatus(id, status, h.adminActor(), note); err != nil {
+		var transitionErr *models.StatusTransitionError
+		if errors.As(err, &transitionErr) || !status.IsValid() {
+			return h.renderOrderView(w, r, id, "That status change is not allowed from the order's current status")
+		}
+		return fmt.Errorf("update order status from view (id %d): %w", id, err)
+	}
+
+	if err := h.renderOrderView(w, r, id, ""); err != nil {
+		return err
+	}
+	order, err := h.orderRepo.GetOrderByID(id)
+	if err != nil {
+		return fmt.Errorf("update order status from view: get order (id %d): %w", id, err)
+	}
+	if err := partials.OrderStatusBadge(partials.OrderStatusBadgeProps{OrderID: id, Status: order.Status, OOB: true}).Render(r.Context(), w); err != nil {
+		return fmt.Errorf("update order status from view: render badge (id %d): %w", id, err)
+	}
+	return partials.OrderStatusSelect(*order, true).Render(r.Context(), w)
+}
+
 func (h *Handler) UpdateOrder(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
@@ -23,9 +130,12 @@ func (h *Handler) UpdateOrder(cart models.Cart, w http.ResponseWriter, r *http.R
 		return fmt.Errorf("parse form for order update (id %d): %w", id, err)
 	}
 
-	// Update order fields from form data
-	if status := r.FormValue("status"); status != "" {
-		order.Status = models.OrderStatus(status)
+	// status goes through the transition graph before the rest of the order is saved
+	if status := models.OrderStatus(r.FormValue("status")); status != "" && status != order.Status {
+		if err := h.changeOrderStatus(id, status, h.adminActor(), ""); err != nil {
+			return fmt.Errorf("update order status (id %d): %w", id, err)
+		}
+		order.Status = status
 	}
 
 	if customerName := r.FormValue("customer_name"); customerName != "" {
@@ -92,8 +202,26 @@ func (h *Handler) UpdateOrderStatus(cart models.Cart, w http.ResponseWriter, r *
 	}
 
 	// Update order status
-	if err := h.orderRepo.UpdateStatus(id, models.OrderStatus(status)); err != nil {
-		return fmt.Errorf("update order status (id %d): %w", id, err)
+	if err := h.changeOrderStatus(id, models.OrderStatus(status), h.adminActor(), ""); err != nil {
+		var transitionErr *models.StatusTransitionError
+		if !errors.As(err, &transitionErr) {
+			return fmt.Errorf("update order status (id %d): %w", id, err)
+		}
+		// leave the badge on the current status and say why
+		props := partials.OrderStatusBadgeProps{
+			OrderID: id,
+			Status:  transitionErr.From,
+			Error:   fmt.Sprintf("Cannot move to %s", transitionErr.To),
+		}
+		return partials.OrderStatusBadge(props).Render(r.Context(), w)
 	}
-	return nil
+
+	order, err := h.orderRepo.GetOrderByID(id)
+	if err != nil {
+		return fmt.Errorf("update order status: get order (id %d): %w", id, err)
+	}
+	if err := partials.OrderStatusBadge(partials.OrderStatusBadgeProps{OrderID: id, Status: order.Status}).Render(r.Context(), w); err != nil {
+		return fmt.Errorf("update order status: render badge (id %d): %w", id, err)
+	}
+	return partials.OrderStatusSelect(*order, true).Render(r.Context(), w)
 }
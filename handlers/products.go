@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -15,6 +17,200 @@ import (
 	"github.com/seanomeara96/gates/views/partials"
 )
 
+var validProductTypes = map[models.ProductType]bool{
+	models.ProductTypeGate:      true,
+	models.ProductTypeExtension: true,
+	models.ProductTypeBundle:    true,
+}
+
+// productFromForm applies the admin product form onto product and returns a
+// map of field name to validation message. An empty map means the product is
+// safe to persist.
+func productFromForm(form url.Values, product models.Product) (models.Product, map[string]string) {
+	errs := map[string]string{}
+
+	product.Name = strings.TrimSpace(form.Get("name"))
+	product.Color = strings.TrimSpace(form.Get("color"))
+	product.Img = strings.TrimSpace(form.Get("img"))
+
+	if product.Name == "" {
+		errs["name"] = "Name is required"
+	} else if len(product.Name) > 200 {
+		errs["name"] = "Name must be 200 characters or fewer"
+	}
+
+	if len(product.Color) > 50 {
+		errs["color"] = "Color must be 50 characters or fewer"
+	}
+
+	if product.Img != "" {
+		u, err := url.Parse(product.Img)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs["img"] = "Image must be an http or https URL"
+		}
+	}
+
+	productType := models.ProductType(form.Get("type"))
+	if validProductTypes[productType] {
+		product.Type = productType
+	} else {
+		errs["type"] = "Choose a gate, extension or bundle"
+	}
+
+	// only gates use it; a bundle takes its gate's
+	product.MountingType = models.MountingType(form.Get("mounting_type"))
+	if product.MountingType == "" {
+		product.MountingType = models.MountingTypePressureFit
+	} else if !product.MountingType.IsValid() {
+		errs["mounting_type"] = "Choose pressure fit or screw fit"
+	}
+
+	parseFloat := func(field string, dest *float32) {
+		raw := strings.TrimSpace(form.Get(field))
+		if raw == "" {
+			errs[field] = "Required"
+			return
+		}
+		v, err := strconv.ParseFloat(raw, 32)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			errs[field] = "Must be a number"
+			return
+		}
+		if v < 0 {
+			errs[field] = "Cannot be negative"
+			return
+		}
+		*dest = float32(v)
+	}
+
+	parseFloat("width", &product.Width)
+	parseFloat("tolerance", &product.Tolerance)
+
+	if raw := strings.TrimSpace(form.Get("price")); raw == "" {
+		errs["price"] = "Required"
+	} else if price, err := models.ParseMoney(raw, models.DefaultCurrency); err != nil {
+		errs["price"] = "Must be an amount like 49.99"
+	} else if price.Amount < 0 {
+		errs["price"] = "Cannot be negative"
+	} else {
+		product.Price = price
+	}
+
+	if _, found := errs["width"]; !found && product.Width == 0 {
+		errs["width"] = "Must be greater than 0"
+	}
+	if _, found := errs["tolerance"]; !found && product.Width > 0 && product.Tolerance >= product.Width {
+		errs["tolerance"] = "Must be less than the width"
+	}
+
+	if product.Type == models.ProductTypeGate {
+		parseFloat("max_span", &product.MaxSpan)
+		if _, found := errs["max_span"]; !found && product.MaxSpan < product.Width {
+			errs["max_span"] = "Must be at least as wide as the gate"
+		} else if product.MaxSpan > models.MaxOpening {
+			errs["max_span"] = fmt.Sprintf("Must be %dcm or less", models.MaxOpening)
+		}
+
+		if raw := strings.TrimSpace(form.Get("max_extensions")); raw != "" {
+			maxExtensions, err := strconv.Atoi(raw)
+			if err != nil {
+				errs["max_extensions"] = "Must be a whole number"
+			} else if maxExtensions < 0 {
+				errs["max_extensions"] = "Cannot be negative"
+			} else {
+				product.MaxExtensions = maxExtensions
+			}
+		}
+	}
+
+	product.TaxClass = models.TaxClass(form.Get("tax_class"))
+	if product.TaxClass == "" {
+		product.TaxClass = models.TaxStandard
+	} else if !product.TaxClass.IsValid() {
+		errs["tax_class"] = "Choose a tax class"
+	}
+
+	if raw := strings.TrimSpace(form.Get("weight")); raw != "" {
+		weight, err := strconv.Atoi(raw)
+		if err != nil {
+			errs["weight"] = "Must be a whole number of grams"
+		} else if weight < 0 {
+			errs["weight"] = "Cannot be negative"
+		} else {
+			product.Weight = weight
+		}
+	}
+
+	inventoryLevelStr := strings.TrimSpace(form.Get("inventory_level"))
+	if inventoryLevelStr != "" {
+		inventoryLevel, err := strconv.Atoi(inventoryLevelStr)
+		if err != nil {
+			errs["inventory_level"] = "Must be a whole number"
+		} else if inventoryLevel < 0 {
+			errs["inventory_level"] = "Cannot be negative"
+		} else {
+			product.InventoryLevel = inventoryLevel
+		}
+	}
+
+	// unchecked checkboxes are left out of the form entirely
+	product.AllowBackorder = form.Get("allow_backorder") != ""
+
+	return product, errs
+}
+
+func (h *Handler) GetNewProductForm(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	props := partials.ProductFormProps{
+		Product: models.Product{Type: models.ProductTypeGate},
+	}
+	return partials.ProductFormModal(props).Render(r.Context(), w)
+}
+
+func (h *Handler) CreateProduct(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB limit
+
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("CreateProduct: failed to parse form data (path=%s): %w", r.URL.Path, err)
+	}
+
+	product, errs := productFromForm(r.Form, models.Product{})
+	if len(errs) > 0 {
+		props := partials.ProductFormProps{Product: product, Errors: errs}
+		return partials.ProductFormModal(props).Render(r.Context(), w)
+	}
+
+	id, err := h.productCache.InsertProduct(product)
+	if err != nil {
+		return fmt.Errorf("CreateProduct: failed to insert product (name=%q, path=%s): %w", product.Name, r.URL.Path, err)
+	}
+	product.Id = id
+	h.catalogChanged()
+
+	if err := h.stockRepo.RecordAdjustment(id, product.InventoryLevel); err != nil {
+		return fmt.Errorf("CreateProduct: record opening stock (productID=%d): %w", id, err)
+	}
+
+	if err := partials.ModalsPlaceholder().Render(r.Context(), w); err != nil {
+		return fmt.Errorf("CreateProduct: render modal placeholder (productID=%d): %w", id, err)
+	}
+	return partials.AdminProductRowAppendOOB(product).Render(r.Context(), w)
+}
+
+func (h *Handler) GetEditProductForm(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return fmt.Errorf("GetEditProductForm: invalid product ID format '%s' (path=%s): %w", idStr, r.URL.Path, err)
+	}
+
+	product, err := h.productCache.GetProductByID(id)
+	if err != nil {
+		return fmt.Errorf("GetEditProductForm: failed to retrieve product (ID: %d, path=%s): %w", id, r.URL.Path, err)
+	}
+
+	return partials.ProductFormModal(partials.ProductFormProps{Product: product}).Render(r.Context(), w)
+}
+
 func (h *Handler) UpdateProduct(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
 	// Verify request method
 	if r.Method != http.MethodPost && r.Method != http.MethodPut {
@@ -35,86 +231,48 @@ func (h *Handler) UpdateProduct(cart models.Cart, w http.ResponseWriter, r *http
 	}
 
 	// Parse form data with size limit to prevent DOS attacks
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB limit
 	if err := r.ParseForm(); err != nil {
 		return fmt.Errorf("UpdateProduct: failed to parse form data (productID=%d, path=%s): %w", id, r.URL.Path, err)
 	}
 
-	// Update string fields with sanitization
-	product.Name = html.EscapeString(strings.TrimSpace(r.Form.Get("name")))
-	product.Color = html.EscapeString(strings.TrimSpace(r.Form.Get("color")))
-	product.Img = html.EscapeString(strings.TrimSpace(r.Form.Get("img")))
-
-	// Update numeric fields with validation
-	inventoryLevelStr := r.Form.Get("inventory_level")
-	if inventoryLevelStr != "" {
-		inventoryLevel, err := strconv.Atoi(inventoryLevelStr)
-		if err != nil {
-			return fmt.Errorf("UpdateProduct: invalid inventory_level format '%s' (productID=%d, path=%s): %w",
-				inventoryLevelStr, id, r.URL.Path, err)
-		}
-		product.InventoryLevel = inventoryLevel
-	}
-
-	priceStr := r.Form.Get("price")
-	if priceStr != "" {
-		price, err := strconv.ParseFloat(priceStr, 32)
-		if err != nil {
-			return fmt.Errorf("UpdateProduct: invalid price format '%s' (productID=%d, path=%s): %w",
-				priceStr, id, r.URL.Path, err)
-		}
-		product.Price = float32(price)
-	}
-
-	qtyStr := r.Form.Get("qty")
-	if qtyStr != "" {
-		qty, err := strconv.Atoi(qtyStr)
-		if err != nil {
-			return fmt.Errorf("UpdateProduct: invalid qty format '%s' (productID=%d, path=%s): %w",
-				qtyStr, id, r.URL.Path, err)
-		}
-		product.Qty = qty
+	previousLevel := product.InventoryLevel
+	product, errs := productFromForm(r.Form, product)
+	if len(errs) > 0 {
+		props := partials.ProductFormProps{Product: product, Errors: errs}
+		return partials.ProductFormModal(props).Render(r.Context(), w)
 	}
 
-	toleranceStr := r.Form.Get("tolerance")
-	if toleranceStr != "" {
-		tolerance, err := strconv.ParseFloat(toleranceStr, 32)
-		if err != nil {
-			return fmt.Errorf("UpdateProduct: invalid tolerance format '%s' (productID=%d, path=%s): %w",
-				toleranceStr, id, r.URL.Path, err)
-		}
-		product.Tolerance = float32(tolerance)
+	// Persist updated product
+	if err := h.productCache.UpdateProductByID(id, product); err != nil {
+		return fmt.Errorf("UpdateProduct: failed to update product in database (ID: %d, path=%s): %w", id, r.URL.Path, err)
 	}
+	h.catalogChanged()
 
-	widthStr := r.Form.Get("width")
-	if widthStr != "" {
-		width, err := strconv.ParseFloat(widthStr, 32)
-		if err != nil {
-			return fmt.Errorf("UpdateProduct: invalid width format '%s' (productID=%d, path=%s): %w",
-				widthStr, id, r.URL.Path, err)
-		}
-		product.Width = float32(width)
+	if err := h.stockRepo.RecordAdjustment(id, product.InventoryLevel-previousLevel); err != nil {
+		return fmt.Errorf("UpdateProduct: record stock adjustment (productID=%d): %w", id, err)
 	}
 
-	// Validate product type
-	typeStr := r.Form.Get("type")
-	productType := models.ProductType(typeStr)
-	validTypes := map[models.ProductType]bool{
-		models.ProductTypeGate:      true,
-		models.ProductTypeExtension: true,
-		models.ProductTypeBundle:    true,
+	if err := partials.ModalsPlaceholder().Render(r.Context(), w); err != nil {
+		return fmt.Errorf("UpdateProduct: render modal placeholder (productID=%d): %w", id, err)
 	}
+	return partials.AdminProductRowOOB(product).Render(r.Context(), w)
+}
 
-	if validTypes[productType] {
-		product.Type = productType
-	} else if typeStr != "" {
-		return fmt.Errorf("UpdateProduct: invalid product type '%s' (productID=%d, path=%s)", typeStr, id, r.URL.Path)
+func (h *Handler) DeleteProduct(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return fmt.Errorf("DeleteProduct: invalid product ID format '%s' (path=%s): %w", idStr, r.URL.Path, err)
 	}
 
-	// Persist updated product
-	if err := h.productCache.UpdateProductByID(id, product); err != nil {
-		return fmt.Errorf("UpdateProduct: failed to update product in database (ID: %d, path=%s): %w", id, r.URL.Path, err)
+	if err := h.productCache.DeleteProductByID(id); err != nil {
+		return fmt.Errorf("DeleteProduct: failed to delete product (ID: %d, path=%s): %w", id, r.URL.Path, err)
 	}
+	h.catalogChanged()
 
+	// an empty body swaps the row out of the dashboard table
+	w.WriteHeader(http.StatusOK)
 	return nil
 }
 
@@ -311,8 +469,15 @@ func AddItemToCart(cartRepo *sqlite.CartRepo, cartID string, cartItem models.Car
 				cartID, cartItem.ID, len(cartItem.Components), err)
 		}
 	} else {
-		if err := cartRepo.IncrementCartItem(cartID, cartItem.ID); err != nil {
-			return fmt.Errorf("AddItemToCart: failed to increment cart item (cartID=%s, itemID=%s): %w",
+		existing, err := cartRepo.SelectCartItem(cartID, cartItem.ID)
+		if err != nil {
+			return fmt.Errorf("AddItemToCart: failed to select cart item (cartID=%s, itemID=%s): %w",
+				cartID, cartItem.ID, err)
+		}
+		// adding the same item again stops at maxCartItemQty
+		qty := min(existing.Qty+cartItem.Qty, maxCartItemQty)
+		if err := cartRepo.SetCartItemQty(cartID, cartItem.ID, qty); err != nil {
+			return fmt.Errorf("AddItemToCart: failed to add to cart item qty (cartID=%s, itemID=%s): %w",
 				cartID, cartItem.ID, err)
 		}
 	}
@@ -325,6 +490,11 @@ func AddItemToCart(cartRepo *sqlite.CartRepo, cartID string, cartItem models.Car
 	return nil
 }
 
+// AddItemToCart adds a saved bundle, by bundle_id, or products, by product_id
+// with a qty for each, to the cart. Only ids and quantities come from the
+// form; names, prices and the rest come from the catalog. A lone product's
+// qty is how many of it to add. With several, the first is a gate, the rest
+// extensions that fit it, and each qty is per bundle.
 func (h *Handler) AddItemToCart(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
 	if r.Method != http.MethodPost {
 		return fmt.Errorf("AddItemToCart: unsupported HTTP method %s (path=%s, cartID=%s)", r.Method, r.URL.Path, cart.ID)
@@ -337,30 +507,19 @@ func (h *Handler) AddItemToCart(cart models.Cart, w http.ResponseWriter, r *http
 		return fmt.Errorf("AddItemToCart: failed to parse form (cartID=%s, path=%s): %w", cart.ID, r.URL.Path, err)
 	}
 
-	formData := r.Form["data"]
-	if len(formData) < 1 {
-		return h.rndr.Partial(w, "cart-modal", cart)
+	item, err := h.cartItemFromForm(cart.ID, r.Form)
+	if errors.Is(err, errInvalidCartItem) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
 	}
-
-	components := []models.CartItemComponent{}
-
-	for i, d := range formData {
-		// Validate JSON to prevent injection attacks
-		if !json.Valid([]byte(d)) {
-			return fmt.Errorf("AddItemToCart: invalid JSON in form data (index=%d, cartID=%s, path=%s)", i, cart.ID, r.URL.Path)
-		}
-
-		component := models.NewCartItemComponent(cart.ID)
-		if err := json.Unmarshal([]byte(d), &component); err != nil {
-			return fmt.Errorf("AddItemToCart: failed to unmarshal cart item component (index=%d, cartID=%s, path=%s): %w",
-				i, cart.ID, r.URL.Path, err)
-		}
-		components = append(components, component)
+	if err != nil {
+		return fmt.Errorf("AddItemToCart (cartID=%s): %w", cart.ID, err)
 	}
 
-	if err := AddItemToCart(h.cartRepo, cart.ID, models.NewCartItem(cart.ID, components)); err != nil {
+	if err := AddItemToCart(h.cartRepo, cart.ID, item); err != nil {
 		return fmt.Errorf("AddItemToCart: failed to add item to cart (cartID=%s, path=%s): %w", cart.ID, r.URL.Path, err)
 	}
+	h.markAddedToCart(cart.ID, item)
 
 	cart, found, err := h.cartRepo.GetCartByID(cart.ID)
 	if err != nil || !found {
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/views/pages"
+	"github.com/seanomeara96/gates/views/partials"
+)
+
+// maxProjectOpenings caps how many openings one project can hold.
+const maxProjectOpenings = 10
+
+// GetProjectPage serves the project builder, where a customer lists every
+// opening in the house and gets a gate for each of them at once.
+func (h *Handler) GetProjectPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	props := pages.ProjectPageProps{
+		BaseProps: pages.BaseProps{
+			PageTitle:       "Plan Gates For Your Whole House",
+			MetaDescription: "List every doorway and stairway that needs a gate and we'll build a gate to fit each one, with one total for the lot.",
+			Cart:            cart,
+			Env:             h.cfg.Mode,
+		},
+		MaxOpenings:       maxProjectOpenings,
+		MultiGateDiscount: h.cfg.MultiGateDiscount(),
+	}
+	return pages.Project(props).Render(r.Context(), w)
+}
+
+// GetProjectOpeningRow renders a blank opening for the project form.
+func (h *Handler) GetProjectOpeningRow(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	return partials.ProjectOpeningRow(models.Opening{Location: models.OpeningDoorway}).Render(r.Context(), w)
+}
+
+// BuildProject runs the bundle builder for every opening in the project form
+// and renders the bundles to pick from.
+func (h *Handler) BuildProject(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("build project: failed to parse form: %w", err)
+	}
+
+	goal := models.BundleGoal(r.Form.Get("goal"))
+	if !goal.IsValid() {
+		goal = models.BundleGoalPrice
+	}
+
+	names := r.Form["opening-name"]
+	widths := r.Form["opening-width"]
+	locations := r.Form["opening-location"]
+
+	props := partials.ProjectResultsProps{Goal: goal}
+	for i := 0; i < len(widths) && i < maxProjectOpenings; i++ {
+		result := partials.ProjectOpening{Index: i}
+		result.Opening.Name = strings.TrimSpace(formValueAt(names, i))
+		if result.Opening.Name == "" {
+			result.Opening.Name = fmt.Sprintf("Opening %d", i+1)
+		}
+		result.Opening.Location = models.OpeningLocation(formValueAt(locations, i))
+		if !result.Opening.Location.IsValid() {
+			result.Opening.Location = models.OpeningDoorway
+		}
+
+		width, err := strconv.ParseFloat(strings.TrimSpace(widths[i]), 32)
+		switch {
+		case err != nil || !isOpeningWidth(width):
+			result.Problem = openingWidthProblem
+		default:
+			result.Opening.Width = float32(width)
+			mountingType := result.Opening.Location.MountingType()
+			result.Bundles, result.Problem, err = h.buildBundles(cart.ID, mountingType, result.Opening.Width, goal, defaultBundleAlternatives)
+			if err != nil {
+				return fmt.Errorf("build project: failed to build bundles (opening=%d): %w", i, err)
+			}
+		}
+		props.Openings = append(props.Openings, result)
+	}
+
+	props.Total = h.projectTotal(projectChoices(props.Openings))
+	return partials.ProjectResults(props).Render(r.Context(), w)
+}
+
+// QuoteProject re-totals the project as the customer changes which bundle
+// they want for each opening.
+func (h *Handler) QuoteProject(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("quote project: failed to parse form: %w", err)
+	}
+	items, err := h.projectItems(cart.ID, r.Form)
+	if errors.Is(err, errInvalidCartItem) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("quote project: %w", err)
+	}
+	return partials.ProjectTotal(h.projectTotal(items)).Render(r.Context(), w)
+}
+
+// AddProjectToCart adds the bundle chosen for every opening to the cart in
+// one go.
+func (h *Handler) AddProjectToCart(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB limit
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("add project to cart: failed to parse form (cartID=%s): %w", cart.ID, err)
+	}
+
+	items, err := h.projectItems(cart.ID, r.Form)
+	if errors.Is(err, errInvalidCartItem) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("add project to cart (cartID=%s): %w", cart.ID, err)
+	}
+	for _, item := range items {
+		if err := AddItemToCart(h.cartRepo, cart.ID, item); err != nil {
+			return fmt.Errorf("add project to cart: failed to add item (cartID=%s, itemID=%s): %w", cart.ID, item.ID, err)
+		}
+		h.markAddedToCart(cart.ID, item)
+	}
+
+	cart, found, err := h.cartRepo.GetCartByID(cart.ID)
+	if err != nil || !found {
+		return fmt.Errorf("add project to cart: failed to retrieve updated cart (cartID=%s, found=%t): %w", cart.ID, found, err)
+	}
+	return partials.CartModal(cart).Render(r.Context(), w)
+}
+
+// projectItems turns the bundle picked for each opening, sent as choice-0,
+// choice-1 and so on, into cart items. Names and prices come from the catalog,
+// not the form. Openings with nothing picked are skipped.
+func (h *Handler) projectItems(cartID string, form url.Values) ([]models.CartItem, error) {
+	var items []models.CartItem
+	for i := 0; i < maxProjectOpenings; i++ {
+		key := form.Get("choice-" + strconv.Itoa(i))
+		if key == "" {
+			continue
+		}
+		products, err := h.bundleComponents(key)
+		if err != nil {
+			return nil, fmt.Errorf("choice (opening=%d): %w", i, err)
+		}
+		items = append(items, bundleCartItem(cartID, products))
+	}
+	return items, nil
+}
+
+// projectTotal prices items as a cart of their own, so the customer sees the
+// multi-gate discount the project earns.
+func (h *Handler) projectTotal(items []models.CartItem) partials.ProjectTotalProps {
+	quote := models.Cart{Items: items}
+	quote.SetTotalValue()
+	quote.ApplyDiscounts(h.cfg.MultiGateDiscount())
+	return partials.ProjectTotalProps{Quote: quote, MultiGateDiscount: h.cfg.MultiGateDiscount()}
+}
+
+// projectChoices are the items for the bundle preselected for each opening,
+// i.e. the best one.
+func projectChoices(openings []partials.ProjectOpening) []models.CartItem {
+	var items []models.CartItem
+	for _, opening := range openings {
+		if len(opening.Bundles) == 0 {
+			continue
+		}
+		bundle := opening.Bundles[0]
+		components := make([]models.CartItemComponent, 0, len(bundle.Components))
+		for _, product := range bundle.Components {
+			components = append(components, models.CartItemComponent{Product: product})
+		}
+		item := models.NewCartItem("", components)
+		item.SetPrice()
+		items = append(items, item)
+	}
+	return items
+}
+
+func formValueAt(values []string, i int) string {
+	if i < len(values) {
+		return values[i]
+	}
+	return ""
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/repos"
+	"github.com/seanomeara96/gates/views/pages"
+)
+
+var (
+	errUnknownPromoCode = errors.New("we do not recognise this code")
+	errBadPromoEmail    = errors.New("that email address does not look right")
+)
+
+// promotionDateLayout is the format of the date inputs on the promotion form.
+const promotionDateLayout = "2006-01-02"
+
+// ApplyPromoCode puts the code the customer entered on their cart, or tells
+// them why it cannot be used.
+func (h *Handler) ApplyPromoCode(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB limit
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("apply promo code: parse form: %w", err)
+	}
+	code := models.NormalizePromotionCode(r.Form.Get("code"))
+	email := strings.TrimSpace(r.Form.Get("email"))
+
+	promotion, problem, err := h.promoCode(code, email)
+	if err != nil {
+		return fmt.Errorf("apply promo code (cart_id=%s, code=%q): %w", cart.ID, code, err)
+	}
+	if problem != nil {
+		return h.renderCartMain(w, cart.ID, models.PromoCodeWarning(code, problem))
+	}
+	if err := h.promotionRepo.SetCartCode(cart.ID, promotion.ID, email); err != nil {
+		return fmt.Errorf("apply promo code: %w", err)
+	}
+	return h.renderCartMain(w, cart.ID)
+}
+
+// promoCode looks up the promotion for code. problem is why the customer,
+// going by email, cannot use it right now, err a failure looking it up.
+func (h *Handler) promoCode(code, email string) (promotion models.Promotion, problem error, err error) {
+	if email != "" && !h.emailRegex.MatchString(email) {
+		return promotion, errBadPromoEmail, nil
+	}
+	if code == "" {
+		return promotion, errUnknownPromoCode, nil
+	}
+	promotion, err = h.promotionRepo.GetPromotionByCode(code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return promotion, errUnknownPromoCode, nil
+	}
+	if err != nil {
+		return promotion, nil, err
+	}
+	uses, err := h.promotionRepo.CustomerUses(promotion.ID, email)
+	if err != nil {
+		return promotion, nil, err
+	}
+	return promotion, promotion.Available(time.Now(), email, uses), nil
+}
+
+// RemovePromoCode takes the promotion code off the customer's cart.
+func (h *Handler) RemovePromoCode(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	if err := h.promotionRepo.RemoveCartCode(cart.ID); err != nil {
+		return fmt.Errorf("remove promo code: %w", err)
+	}
+	return h.renderCartMain(w, cart.ID)
+}
+
+// renderCartMain renders the cart as it is now, and the cart modal out of
+// band, with warnings on top of those from reviewing it.
+func (h *Handler) renderCartMain(w http.ResponseWriter, cartID string, warnings ...models.CartWarning) error {
+	cart, found, err := h.cartRepo.GetCartByID(cartID)
+	if err != nil {
+		return fmt.Errorf("render cart main: retrieve cart (cart_id=%s): %w", cartID, err)
+	}
+	if !found {
+		return fmt.Errorf("render cart main: retrieve cart (cart_id=%s): not found", cartID)
+	}
+	if err := h.reviewCart(&cart); err != nil {
+		return fmt.Errorf("render cart main: %w", err)
+	}
+	cart.Warnings = append(cart.Warnings, warnings...)
+
+	if err := h.rndr.Partial(w, "cart-main", cart); err != nil {
+		return fmt.Errorf("render cart main: render partial (cart-main) (cart_id=%s): %w", cart.ID, err)
+	}
+	if err := h.rndr.Partial(w, "cart-modal-oob", cart); err != nil {
+		return fmt.Errorf("render cart main: render partial (cart-modal-oob) (cart_id=%s): %w", cart.ID, err)
+	}
+	return nil
+}
+
+// GetPromotionsPage lists the promotions with how often they have been used,
+// and a form to add one.
+func (h *Handler) GetPromotionsPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	return h.renderPromotionsPage(cart, w, r, url.Values{}, nil)
+}
+
+func (h *Handler) renderPromotionsPage(cart models.Cart, w http.ResponseWriter, r *http.Request, form url.Values, errs map[string]string) error {
+	promotions, err := h.promotionRepo.GetPromotions()
+	if err != nil {
+		return fmt.Errorf("promotions page: %w", err)
+	}
+	gates, err := h.productCache.GetGates(repos.ProductFilterParams{Type: models.ProductTypeGate})
+	if err != nil {
+		return fmt.Errorf("promotions page: get gates: %w", err)
+	}
+	extensions, err := h.productCache.GetExtensions(repos.ProductFilterParams{Type: models.ProductTypeExtension})
+	if err != nil {
+		return fmt.Errorf("promotions page: get extensions: %w", err)
+	}
+
+	props := pages.AdminPromotionsPageProps{
+		BaseProps: pages.BaseProps{
+			PageTitle: "Promotions",
+			Env:       h.cfg.Mode,
+			Cart:      cart,
+		},
+		Promotions: promotions,
+		Gates:      gates,
+		Extensions: extensions,
+		Form:       form,
+		Errors:     errs,
+		Now:        time.Now(),
+	}
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	return pages.AdminPromotions(props).Render(r.Context(), w)
+}
+
+// CreatePromotion adds a promotion from the form on the promotions page.
+func (h *Handler) CreatePromotion(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB limit
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("create promotion: parse form: %w", err)
+	}
+
+	promotion, errs := promotionFromForm(r.Form)
+	if len(errs) == 0 && promotion.Code != "" {
+		if _, err := h.promotionRepo.GetPromotionByCode(promotion.Code); err == nil {
+			errs["code"] = "Another promotion already uses this code"
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("create promotion: %w", err)
+		}
+	}
+	if len(errs) > 0 {
+		return h.renderPromotionsPage(cart, w, r, r.Form, errs)
+	}
+
+	if _, err := h.promotionRepo.CreatePromotion(promotion); err != nil {
+		return fmt.Errorf("create promotion: %w", err)
+	}
+	http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+	return nil
+}
+
+// SetPromotionActive switches a promotion on or off, with active=1 or 0.
+func (h *Handler) SetPromotionActive(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("set promotion active: parse id from path: %w", err)
+	}
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("set promotion active: parse form: %w", err)
+	}
+	if err := h.promotionRepo.SetPromotionActive(id, r.Form.Get("active") == "1"); err != nil {
+		return fmt.Errorf("set promotion active: %w", err)
+	}
+	http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+	return nil
+}
+
+// promotionFromForm reads a promotion from the admin form. Problems are keyed
+// by field, with those across fields under "form".
+func promotionFromForm(form url.Values) (models.Promotion, map[string]string) {
+	errs := map[string]string{}
+	p := models.Promotion{
+		Name:   strings.TrimSpace(form.Get("name")),
+		Code:   models.NormalizePromotionCode(form.Get("code")),
+		Kind:   models.PromotionKind(form.Get("kind")),
+		Active: true,
+	}
+
+	if p.Name == "" {
+		errs["name"] = "Name is required"
+	} else if len(p.Name) > 100 {
+		errs["name"] = "Name must be 100 characters or fewer"
+	}
+	if strings.ContainsAny(p.Code, " \t") || len(p.Code) > 40 {
+		errs["code"] = "Codes are up to 40 characters without spaces"
+	}
+	if !p.Kind.IsValid() {
+		errs["kind"] = "Choose what the promotion does"
+	}
+
+	parseInt := func(field string, dest *int) {
+		raw := strings.TrimSpace(form.Get(field))
+		if raw == "" {
+			return
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			errs[field] = "Must be a whole number, 0 or more"
+			return
+		}
+		*dest = v
+	}
+	parseInt("percent", &p.Percent)
+	parseInt("min_extensions", &p.MinExtensions)
+	parseInt("gate_id", &p.GateID)
+	parseInt("extension_id", &p.ExtensionID)
+	parseInt("usage_limit", &p.UsageLimit)
+	parseInt("per_customer_limit", &p.PerCustomerLimit)
+
+	if raw := strings.TrimSpace(form.Get("amount")); raw != "" {
+		amount, err := models.ParseMoney(raw, models.DefaultCurrency)
+		if err != nil || amount.Amount < 0 {
+			errs["amount"] = "Must be an amount like 9.99"
+		} else {
+			p.Amount = amount
+		}
+	}
+
+	parseDate := func(field string, dest *time.Time) {
+		raw := strings.TrimSpace(form.Get(field))
+		if raw == "" {
+			return
+		}
+		t, err := time.ParseInLocation(promotionDateLayout, raw, time.Local)
+		if err != nil {
+			errs[field] = "Must be a date"
+			return
+		}
+		*dest = t
+	}
+	parseDate("starts_at", &p.StartsAt)
+	parseDate("ends_at", &p.EndsAt)
+	if !p.EndsAt.IsZero() {
+		// the end date is the last day it can be used
+		p.EndsAt = p.EndsAt.AddDate(0, 0, 1)
+	}
+
+	if len(errs) == 0 {
+		if err := p.Validate(); err != nil {
+			errs["form"] = err.Error()
+		}
+	}
+	return p, errs
+}
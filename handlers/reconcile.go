@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/payments"
+	"github.com/seanomeara96/gates/views/partials"
+)
+
+// ErrNoPaymentRef is returned when reconciling an order that never got as far
+// as creating a checkout session.
+var ErrNoPaymentRef = errors.New("order has no payment reference")
+
+// ReconcileResult records what reconciling one order found and changed.
+type ReconcileResult struct {
+	OrderID       int
+	PaymentStatus payments.PaymentStatus
+	From          models.OrderStatus
+	To            models.OrderStatus
+}
+
+func (r ReconcileResult) Changed() bool {
+	return r.From != r.To
+}
+
+// ReconcileOrder brings an order in line with what the payment provider says
+// happened to its checkout session. It covers the same ground as the
+// webhooks, for when one was missed: customer details and payment method are
+// copied over, a paid session moves the order on as payment_intent.succeeded
+// would, and a session that expired unpaid cancels the order and releases
+// its stock.
+func (h *Handler) ReconcileOrder(ctx context.Context, orderID int) (ReconcileResult, error) {
+	order, err := h.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return ReconcileResult{}, fmt.Errorf("reconcile order: %w", err)
+	}
+	result := ReconcileResult{OrderID: orderID, From: order.Status, To: order.Status}
+
+	if !order.StripeRef.Valid || order.StripeRef.String == "" {
+		return result, fmt.Errorf("reconcile order (order_id=%d): %w", orderID, ErrNoPaymentRef)
+	}
+	sessionID := order.StripeRef.String
+
+	payment, err := h.payments.FetchPayment(ctx, sessionID)
+	if err != nil {
+		return result, fmt.Errorf("reconcile order (order_id=%d, session_id=%s): %w", orderID, sessionID, err)
+	}
+	result.PaymentStatus = payment.Status
+
+	if payment.Customer != nil {
+		applyCustomerDetails(order, payment.Customer)
+	}
+	if payment.PaymentMethod != "" {
+		order.PaymentMethod = sql.NullString{String: payment.PaymentMethod, Valid: true}
+	}
+	if payment.PaymentID != "" {
+		order.PaymentRef = sql.NullString{String: payment.PaymentID, Valid: true}
+	}
+	if err := h.orderRepo.UpdateOrder(order); err != nil {
+		return result, fmt.Errorf("reconcile order: update customer details (order_id=%d, session_id=%s): %w", orderID, sessionID, err)
+	}
+	if err := h.chargeVATByAddress(order, payment.Customer); err != nil {
+		return result, fmt.Errorf("reconcile order (order_id=%d, session_id=%s): %w", orderID, sessionID, err)
+	}
+
+	if order.Status.IsUnsettled() {
+		switch payment.Status {
+		case payments.PaymentSucceeded:
+			event := payments.Event{OrderID: orderID, SessionID: sessionID, PaymentID: payment.PaymentID}
+			if err := h.handlePaymentSucceeded(event); err != nil {
+				return result, fmt.Errorf("reconcile order (order_id=%d, session_id=%s): %w", orderID, sessionID, err)
+			}
+		case payments.PaymentCanceled:
+			note := fmt.Sprintf("checkout session %s closed without payment", sessionID)
+			if err := h.changeOrderStatus(orderID, models.OrderStatusCanceled, h.payments.Name(), note); err != nil {
+				return result, fmt.Errorf("reconcile order: cancel (order_id=%d, session_id=%s): %w", orderID, sessionID, err)
+			}
+		}
+	}
+
+	order, err = h.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return result, fmt.Errorf("reconcile order: %w", err)
+	}
+	result.To = order.Status
+	return result, nil
+}
+
+// ReconcileUnsettledOrders reconciles every order still waiting on its
+// payment. One order failing does not stop the rest; every failure is
+// returned joined together.
+func (h *Handler) ReconcileUnsettledOrders(ctx context.Context) ([]ReconcileResult, error) {
+	orders, err := h.orderRepo.GetOrdersByStatus(models.UnsettledOrderStatuses...)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile unsettled orders: %w", err)
+	}
+
+	var results []ReconcileResult
+	var errs []error
+	for _, order := range orders {
+		if err := ctx.Err(); err != nil {
+			return results, errors.Join(append(errs, err)...)
+		}
+		result, err := h.ReconcileOrder(ctx, order.ID)
+		if err != nil {
+			if errors.Is(err, ErrNoPaymentRef) {
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// FetchOrderDetailsFromStripe backs the dashboard's refresh button. It
+// reconciles one order with the payment provider and swaps in its updated row.
+func (h *Handler) FetchOrderDetailsFromStripe(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("parse order id from path: %w", err)
+	}
+
+	var errMsg string
+	if _, err := h.ReconcileOrder(r.Context(), orderID); err != nil {
+		log.Printf("[ERROR] refresh order %d: %v", orderID, err)
+		if errors.Is(err, ErrNoPaymentRef) {
+			errMsg = "No checkout session to refresh from"
+		} else {
+			errMsg = fmt.Sprintf("Could not refresh from %s", h.payments.Name())
+		}
+	}
+
+	order, err := h.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return fmt.Errorf("get order by id %d: %w", orderID, err)
+	}
+	return partials.AdminOrderRow(partials.AdminOrderRowProps{Order: *order, Error: errMsg}).Render(r.Context(), w)
+}
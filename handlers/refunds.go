@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/views/partials"
+)
+
+// RefundOrder handles the refund form in the order view modal. A full refund
+// gives back whatever has not been refunded yet, a partial one the chosen
+// quantity of each component. Either can put the refunded components back in
+// stock. The modal is re-rendered along with the dashboard row's badge and
+// select.
+func (h *Handler) RefundOrder(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("refund order: parse order id from path: %w", err)
+	}
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("refund order: parse form (id %d): %w", id, err)
+	}
+
+	refundErr, err := h.refundOrder(r.Context(), id, r.Form)
+	if err != nil {
+		return fmt.Errorf("refund order (id %d): %w", id, err)
+	}
+
+	props, err := h.orderViewProps(id)
+	if err != nil {
+		return fmt.Errorf("refund order: %w", err)
+	}
+	props.RefundError = refundErr
+	if err := partials.OrderViewModal(props).Render(r.Context(), w); err != nil {
+		return fmt.Errorf("refund order: render order view (id %d): %w", id, err)
+	}
+	if err := partials.OrderStatusBadge(partials.OrderStatusBadgeProps{OrderID: id, Status: props.Order.Status, OOB: true}).Render(r.Context(), w); err != nil {
+		return fmt.Errorf("refund order: render badge (id %d): %w", id, err)
+	}
+	return partials.OrderStatusSelect(props.Order, true).Render(r.Context(), w)
+}
+
+// refundOrder returns a message for the admin when the form asks for a refund
+// that cannot be made.
+func (h *Handler) refundOrder(ctx context.Context, orderID int, form url.Values) (string, error) {
+	order, err := h.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return "", err
+	}
+	if !order.PaymentRef.Valid || order.PaymentRef.String == "" {
+		return "There is no payment on this order to refund", nil
+	}
+	if !order.Status.CanRefund() {
+		return fmt.Sprintf("Orders that are %s cannot be refunded", order.Status.Label()), nil
+	}
+	note := strings.TrimSpace(form.Get("note"))
+	if len(note) > maxStatusNoteLength {
+		return fmt.Sprintf("Notes must be %d characters or fewer", maxStatusNoteLength), nil
+	}
+
+	items, err := h.orderRepo.GetOrderItems(orderID)
+	if err != nil {
+		return "", err
+	}
+	refunds, err := h.refundRepo.GetByOrderID(orderID)
+	if err != nil {
+		return "", err
+	}
+	discounts, err := h.orderRepo.GetOrderDiscounts(orderID)
+	if err != nil {
+		return "", err
+	}
+	total := models.OrderTotal(items)
+	for _, discount := range discounts {
+		total = total.Sub(discount.Amount)
+	}
+	// a full refund gives back shipping as well
+	total = total.Add(order.ShippingCost)
+	remaining := total.Amount - models.RefundedTotal(refunds).Amount
+	if remaining <= 0 {
+		return "This order has been refunded in full", nil
+	}
+
+	full := form.Get("mode") == "full"
+	var lines []models.RefundLine
+	var amount models.Money
+	restockQtys := map[int]int{}
+	for _, component := range models.RefundableComponents(items, discounts, refunds) {
+		qty := component.Refundable
+		if !full {
+			raw := strings.TrimSpace(form.Get("qty_" + component.Key()))
+			if raw == "" {
+				continue
+			}
+			qty, err = strconv.Atoi(raw)
+			if err != nil || qty < 0 || qty > component.Refundable {
+				return fmt.Sprintf("Enter a quantity from 0 to %d for %s", component.Refundable, component.Product.Name), nil
+			}
+		}
+		if qty == 0 {
+			continue
+		}
+		line := models.RefundLine{
+			OrderItemID: component.OrderItemID,
+			ProductID:   component.Product.Id,
+			Qty:         qty,
+			Amount:      component.RefundAmount(qty),
+		}
+		lines = append(lines, line)
+		amount = amount.Add(line.Amount)
+		restockQtys[line.ProductID] += qty
+	}
+	if !full && len(lines) == 0 {
+		return "Choose at least one component to refund", nil
+	}
+
+	// a full refund lets the provider work out what is left, anything else
+	// is capped at what has not been refunded yet
+	var requested int64
+	if !full {
+		requested = min(amount.Amount, remaining)
+	}
+	h.refunding.Store(orderID, struct{}{})
+	defer h.refunding.Delete(orderID)
+	refund, err := h.payments.Refund(ctx, order.PaymentRef.String, requested)
+	if err != nil {
+		if refund.ID == "" {
+			log.Printf("[ERROR] refund order %d: %v", orderID, err)
+			return fmt.Sprintf("%s did not make the refund", h.payments.Name()), nil
+		}
+		// the money went back but something after it failed, e.g. the
+		// provider's webhook. Record it all the same.
+		log.Printf("[ERROR] refund order %d: %v", orderID, err)
+	}
+
+	currency := total.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	restock := form.Get("restock") != ""
+	record := models.Refund{
+		OrderID:     orderID,
+		Provider:    h.payments.Name(),
+		ProviderRef: refund.ID,
+		Amount:      models.NewMoney(refund.Amount, currency),
+		Restocked:   restock,
+		Actor:       h.adminActor(),
+		Note:        sql.NullString{String: note, Valid: note != ""},
+		Lines:       lines,
+	}
+	if _, err := h.refundRepo.Create(record); err != nil {
+		return "", err
+	}
+
+	if restock {
+		movements, err := h.stockRepo.RestockProducts(orderID, restockQtys, models.StockMovementRefund)
+		if err != nil {
+			return "", err
+		}
+		if len(movements) > 0 {
+			h.productCache.Flush()
+		}
+	}
+
+	status := models.OrderStatusPartialRefunded
+	if refund.Amount >= remaining {
+		status = models.OrderStatusRefunded
+	}
+	statusNote := fmt.Sprintf("refunded %s", record.Amount)
+	if note != "" {
+		statusNote += ": " + note
+	}
+	// the provider's refund webhook may already have moved the order, in
+	// which case this is a no-op, or further than this would
+	if err := h.changeOrderStatus(orderID, status, h.adminActor(), statusNote); err != nil {
+		var transitionErr *models.StatusTransitionError
+		if !errors.As(err, &transitionErr) {
+			return "", err
+		}
+		log.Printf("[WARNING] refund order %d: %v", orderID, err)
+	}
+	return "", nil
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/views/pages"
+)
+
+// ChooseShipping sets how the customer wants their cart, from option, the
+// key of one of the cart's shipping options.
+func (h *Handler) ChooseShipping(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB limit
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("choose shipping: parse form: %w", err)
+	}
+	key := r.Form.Get("option")
+	for _, option := range cart.ShippingOptions {
+		if option.Key() != key {
+			continue
+		}
+		if err := h.shippingRepo.SetCartShipping(cart.ID, option); err != nil {
+			return fmt.Errorf("choose shipping: %w", err)
+		}
+		break
+	}
+	// an option that no longer applies leaves the choice as it was
+	return h.renderCartMain(w, cart.ID)
+}
+
+// GetShippingPage lists the shipping zones with their rate tables, with
+// forms to change them.
+func (h *Handler) GetShippingPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	return h.renderShippingPage(cart, w, r, "", url.Values{}, "")
+}
+
+// renderShippingPage shows the page with problem, when not empty, under the
+// form named formName, filled in again from form.
+func (h *Handler) renderShippingPage(cart models.Cart, w http.ResponseWriter, r *http.Request, formName string, form url.Values, problem string) error {
+	zones, err := h.shippingRepo.GetShippingZones()
+	if err != nil {
+		return fmt.Errorf("shipping page: %w", err)
+	}
+	props := pages.AdminShippingPageProps{
+		BaseProps: pages.BaseProps{
+			PageTitle: "Shipping",
+			Env:       h.cfg.Mode,
+			Cart:      cart,
+		},
+		Zones:          zones,
+		CollectAddress: h.cfg.ClickAndCollectAddress,
+		FormName:       formName,
+		Form:           form,
+		Error:          problem,
+	}
+	if problem != "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	return pages.AdminShipping(props).Render(r.Context(), w)
+}
+
+// CreateShippingZone adds a zone from the new zone form. Its rates are added
+// after.
+func (h *Handler) CreateShippingZone(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("create shipping zone: parse form: %w", err)
+	}
+	zone, problem := shippingZoneFromForm(r.Form)
+	if problem != "" {
+		return h.renderShippingPage(cart, w, r, "zone", r.Form, problem)
+	}
+	if _, err := h.shippingRepo.CreateShippingZone(zone); err != nil {
+		return fmt.Errorf("create shipping zone: %w", err)
+	}
+	http.Redirect(w, r, "/admin/shipping", http.StatusSeeOther)
+	return nil
+}
+
+// UpdateShippingZone saves a zone's name, countries and free shipping
+// threshold.
+func (h *Handler) UpdateShippingZone(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("update shipping zone: parse id from path: %w", err)
+	}
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("update shipping zone: parse form: %w", err)
+	}
+	zone, problem := shippingZoneFromForm(r.Form)
+	if problem != "" {
+		return h.renderShippingPage(cart, w, r, fmt.Sprintf("zone-%d", id), r.Form, problem)
+	}
+	zone.ID = id
+	if err := h.shippingRepo.UpdateShippingZone(zone); err != nil {
+		return fmt.Errorf("update shipping zone: %w", err)
+	}
+	http.Redirect(w, r, "/admin/shipping", http.StatusSeeOther)
+	return nil
+}
+
+// DeleteShippingZone stops delivering to a zone.
+func (h *Handler) DeleteShippingZone(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("delete shipping zone: parse id from path: %w", err)
+	}
+	if err := h.shippingRepo.DeleteShippingZone(id); err != nil {
+		return fmt.Errorf("delete shipping zone: %w", err)
+	}
+	http.Redirect(w, r, "/admin/shipping", http.StatusSeeOther)
+	return nil
+}
+
+// AddShippingRate adds a band to a zone's rate table.
+func (h *Handler) AddShippingRate(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	zoneID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("add shipping rate: parse zone id from path: %w", err)
+	}
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("add shipping rate: parse form: %w", err)
+	}
+	rate, problem := shippingRateFromForm(r.Form)
+	if problem != "" {
+		return h.renderShippingPage(cart, w, r, fmt.Sprintf("rate-%d", zoneID), r.Form, problem)
+	}
+	rate.ZoneID = zoneID
+	if _, err := h.shippingRepo.AddShippingRate(rate); err != nil {
+		return fmt.Errorf("add shipping rate: %w", err)
+	}
+	http.Redirect(w, r, "/admin/shipping", http.StatusSeeOther)
+	return nil
+}
+
+// DeleteShippingRate removes a band from a zone's rate table.
+func (h *Handler) DeleteShippingRate(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("delete shipping rate: parse id from path: %w", err)
+	}
+	if err := h.shippingRepo.DeleteShippingRate(id); err != nil {
+		return fmt.Errorf("delete shipping rate: %w", err)
+	}
+	http.Redirect(w, r, "/admin/shipping", http.StatusSeeOther)
+	return nil
+}
+
+// shippingZoneFromForm reads a zone from the admin form, or says what is
+// wrong with it.
+func shippingZoneFromForm(form url.Values) (models.ShippingZone, string) {
+	zone := models.ShippingZone{Name: strings.TrimSpace(form.Get("name"))}
+	countries, err := models.ParseCountries(form.Get("countries"))
+	if err != nil {
+		return zone, "Countries are two letter codes separated by commas, e.g. IE, GB"
+	}
+	zone.Countries = countries
+	if raw := strings.TrimSpace(form.Get("free_over")); raw != "" {
+		zone.FreeOver, err = models.ParseMoney(raw, models.DefaultCurrency)
+		if err != nil {
+			return zone, "Free shipping over must be an amount like 100.00"
+		}
+	}
+	if err := zone.Validate(); err != nil {
+		return zone, err.Error()
+	}
+	return zone, ""
+}
+
+// shippingRateFromForm reads a rate band from the admin form, or says what is
+// wrong with it. Bands by weight are in grams, those by value in euro.
+func shippingRateFromForm(form url.Values) (models.ShippingRate, string) {
+	rate := models.ShippingRate{
+		Service: strings.TrimSpace(form.Get("service")),
+		Basis:   models.ShippingBasis(form.Get("basis")),
+	}
+	if !rate.Basis.IsValid() {
+		return rate, "Choose whether the rate goes by weight or value"
+	}
+
+	bound := func(field string) (int64, bool) {
+		raw := strings.TrimSpace(form.Get(field))
+		if raw == "" {
+			return 0, true
+		}
+		if rate.Basis == models.ShippingByValue {
+			amount, err := models.ParseMoney(raw, models.DefaultCurrency)
+			return amount.Amount, err == nil
+		}
+		grams, err := strconv.ParseInt(raw, 10, 64)
+		return grams, err == nil
+	}
+	var ok bool
+	if rate.Min, ok = bound("min"); !ok {
+		return rate, "From must be grams, or an amount like 50.00"
+	}
+	if rate.Max, ok = bound("max"); !ok {
+		return rate, "Up to must be grams, or an amount like 50.00"
+	}
+
+	price, err := models.ParseMoney(strings.TrimSpace(form.Get("price")), models.DefaultCurrency)
+	if err != nil {
+		return rate, "Price must be an amount like 6.95"
+	}
+	rate.Price = price
+	if err := rate.Validate(); err != nil {
+		return rate, err.Error()
+	}
+	return rate, ""
+}
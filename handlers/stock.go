@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+)
+
+// checkoutSessionTTL is how long a Stripe checkout session, and the stock
+// reserved for it, stays open. Stripe rejects expiries less than 30 minutes
+// out, so leave a few minutes of headroom.
+const checkoutSessionTTL = 35 * time.Minute
+
+// reservationSweepInterval is how often expired reservations are released.
+const reservationSweepInterval = time.Minute
+
+// restockReasons maps the order statuses that hand stock back to the reason
+// recorded in the ledger. Refunds are not among them: whether refunded goods
+// go back on the shelf is up to staff when they refund, see RefundOrder, and
+// full refunds made on the provider's side restock what they cover when its
+// webhook arrives, see handleChargeRefunded.
+var restockReasons = map[models.OrderStatus]models.StockMovementReason{
+	models.OrderStatusCanceled: models.StockMovementCancel,
+}
+
+// commitOrderStock takes stock for a paid order. The product cache is flushed
+// whenever inventory levels actually change.
+func (h *Handler) commitOrderStock(orderID int) error {
+	movements, err := h.stockRepo.CommitOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if len(movements) > 0 {
+		h.productCache.Flush()
+	}
+	return nil
+}
+
+// restockForStatus returns an order's stock when it moves to a status that
+// releases it. Other statuses are left alone.
+func (h *Handler) restockForStatus(orderID int, status models.OrderStatus) error {
+	reason, found := restockReasons[status]
+	if !found {
+		return nil
+	}
+	movements, err := h.stockRepo.RestockOrder(orderID, reason)
+	if err != nil {
+		return fmt.Errorf("restock for status %s (order_id=%d): %w", status, orderID, err)
+	}
+	if len(movements) > 0 {
+		h.productCache.Flush()
+	}
+	return nil
+}
+
+// sweepReservations releases expired checkout reservations until ctx is
+// canceled. Expired reservations already stop counting against availability,
+// the sweep just makes their status say so.
+func (h *Handler) sweepReservations(ctx context.Context) {
+	ticker := time.NewTicker(reservationSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			orderIDs, err := h.stockRepo.ReleaseExpiredReservations(now)
+			if err != nil {
+				log.Printf("[ERROR] reservation sweeper: %v", err)
+				continue
+			}
+			if len(orderIDs) > 0 {
+				log.Printf("[INFO] reservation sweeper: released reservations for orders %v", orderIDs)
+			}
+		}
+	}
+}
@@ -15,18 +15,46 @@ func (h *Handler) GetSuccessPage(cart models.Cart, w http.ResponseWriter, r *htt
 	if err != nil {
 		return fmt.Errorf("invalid order_id query parameter %q: %w", orderIDStr, err)
 	}
-	if h.cfg.UseTempl {
-		props := pages.OrderSuccessPageProps{
-			BaseProps: pages.BaseProps{
-				PageTitle:       fmt.Sprintf("Order #%d Confirmed | Thank You", orderID),
-				MetaDescription: fmt.Sprintf("Thank you for your order. Your order #%d has been confirmed and is being processed.", orderID),
-				Cart:            cart,
-				Env:             h.cfg.Mode,
-			},
+	props := pages.OrderSuccessPageProps{
+		BaseProps: pages.BaseProps{
+			PageTitle:       fmt.Sprintf("Order #%d Confirmed | Thank You", orderID),
+			MetaDescription: fmt.Sprintf("Thank you for your order. Your order #%d has been confirmed and is being processed.", orderID),
+			Cart:            cart,
+			Env:             h.cfg.Mode,
+		},
+		OrderID: orderID,
+	}
+
+	// what was bought is only shown to whoever bought it, anyone can guess
+	// an order number
+	order, err := h.orderRepo.GetOrderByID(orderID)
+	if err == nil && order.CartID == cart.ID {
+		if props.Items, err = h.orderRepo.GetOrderItems(orderID); err != nil {
+			return fmt.Errorf("success page: %w", err)
+		}
+		if props.Discounts, err = h.orderRepo.GetOrderDiscounts(orderID); err != nil {
+			return fmt.Errorf("success page: %w", err)
+		}
+		for i := range props.Items {
+			props.Items[i].SetPrice()
 		}
+		lines := models.OrderTaxLines(*order, props.Items)
+		for _, line := range lines {
+			props.Total = props.Total.Add(line.Gross)
+		}
+		props.Order = order
+		props.VAT = models.VATBreakdown(lines...)
+	}
+
+	if h.cfg.UseTempl {
 		return pages.OrderSuccess(props).Render(r.Context(), w)
 	}
 	return h.rndr.Page(w, "success", map[string]any{
-		"OrderID": orderID,
+		"OrderID":   orderID,
+		"Order":     props.Order,
+		"Items":     props.Items,
+		"Discounts": props.Discounts,
+		"Total":     props.Total,
+		"VAT":       props.VAT,
 	})
 }
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/payments"
+	"github.com/seanomeara96/gates/views/pages"
+)
+
+// chargeVATByAddress charges order's VAT at the rates of the country it is
+// being delivered to, once the payment provider has collected the address.
+// Collected orders stay at the home country's rates.
+func (h *Handler) chargeVATByAddress(order *models.Order, details *payments.Customer) error {
+	if order.ShippingMethod == models.ShippingCollect || details == nil || details.Address == nil || details.Address.Country == "" {
+		return nil
+	}
+	rates, err := h.taxRepo.GetVATRates()
+	if err != nil {
+		return fmt.Errorf("charge vat by address (order_id=%d): %w", order.ID, err)
+	}
+	country := strings.ToUpper(details.Address.Country)
+	if err := h.orderRepo.SetTaxCountry(order.ID, country, rates); err != nil {
+		return fmt.Errorf("charge vat by address (order_id=%d): %w", order.ID, err)
+	}
+	return nil
+}
+
+// GetTaxPage lists the VAT rates by country, with forms to change them and
+// to export the VAT on orders.
+func (h *Handler) GetTaxPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	return h.renderTaxPage(cart, w, r, url.Values{}, "")
+}
+
+// renderTaxPage shows the page with problem, when not empty, over the rate
+// form, filled in again from form.
+func (h *Handler) renderTaxPage(cart models.Cart, w http.ResponseWriter, r *http.Request, form url.Values, problem string) error {
+	rates, err := h.taxRepo.GetVATRates()
+	if err != nil {
+		return fmt.Errorf("tax page: %w", err)
+	}
+	props := pages.AdminTaxPageProps{
+		BaseProps: pages.BaseProps{
+			PageTitle: "VAT",
+			Env:       h.cfg.Mode,
+			Cart:      cart,
+		},
+		Rates: rates,
+		Form:  form,
+		Error: problem,
+	}
+	if problem != "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	return pages.AdminTax(props).Render(r.Context(), w)
+}
+
+// SetVATRate adds a country's rate for a tax class, or changes it.
+func (h *Handler) SetVATRate(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("set vat rate: parse form: %w", err)
+	}
+	rate := models.VATRate{
+		Country: strings.ToUpper(strings.TrimSpace(r.Form.Get("country"))),
+		Class:   models.TaxClass(r.Form.Get("class")),
+	}
+	percent, err := models.ParseVATRate(r.Form.Get("rate"))
+	if err != nil {
+		return h.renderTaxPage(cart, w, r, r.Form, "Rate must be a percentage like 23 or 13.5")
+	}
+	rate.Rate = percent
+	if err := rate.Validate(); err != nil {
+		return h.renderTaxPage(cart, w, r, r.Form, err.Error())
+	}
+	if err := h.taxRepo.SetVATRate(rate); err != nil {
+		return fmt.Errorf("set vat rate: %w", err)
+	}
+	http.Redirect(w, r, "/admin/tax", http.StatusSeeOther)
+	return nil
+}
+
+// DeleteVATRate removes a country's rate for a tax class, so the home
+// country's applies there instead.
+func (h *Handler) DeleteVATRate(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("delete vat rate: parse form: %w", err)
+	}
+	if err := h.taxRepo.DeleteVATRate(r.Form.Get("country"), models.TaxClass(r.Form.Get("class"))); err != nil {
+		return fmt.Errorf("delete vat rate: %w", err)
+	}
+	http.Redirect(w, r, "/admin/tax", http.StatusSeeOther)
+	return nil
+}
+
+// ExportOrderVAT downloads the lines of paid orders as CSV with the net, VAT
+// and gross of each, for VAT returns. from and to, as 2006-01-02, limit it
+// to orders placed from the start of from to the end of to.
+func (h *Handler) ExportOrderVAT(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	var from, to time.Time
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		d, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("from must be a date like 2025-01-31, not %q", raw), http.StatusBadRequest)
+			return nil
+		}
+		from = d
+	}
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		d, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("to must be a date like 2025-01-31, not %q", raw), http.StatusBadRequest)
+			return nil
+		}
+		to = d.AddDate(0, 0, 1)
+	}
+
+	lines, err := h.orderRepo.GetVATLines(from, to)
+	if err != nil {
+		return fmt.Errorf("export order vat: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="order-vat.csv"`)
+	out := csv.NewWriter(w)
+	records := [][]string{{"order_id", "placed_at", "status", "country", "line", "qty", "tax_class", "vat_rate", "net", "vat", "gross", "currency"}}
+	for _, line := range lines {
+		records = append(records, []string{
+			strconv.Itoa(line.OrderID),
+			line.PlacedAt.UTC().Format(time.DateTime),
+			string(line.Status),
+			line.TaxCountry,
+			line.Description,
+			strconv.Itoa(line.Qty),
+			string(line.Tax.Class),
+			line.Tax.Percent(),
+			line.Tax.Net.Decimal(),
+			line.Tax.VAT.Decimal(),
+			line.Tax.Gross.Decimal(),
+			string(line.Tax.Gross.Currency),
+		})
+	}
+	if err := out.WriteAll(records); err != nil {
+		return fmt.Errorf("export order vat: write csv: %w", err)
+	}
+	return nil
+}
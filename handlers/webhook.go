@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/payments"
+	"github.com/seanomeara96/gates/repos"
+)
+
+// PaymentWebhook receives events from the configured payment provider. Every
+// verified event is stored before it is applied, and an event that was
+// already applied is acknowledged without applying it again, since providers
+// deliver at least once. Deliveries arriving out of order are safe because
+// order status changes must follow the transition graph.
+func (h *Handler) PaymentWebhook(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	const MaxBodyBytes = int64(65536)
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("[WARNING] webhook: read request body: %v", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return nil
+	}
+
+	event, err := h.payments.VerifyWebhook(payload, r.Header)
+	if err != nil {
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			log.Printf("[WARNING] webhook: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return nil
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return fmt.Errorf("%s webhook: %w", h.payments.Name(), err)
+	}
+
+	stored, err := h.webhookRepo.Record(h.payments.Name(), event.ID, string(event.Type), event.OrderID, payload)
+	if err != nil {
+		return fmt.Errorf("%s webhook: %w", h.payments.Name(), err)
+	}
+
+	claimed, err := h.webhookRepo.Claim(stored.ID, models.WebhookEventReceived, models.WebhookEventFailed)
+	if err != nil {
+		return fmt.Errorf("%s webhook: %w", h.payments.Name(), err)
+	}
+	if !claimed {
+		if stored.Status == models.WebhookEventProcessed || stored.Status == models.WebhookEventIgnored {
+			log.Printf("[INFO] webhook: skipping duplicate event %s (%s)", event.ID, stored.Status)
+			w.WriteHeader(http.StatusOK)
+			return nil
+		}
+		// another delivery is applying it right now; have the provider
+		// try again later rather than acknowledge something unfinished
+		log.Printf("[INFO] webhook: event %s is already being processed", event.ID)
+		w.WriteHeader(http.StatusConflict)
+		return nil
+	}
+
+	if err := h.applyWebhookEvent(stored.ID, event); err != nil {
+		return fmt.Errorf("%s webhook: %s (event_id=%s): %w", h.payments.Name(), event.Type, event.ID, err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// applyWebhookEvent applies a claimed event and records the outcome on it.
+func (h *Handler) applyWebhookEvent(id int, event payments.Event) error {
+	handled, err := h.dispatchWebhookEvent(event)
+
+	status, errMsg := models.WebhookEventProcessed, ""
+	switch {
+	case err != nil:
+		status, errMsg = models.WebhookEventFailed, err.Error()
+	case !handled:
+		status = models.WebhookEventIgnored
+	}
+	if finishErr := h.webhookRepo.Finish(id, status, errMsg); finishErr != nil {
+		return errors.Join(err, finishErr)
+	}
+	return err
+}
+
+// dispatchWebhookEvent reports false for event types the shop does not act on.
+func (h *Handler) dispatchWebhookEvent(event payments.Event) (bool, error) {
+	switch event.Type {
+	case payments.EventPaymentSucceeded:
+		return true, h.handlePaymentSucceeded(event)
+	case payments.EventCheckoutCompleted:
+		return true, h.handleCheckoutCompleted(event)
+	case payments.EventPaymentFailed:
+		return true, h.handlePaymentFailed(event)
+	case payments.EventCheckoutExpired:
+		return true, h.handleCheckoutExpired(event)
+	case payments.EventChargeRefunded:
+		return true, h.handleChargeRefunded(event)
+	case payments.EventDisputeCreated:
+		return true, h.handleDisputeCreated(event)
+	default:
+		log.Printf("[INFO] webhook: unhandled event type %s", event.Type)
+		return false, nil
+	}
+}
+
+func (h *Handler) handlePaymentSucceeded(event payments.Event) error {
+	if event.OrderID == 0 {
+		return fmt.Errorf("missing order_id (payment_id=%s)", event.PaymentID)
+	}
+	id := event.OrderID
+
+	order, err := h.orderRepo.GetOrderByID(id)
+	if err != nil {
+		return fmt.Errorf("get order (order_id=%d, payment_id=%s): %w", id, event.PaymentID, err)
+	}
+	if order.Status != models.OrderStatusProcessing && !order.CanTransitionTo(models.OrderStatusProcessing) {
+		// e.g. the order was canceled while the customer was paying. Retrying
+		// will not change that, so acknowledge the event and leave it to a person.
+		log.Printf("[WARNING] order %d paid while %s, not taking stock (payment_id=%s)", id, order.Status, event.PaymentID)
+		return nil
+	}
+
+	// the customer has paid either way, so a stock shortfall holds the
+	// order for a person to sort out rather than failing the webhook
+	status := models.OrderStatusProcessing
+	if err := h.commitOrderStock(id); err != nil {
+		if !errors.Is(err, repos.ErrInsufficientStock) {
+			return fmt.Errorf("commit stock (order_id=%d, payment_id=%s): %w", id, event.PaymentID, err)
+		}
+		log.Printf("[WARNING] order %d paid but out of stock, putting on hold: %v", id, err)
+		status = models.OrderStatusOnHold
+	}
+
+	if event.PaymentID != "" {
+		if err := h.orderRepo.UpdatePaymentRef(id, event.PaymentID); err != nil {
+			return fmt.Errorf("record payment ref (order_id=%d, payment_id=%s): %w", id, event.PaymentID, err)
+		}
+	}
+
+	note := fmt.Sprintf("payment %s succeeded", event.PaymentID)
+	if err := h.orderRepo.UpdateStatus(id, status, h.payments.Name(), note); err != nil {
+		return fmt.Errorf("update order status to %s (order_id=%d, payment_id=%s): %w", status, id, event.PaymentID, err)
+	}
+	return nil
+}
+
+func (h *Handler) handleCheckoutCompleted(event payments.Event) error {
+	if event.OrderID == 0 {
+		log.Printf("[WARNING] order id not found on checkout session %s", event.SessionID)
+		return nil
+	}
+	id := event.OrderID
+
+	details := event.Customer
+	if details == nil {
+		log.Printf("[WARNING] customer details on checkout session is nil order %d", id)
+		return nil
+	}
+
+	order, err := h.orderRepo.GetOrderByID(id)
+	if err != nil {
+		return fmt.Errorf("get order by id (order_id=%d, session_id=%s): %w", id, event.SessionID, err)
+	}
+
+	applyCustomerDetails(order, details)
+	if event.PaymentID != "" {
+		order.PaymentRef = sql.NullString{String: event.PaymentID, Valid: true}
+	}
+
+	if err := h.orderRepo.UpdateOrder(order); err != nil {
+		return fmt.Errorf("update order with customer details (order_id=%d, session_id=%s): %w", id, event.SessionID, err)
+	}
+	return h.chargeVATByAddress(order, details)
+}
+
+func (h *Handler) handlePaymentFailed(event payments.Event) error {
+	order, err := h.orderForEvent(event)
+	if err != nil || order == nil {
+		return err
+	}
+
+	note := "payment failed"
+	if event.Reason != "" {
+		note += ": " + event.Reason
+	}
+	return h.setStatusFromEvent(order, models.OrderStatusFailed, event, note)
+}
+
+// handleCheckoutExpired cancels an order whose checkout session closed
+// without payment, which releases its stock reservation.
+func (h *Handler) handleCheckoutExpired(event payments.Event) error {
+	order, err := h.orderForEvent(event)
+	if err != nil || order == nil {
+		return err
+	}
+	if !order.Status.IsUnsettled() {
+		// canceling is allowed from processing, but a session that expired
+		// must not cancel an order that was paid some other way
+		log.Printf("[WARNING] order %d is %s, ignoring expired checkout session %s", order.ID, order.Status, event.SessionID)
+		return nil
+	}
+
+	note := fmt.Sprintf("checkout session %s expired", event.SessionID)
+	return h.setStatusFromEvent(order, models.OrderStatusCanceled, event, note)
+}
+
+// handleChargeRefunded marks an order refunded once the whole charge has been
+// refunded, and partially refunded before that. Refunds made from the order
+// view restock as staff chose there. A full refund made on the provider's
+// side puts back the stock of whatever no refund here has covered.
+func (h *Handler) handleChargeRefunded(event payments.Event) error {
+	order, err := h.orderForEvent(event)
+	if err != nil || order == nil {
+		return err
+	}
+	if event.AmountRefunded <= 0 {
+		return nil
+	}
+
+	status := models.OrderStatusPartialRefunded
+	if event.AmountRefunded >= event.Amount {
+		status = models.OrderStatusRefunded
+	}
+	currency := models.Currency(strings.ToUpper(event.Currency))
+	note := fmt.Sprintf("refunded %s of %s", models.NewMoney(event.AmountRefunded, currency), models.NewMoney(event.Amount, currency))
+
+	var restockQtys map[int]int
+	if _, refunding := h.refunding.Load(order.ID); status == models.OrderStatusRefunded && !refunding {
+		restockQtys, err = h.unrefundedQtys(order.ID)
+		if err != nil {
+			return fmt.Errorf("charge refunded (order_id=%d): %w", order.ID, err)
+		}
+		if len(restockQtys) > 0 {
+			note += ", stock put back"
+		}
+	}
+	if err := h.setStatusFromEvent(order, status, event, note); err != nil {
+		return err
+	}
+	if len(restockQtys) == 0 {
+		return nil
+	}
+	movements, err := h.stockRepo.RestockProducts(order.ID, restockQtys, models.StockMovementRefund)
+	if err != nil {
+		return fmt.Errorf("charge refunded: restock (order_id=%d): %w", order.ID, err)
+	}
+	if len(movements) > 0 {
+		h.productCache.Flush()
+	}
+	return nil
+}
+
+// unrefundedQtys is how many of each product in an order no refund recorded
+// here covers, by product id.
+func (h *Handler) unrefundedQtys(orderID int) (map[int]int, error) {
+	items, err := h.orderRepo.GetOrderItems(orderID)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := h.refundRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	qtys := map[int]int{}
+	for _, component := range models.RefundableComponents(items, nil, refunds) {
+		if component.Refundable > 0 {
+			qtys[component.Product.Id] += component.Refundable
+		}
+	}
+	return qtys, nil
+}
+
+func (h *Handler) handleDisputeCreated(event payments.Event) error {
+	order, err := h.orderForEvent(event)
+	if err != nil || order == nil {
+		return err
+	}
+
+	note := "payment disputed"
+	if event.Reason != "" {
+		note += ": " + event.Reason
+	}
+	return h.setStatusFromEvent(order, models.OrderStatusChargeback, event, note)
+}
+
+// orderForEvent finds the order an event is about, by the order id the
+// provider echoed back or else by the payment id recorded when it was paid.
+// It returns a nil order, and logs, when the payment is not one of ours.
+func (h *Handler) orderForEvent(event payments.Event) (*models.Order, error) {
+	if event.OrderID != 0 {
+		order, err := h.orderRepo.GetOrderByID(event.OrderID)
+		if err != nil {
+			return nil, fmt.Errorf("get order (order_id=%d, payment_id=%s): %w", event.OrderID, event.PaymentID, err)
+		}
+		return order, nil
+	}
+	if event.PaymentID == "" {
+		return nil, fmt.Errorf("missing order_id and payment_id (session_id=%s)", event.SessionID)
+	}
+
+	order, err := h.orderRepo.GetOrderByPaymentRef(event.PaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[WARNING] webhook: no order for payment %s, ignoring %s", event.PaymentID, event.Type)
+			return nil, nil
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// setStatusFromEvent moves an order to status on the provider's say so.
+// Events can arrive late or out of order, so a move the transition graph does
+// not allow is logged and acknowledged rather than failed: retrying would not
+// change the answer.
+func (h *Handler) setStatusFromEvent(order *models.Order, status models.OrderStatus, event payments.Event, note string) error {
+	if order.Status != status && !order.CanTransitionTo(status) {
+		log.Printf("[WARNING] order %d is %s, not moving to %s for %s (event_id=%s)", order.ID, order.Status, status, event.Type, event.ID)
+		return nil
+	}
+	if err := h.changeOrderStatus(order.ID, status, h.payments.Name(), note); err != nil {
+		return fmt.Errorf("update order status to %s (order_id=%d): %w", status, order.ID, err)
+	}
+	return nil
+}
+
+// applyCustomerDetails copies what the provider collected at checkout onto
+// the order, leaving fields the customer did not fill in alone.
+func applyCustomerDetails(order *models.Order, details *payments.Customer) {
+	if details.Name != "" {
+		order.CustomerName = sql.NullString{String: details.Name, Valid: true}
+	}
+	if details.Email != "" {
+		order.CustomerEmail = sql.NullString{String: details.Email, Valid: true}
+	}
+	if details.Phone != "" {
+		order.CustomerPhone = sql.NullString{String: details.Phone, Valid: true}
+	}
+
+	if details.Address == nil {
+		return
+	}
+	b, err := json.Marshal(details.Address)
+	if err != nil {
+		log.Printf("[WARNING] could not marshal json for customer details shipping address order: %d", order.ID)
+		return
+	}
+	order.ShippingAddress = sql.NullString{String: string(b), Valid: true}
+}
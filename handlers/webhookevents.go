@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/views/pages"
+	"github.com/seanomeara96/gates/views/partials"
+)
+
+// webhookEventsPageLimit caps how many events the admin webhook log shows.
+const webhookEventsPageLimit = 100
+
+var webhookEventStatuses = []models.WebhookEventStatus{
+	models.WebhookEventReceived,
+	models.WebhookEventProcessing,
+	models.WebhookEventProcessed,
+	models.WebhookEventIgnored,
+	models.WebhookEventFailed,
+}
+
+// GetWebhookEventsPage lists recent payment webhooks, optionally filtered by
+// ?status=.
+func (h *Handler) GetWebhookEventsPage(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	status := models.WebhookEventStatus(r.URL.Query().Get("status"))
+	if !slices.Contains(webhookEventStatuses, status) {
+		status = ""
+	}
+
+	events, err := h.webhookRepo.List(status, webhookEventsPageLimit)
+	if err != nil {
+		return fmt.Errorf("webhook events page: %w", err)
+	}
+
+	props := pages.AdminWebhooksPageProps{
+		BaseProps: pages.BaseProps{
+			PageTitle: "Webhook Events",
+			Env:       h.cfg.Mode,
+			Cart:      cart,
+		},
+		Events: events,
+		Status: status,
+	}
+	return pages.AdminWebhooks(props).Render(r.Context(), w)
+}
+
+// ReplayWebhookEvent applies a stored event again, e.g. after fixing whatever
+// made it fail, and swaps in its updated row.
+func (h *Handler) ReplayWebhookEvent(cart models.Cart, w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("replay webhook event: parse id from path: %w", err)
+	}
+
+	stored, err := h.webhookRepo.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("replay webhook event: %w", err)
+	}
+
+	errMsg, err := h.replayWebhookEvent(stored)
+	if err != nil {
+		return fmt.Errorf("replay webhook event: %w", err)
+	}
+
+	stored, err = h.webhookRepo.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("replay webhook event: %w", err)
+	}
+	return partials.AdminWebhookEventRow(partials.AdminWebhookEventRowProps{Event: stored, Error: errMsg}).Render(r.Context(), w)
+}
+
+// replayWebhookEvent returns a message for the admin when the event could not
+// be replayed or failed again.
+func (h *Handler) replayWebhookEvent(stored models.WebhookEvent) (string, error) {
+	if !stored.Replayable() {
+		return fmt.Sprintf("Event is %s and cannot be replayed", stored.Status), nil
+	}
+	if stored.Provider != h.payments.Name() {
+		return fmt.Sprintf("Event came from %s, payments are using %s", stored.Provider, h.payments.Name()), nil
+	}
+
+	event, err := h.payments.ParseEvent([]byte(stored.Payload))
+	if err != nil {
+		log.Printf("[ERROR] replay webhook event %d: %v", stored.ID, err)
+		return "Stored payload could not be decoded", nil
+	}
+
+	claimed, err := h.webhookRepo.Claim(stored.ID, models.WebhookEventReplayable...)
+	if err != nil {
+		return "", err
+	}
+	if !claimed {
+		return "Event was applied by another delivery", nil
+	}
+
+	if err := h.applyWebhookEvent(stored.ID, event); err != nil {
+		log.Printf("[ERROR] replay webhook event %d: %v", stored.ID, err)
+		return "Replay failed", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,340 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// ErrChecksumMismatch is returned when a migration that has already been
+// applied no longer matches the embedded copy of its up script.
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// ErrUnknownVersion is returned when the database records a migration that
+// this binary does not know about, e.g. when running an older build against a
+// newer database.
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// file names look like 0001_initial_schema.up.sql
+var filenamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type appliedMigration struct {
+	version   int
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New returns a Migrator for the embedded migrations.
+func New(db *sql.DB) (*Migrator, error) {
+	if db == nil {
+		return nil, errors.New("new migrator: db cannot be nil")
+	}
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, fmt.Errorf("new migrator: %w", err)
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads every migration in the sql directory of fsys and returns them
+// ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: read dir: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := filenamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("load migrations: unexpected file name %q", entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("load migrations: parse version (file=%s): %w", entry.Name(), err)
+		}
+		name, direction := match[2], match[3]
+
+		b, err := fs.ReadFile(fsys, path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("load migrations: read file (file=%s): %w", entry.Name(), err)
+		}
+
+		m, found := byVersion[version]
+		if !found {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("load migrations: version %d has conflicting names %q and %q", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(b)
+			sum := sha256.Sum256(b)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("load migrations: version %d (%s) has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func (m *Migrator) ensureVersionTable() error {
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("ensure schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	if err := m.ensureVersionTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("select applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("select applied migrations: scan row: %w", err)
+		}
+		applied[a.version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select applied migrations: iterate rows: %w", err)
+	}
+	return applied, nil
+}
+
+// Verify checks that every applied migration is known to this binary and that
+// its checksum still matches the embedded up script.
+func (m *Migrator) Verify() error {
+	applied, err := m.applied()
+	if err != nil {
+		return fmt.Errorf("verify migrations: %w", err)
+	}
+	return m.verify(applied)
+}
+
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	known := map[int]Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+
+	for _, v := range versions {
+		a := applied[v]
+		migration, found := known[v]
+		if !found {
+			return fmt.Errorf("verify migrations: version %d (%s) is applied but not embedded: %w", v, a.name, ErrUnknownVersion)
+		}
+		if migration.Checksum != a.checksum {
+			return fmt.Errorf("verify migrations: version %d (%s) was applied with checksum %s but is now %s: %w", v, a.name, a.checksum, migration.Checksum, ErrChecksumMismatch)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration in version order. Each migration runs in
+// its own transaction together with its schema_migrations row.
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, fmt.Errorf("migrate up: %w", err)
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, fmt.Errorf("migrate up: %w", err)
+	}
+
+	var ran []Migration
+	for _, migration := range m.migrations {
+		if _, done := applied[migration.Version]; done {
+			continue
+		}
+		if err := m.apply(migration); err != nil {
+			return ran, fmt.Errorf("migrate up: %w", err)
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+func (m *Migrator) apply(migration Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("apply migration: begin transaction (version=%d, name=%s): %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.Exec(migration.Up); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("apply migration: exec up script (version=%d, name=%s): %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+		migration.Version, migration.Name, migration.Checksum, time.Now(),
+	); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("apply migration: record version (version=%d, name=%s): %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("apply migration: commit transaction (version=%d, name=%s): %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// Down rolls back the most recently applied migrations, at most steps of them.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("migrate down: steps must be at least 1 (steps=%d)", steps)
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, fmt.Errorf("migrate down: %w", err)
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, fmt.Errorf("migrate down: %w", err)
+	}
+
+	var reverted []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+		if _, done := applied[migration.Version]; !done {
+			continue
+		}
+		if err := m.revert(migration); err != nil {
+			return reverted, fmt.Errorf("migrate down: %w", err)
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+func (m *Migrator) revert(migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("revert migration: version %d (%s) has no down script", migration.Version, migration.Name)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("revert migration: begin transaction (version=%d, name=%s): %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.Exec(migration.Down); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("revert migration: exec down script (version=%d, name=%s): %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, migration.Version); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("revert migration: delete version (version=%d, name=%s): %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("revert migration: commit transaction (version=%d, name=%s): %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// Status reports every embedded migration and whether it has been applied.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, fmt.Errorf("migration status: %w", err)
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := Status{Migration: migration}
+		if a, done := applied[migration.Version]; done {
+			s.Applied = true
+			s.AppliedAt = a.appliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Run applies all pending embedded migrations to db. The server calls it on
+// startup so a fresh checkout gets a working schema.
+func Run(db *sql.DB) error {
+	m, err := New(db)
+	if err != nil {
+		return err
+	}
+	if _, err := m.Up(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	require.NoError(t, err)
+	return count > 0
+}
+
+func TestUpDownFromEmptyDatabase(t *testing.T) {
+	db := openTestDB(t)
+	m, err := New(db)
+	require.NoError(t, err)
+
+	ran, err := m.Up()
+	require.NoError(t, err)
+	require.Len(t, ran, len(m.migrations))
+
+	for _, table := range []string{"products", "compatibles", "cart", "orders", "order_item_components"} {
+		require.True(t, tableExists(t, db, table), table)
+	}
+
+	ran, err = m.Up()
+	require.NoError(t, err)
+	require.Empty(t, ran)
+
+	reverted, err := m.Down(len(m.migrations))
+	require.NoError(t, err)
+	require.Len(t, reverted, len(m.migrations))
+	require.False(t, tableExists(t, db, "products"))
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	for _, s := range statuses {
+		require.False(t, s.Applied)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	db := openTestDB(t)
+	m, err := New(db)
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	_, err = db.Exec(`UPDATE schema_migrations SET checksum = 'tampered' WHERE version = 1`)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, m.Verify(), ErrChecksumMismatch)
+	_, err = m.Up()
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestUnknownAppliedVersion(t *testing.T) {
+	db := openTestDB(t)
+	m, err := New(db)
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (9999, 'from_the_future', 'x', CURRENT_TIMESTAMP)`)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, m.Verify(), ErrUnknownVersion)
+}
+
+func TestLoadRejectsBadFiles(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"sql/0001_init.down.sql": {Data: []byte("DROP TABLE x;")},
+	})
+	require.Error(t, err, "migration without an up script")
+
+	_, err = Load(fstest.MapFS{
+		"sql/init.up.sql": {Data: []byte("CREATE TABLE x (id INTEGER);")},
+	})
+	require.Error(t, err, "file name without a version")
+
+	migrations, err := Load(fstest.MapFS{
+		"sql/0002_second.up.sql": {Data: []byte("SELECT 2;")},
+		"sql/0001_first.up.sql":  {Data: []byte("SELECT 1;")},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, migrations[0].Version)
+	require.Equal(t, 2, migrations[1].Version)
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS order_item_components;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS cart_item_component;
+DROP TABLE IF EXISTS cart_item;
+DROP TABLE IF EXISTS cart;
+DROP TABLE IF EXISTS contact;
+DROP TABLE IF EXISTS bundle_components;
+DROP TABLE IF EXISTS bundle_sizes;
+DROP TABLE IF EXISTS compatibles;
+DROP TABLE IF EXISTS products;
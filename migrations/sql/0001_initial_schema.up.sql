@@ -0,0 +1,105 @@
+-- Baseline schema. Every statement is guarded with IF NOT EXISTS so that
+-- databases created before the migration runner existed can be adopted
+-- without losing data.
+
+CREATE TABLE IF NOT EXISTS products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL,
+    name TEXT,
+    width REAL,
+    price REAL,
+    img TEXT,
+    tolerance REAL,
+    color TEXT,
+    inventory_level INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS compatibles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gate_id INTEGER,
+    extension_id INTEGER,
+    FOREIGN KEY (gate_id) REFERENCES products(id),
+    FOREIGN KEY (extension_id) REFERENCES products(id)
+);
+
+CREATE TABLE IF NOT EXISTS bundle_sizes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL,
+    size REAL NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bundle_components (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    product_type TEXT NOT NULL,
+    bundle_id INTEGER NOT NULL,
+    qty INTEGER NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE TABLE IF NOT EXISTS contact (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT,
+    email TEXT NOT NULL,
+    message TEXT NOT NULL,
+    timestamp DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS cart (
+    id              TEXT     PRIMARY KEY,
+    created_at      DATETIME NOT NULL,
+    last_updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS cart_item (
+    id          TEXT     NOT NULL,
+    cart_id     TEXT     NOT NULL,
+    qty         INTEGER  NOT NULL,
+    created_at  DATETIME NOT NULL,
+    FOREIGN KEY (cart_id) REFERENCES cart(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS cart_item_component (
+    cart_item_id TEXT    NOT NULL,
+    cart_id      TEXT    NOT NULL,
+    product_id   TEXT    NOT NULL,           -- only product_id and qty are stored, the rest is hydrated from products
+    qty          INTEGER NOT NULL,
+    created_at   DATETIME NOT NULL,
+    FOREIGN KEY (cart_item_id) REFERENCES cart_item(id) ON DELETE CASCADE,
+    FOREIGN KEY (cart_id)      REFERENCES cart(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    cart_id INTEGER NOT NULL,
+    status TEXT DEFAULT 'pending_payment',
+    customer_name TEXT,
+    customer_email TEXT,
+    customer_phone TEXT,
+    shipping_address TEXT,
+    billing_address TEXT,
+    payment_method TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    stripe_ref TEXT,
+    session_id TEXT
+);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL,
+    item_name TEXT NOT NULL,
+    item_quantity INTEGER NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE TABLE IF NOT EXISTS order_item_components (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL,
+    order_item_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    product_name TEXT NOT NULL,
+    product_price REAL NOT NULL,
+    product_qty INTEGER NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id),
+    FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+);
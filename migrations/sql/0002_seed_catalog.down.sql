@@ -0,0 +1,2 @@
+DELETE FROM compatibles WHERE id BETWEEN 1 AND 6;
+DELETE FROM products WHERE id BETWEEN 1 AND 8;
@@ -0,0 +1,19 @@
+-- Starter catalog so a fresh database has something to build bundles from.
+
+INSERT OR IGNORE INTO products (id, type, name, width, price, img, tolerance, color, inventory_level) VALUES
+    (1, 'gate', 'BabyDan Premier True Pressure Fit Safety Gate', 76.0, 76.0, 'https://cdn.babydan.com/perfion/image.aspx?id=1f446ea7-8251-4ef2-84e9-88bb4ac9f76a&size=600x600', 6.0, 'white', 10),
+    (2, 'gate', 'BabyDan Premier True Pressure Fit Safety Gate', 76.0, 76.0, 'https://cdn.babydan.com/perfion/image.aspx?id=38566fd5-140f-461b-a7ca-13214f518efd&size=600x600', 6.0, 'black', 10),
+    (3, 'extension', 'BabyDan Premier Gate Extension Large', 64.0, 64.0, 'https://cdn.babydan.com/perfion/image.aspx?id=32790cd3-396a-4ce3-a5f9-c242eeee8d12&size=600x600', 0.0, 'white', 10),
+    (4, 'extension', 'Babydan Premier Gate Extension Medium', 32.0, 32.0, 'https://cdn.babydan.com/perfion/image.aspx?id=cba4aa1c-604f-499f-9805-7edb3ea463ce&size=600x600', 0.0, 'white', 10),
+    (5, 'extension', 'BabyDan Premier Gate Extension Small', 7.0, 7.0, 'https://cdn.babydan.com/perfion/image.aspx?id=e5577f8b-5312-47fa-833a-ec0c1e0f8b3a&size=600x600', 0.0, 'white', 10),
+    (6, 'extension', 'BabyDan Premier Gate Extension Large', 64.0, 64.0, 'https://cdn.babydan.com/perfion/image.aspx?id=ed053af9-c9c5-4fa0-ae15-500374732860&size=600x600', 0.0, 'black', 10),
+    (7, 'extension', 'Babydan Premier Gate Extension Medium', 32.0, 32.0, 'https://cdn.babydan.com/perfion/image.aspx?id=d86c79d2-25c7-4d18-a547-8077cd67af3d&size=600x600', 0.0, 'black', 10),
+    (8, 'extension', 'BabyDan Premier Gate Extension Small', 7.0, 7.0, 'https://cdn.babydan.com/perfion/image.aspx?id=a3316957-04ae-4d2a-ae03-33efe2066d1a&size=600x600', 0.0, 'black', 10);
+
+INSERT OR IGNORE INTO compatibles (id, gate_id, extension_id) VALUES
+    (1, 1, 3),
+    (2, 1, 4),
+    (3, 1, 5),
+    (4, 2, 6),
+    (5, 2, 7),
+    (6, 2, 8);
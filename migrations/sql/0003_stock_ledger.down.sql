@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_stock_movements_product_id;
+DROP INDEX IF EXISTS idx_stock_movements_order_id;
+DROP TABLE IF EXISTS stock_movements;
+ALTER TABLE products DROP COLUMN allow_backorder;
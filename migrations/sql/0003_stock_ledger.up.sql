@@ -0,0 +1,15 @@
+ALTER TABLE products ADD COLUMN allow_backorder INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE stock_movements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    order_id INTEGER,
+    delta INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES products(id),
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_stock_movements_order_id ON stock_movements(order_id);
+CREATE INDEX idx_stock_movements_product_id ON stock_movements(product_id);
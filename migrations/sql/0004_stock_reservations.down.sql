@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_stock_reservations_status_expires;
+DROP INDEX IF EXISTS idx_stock_reservations_product_status;
+DROP TABLE IF EXISTS stock_reservations;
@@ -0,0 +1,15 @@
+CREATE TABLE stock_reservations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    qty INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active',
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id),
+    FOREIGN KEY (product_id) REFERENCES products(id),
+    UNIQUE (order_id, product_id)
+);
+
+CREATE INDEX idx_stock_reservations_product_status ON stock_reservations(product_id, status);
+CREATE INDEX idx_stock_reservations_status_expires ON stock_reservations(status, expires_at);
@@ -0,0 +1,17 @@
+CREATE TABLE order_status_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    note TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_order_status_history_order_id ON order_status_history(order_id);
+
+-- give existing orders a starting point so their history is never empty
+INSERT INTO order_status_history (order_id, from_status, to_status, actor, note, created_at)
+SELECT id, NULL, COALESCE(status, 'pending_payment'), 'system', 'status at the time history was introduced', COALESCE(created_at, CURRENT_TIMESTAMP)
+  FROM orders;
@@ -0,0 +1,11 @@
+ALTER TABLE order_item_components DROP COLUMN product_currency;
+ALTER TABLE order_item_components ADD COLUMN product_price_real REAL NOT NULL DEFAULT 0;
+UPDATE order_item_components SET product_price_real = product_price / 100.0;
+ALTER TABLE order_item_components DROP COLUMN product_price;
+ALTER TABLE order_item_components RENAME COLUMN product_price_real TO product_price;
+
+ALTER TABLE products DROP COLUMN currency;
+ALTER TABLE products ADD COLUMN price_real REAL;
+UPDATE products SET price_real = price / 100.0;
+ALTER TABLE products DROP COLUMN price;
+ALTER TABLE products RENAME COLUMN price_real TO price;
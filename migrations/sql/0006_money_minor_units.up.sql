@@ -0,0 +1,16 @@
+-- Prices were stored as REAL euros, which cannot hold every cent exactly.
+-- Store them as integer minor units alongside an ISO 4217 currency code.
+-- SQLite cannot change a column's type, so each price is copied into a new
+-- column which then takes the old one's name.
+
+ALTER TABLE products ADD COLUMN price_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE products SET price_minor = CAST(ROUND(COALESCE(price, 0) * 100) AS INTEGER);
+ALTER TABLE products DROP COLUMN price;
+ALTER TABLE products RENAME COLUMN price_minor TO price;
+ALTER TABLE products ADD COLUMN currency TEXT NOT NULL DEFAULT 'EUR';
+
+ALTER TABLE order_item_components ADD COLUMN product_price_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE order_item_components SET product_price_minor = CAST(ROUND(product_price * 100) AS INTEGER);
+ALTER TABLE order_item_components DROP COLUMN product_price;
+ALTER TABLE order_item_components RENAME COLUMN product_price_minor TO product_price;
+ALTER TABLE order_item_components ADD COLUMN product_currency TEXT NOT NULL DEFAULT 'EUR';
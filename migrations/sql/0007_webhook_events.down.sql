@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_webhook_events_order_id;
+DROP INDEX IF EXISTS idx_webhook_events_status;
+DROP TABLE IF EXISTS webhook_events;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_payment_ref;
+ALTER TABLE orders DROP COLUMN payment_ref;
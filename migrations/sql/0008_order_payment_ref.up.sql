@@ -0,0 +1,5 @@
+-- charge and dispute events only carry the payment id, so keep it on the
+-- order to find it again
+ALTER TABLE orders ADD COLUMN payment_ref TEXT;
+
+CREATE INDEX idx_orders_payment_ref ON orders(payment_ref);
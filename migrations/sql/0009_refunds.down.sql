@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_refund_lines_refund_id;
+DROP TABLE IF EXISTS refund_lines;
+DROP INDEX IF EXISTS idx_refunds_order_id;
+DROP TABLE IF EXISTS refunds;
@@ -0,0 +1,29 @@
+CREATE TABLE refunds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL,
+    provider TEXT NOT NULL,
+    provider_ref TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'EUR',
+    restocked INTEGER NOT NULL DEFAULT 0,
+    actor TEXT NOT NULL,
+    note TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_refunds_order_id ON refunds(order_id);
+
+-- the components a partial refund covered, so they are not refunded twice
+CREATE TABLE refund_lines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    refund_id INTEGER NOT NULL,
+    order_item_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    qty INTEGER NOT NULL,
+    amount INTEGER NOT NULL,
+    FOREIGN KEY (refund_id) REFERENCES refunds(id),
+    FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+);
+
+CREATE INDEX idx_refund_lines_refund_id ON refund_lines(refund_id);
@@ -0,0 +1,11 @@
+-- discounts taken off an order at checkout, so refunds know what was charged
+CREATE TABLE order_discounts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL,
+    label TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'EUR',
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_order_discounts_order_id ON order_discounts(order_id);
@@ -0,0 +1,2 @@
+UPDATE bundle_sizes SET type = 'pressure fit' WHERE type = 'pressure_fit';
+ALTER TABLE products DROP COLUMN mounting_type;
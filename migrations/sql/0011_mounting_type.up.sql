@@ -0,0 +1,6 @@
+-- gates are either held in place by pressure or screwed to the wall, and
+-- each kind is sized differently. Everything sold so far is pressure fit.
+ALTER TABLE products ADD COLUMN mounting_type TEXT NOT NULL DEFAULT 'pressure_fit';
+
+-- requested sizes are now recorded against the mounting type asked for
+UPDATE bundle_sizes SET type = 'pressure_fit' WHERE type = 'pressure fit';
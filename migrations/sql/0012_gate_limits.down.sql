@@ -0,0 +1,2 @@
+ALTER TABLE products DROP COLUMN max_extensions;
+ALTER TABLE products DROP COLUMN max_span;
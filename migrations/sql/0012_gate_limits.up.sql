@@ -0,0 +1,7 @@
+-- the widest opening the manufacturer says a gate can safely span with
+-- extensions, and the most extensions it takes. 0 means no limit.
+ALTER TABLE products ADD COLUMN max_span REAL NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN max_extensions INTEGER NOT NULL DEFAULT 0;
+
+-- the builder used to stop every gate at 220cm
+UPDATE products SET max_span = 220 WHERE type = 'gate';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_bundle_components_bundle_id;
+DROP INDEX IF EXISTS idx_products_slug;
+ALTER TABLE products DROP COLUMN slug;
@@ -0,0 +1,5 @@
+-- saved bundles are products of type bundle with their gate and extensions
+-- in bundle_components. The slug is their public address, /bundles/{slug}.
+ALTER TABLE products ADD COLUMN slug TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_slug ON products(slug) WHERE slug != '';
+CREATE INDEX IF NOT EXISTS idx_bundle_components_bundle_id ON bundle_components(bundle_id);
@@ -0,0 +1,12 @@
+-- the builder's bundles for every whole centimetre it supports, worked out
+-- ahead of time from the catalog and replaced whenever the catalog changes.
+-- Only which products and how many are kept; prices and stock are looked up
+-- when a bundle is served.
+CREATE TABLE IF NOT EXISTS bundle_lookup (
+    mounting_type TEXT    NOT NULL,
+    goal          TEXT    NOT NULL,
+    width         INTEGER NOT NULL, -- cm
+    rank          INTEGER NOT NULL, -- order the builder returned them in
+    item_key      TEXT    NOT NULL, -- models.CartItemKey of the gate and extensions
+    PRIMARY KEY (mounting_type, goal, width, rank)
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_bundle_sizes_created_at;
+DROP INDEX IF EXISTS idx_bundle_sizes_cart_id;
+ALTER TABLE bundle_sizes DROP COLUMN created_at;
+ALTER TABLE bundle_sizes DROP COLUMN added_to_cart;
+ALTER TABLE bundle_sizes DROP COLUMN outcome;
+ALTER TABLE bundle_sizes DROP COLUMN cart_id;
@@ -0,0 +1,10 @@
+-- what became of each width asked of the builder: the cart that asked, how
+-- well the catalog served it, and whether that cart went on to add a bundle
+-- that fits it. Rows from before this have no cart, outcome or time.
+ALTER TABLE bundle_sizes ADD COLUMN cart_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE bundle_sizes ADD COLUMN outcome TEXT NOT NULL DEFAULT '';
+ALTER TABLE bundle_sizes ADD COLUMN added_to_cart INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE bundle_sizes ADD COLUMN created_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_bundle_sizes_cart_id ON bundle_sizes (cart_id);
+CREATE INDEX IF NOT EXISTS idx_bundle_sizes_created_at ON bundle_sizes (created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE cart_item_component DROP COLUMN currency;
+ALTER TABLE cart_item_component DROP COLUMN price;
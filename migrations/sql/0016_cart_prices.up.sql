@@ -0,0 +1,5 @@
+-- the unit price each cart component was last shown to the customer at, so
+-- the cart can tell them when the catalog price has since changed.
+-- Components from before this have none and are not compared.
+ALTER TABLE cart_item_component ADD COLUMN price INTEGER;
+ALTER TABLE cart_item_component ADD COLUMN currency TEXT;
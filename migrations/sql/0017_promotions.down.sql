@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_order_discounts_promotion_id;
+ALTER TABLE order_discounts DROP COLUMN free_shipping;
+ALTER TABLE order_discounts DROP COLUMN promotion_id;
+DROP TABLE IF EXISTS cart_promotion_codes;
+DROP TABLE IF EXISTS promotions;
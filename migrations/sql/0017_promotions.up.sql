@@ -0,0 +1,36 @@
+-- sales: codes customers enter on their cart, and automatic promotions,
+-- those without a code, that apply to every cart
+CREATE TABLE promotions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    code TEXT UNIQUE COLLATE NOCASE,
+    kind TEXT NOT NULL,
+    percent INTEGER NOT NULL DEFAULT 0,
+    amount INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT 'EUR',
+    min_extensions INTEGER NOT NULL DEFAULT 0,
+    gate_id INTEGER NOT NULL DEFAULT 0,
+    extension_id INTEGER NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    usage_limit INTEGER NOT NULL DEFAULT 0,
+    per_customer_limit INTEGER NOT NULL DEFAULT 0,
+    active INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- the code a cart has entered, with the email given for codes limited per
+-- customer
+CREATE TABLE cart_promotion_codes (
+    cart_id TEXT PRIMARY KEY,
+    promotion_id INTEGER NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cart_id) REFERENCES cart(id) ON DELETE CASCADE,
+    FOREIGN KEY (promotion_id) REFERENCES promotions(id)
+);
+
+-- which promotion gave an order's discount, so its uses can be counted
+ALTER TABLE order_discounts ADD COLUMN promotion_id INTEGER REFERENCES promotions(id);
+ALTER TABLE order_discounts ADD COLUMN free_shipping INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX idx_order_discounts_promotion_id ON order_discounts(promotion_id);
@@ -0,0 +1,9 @@
+ALTER TABLE orders DROP COLUMN shipping_currency;
+ALTER TABLE orders DROP COLUMN shipping_amount;
+ALTER TABLE orders DROP COLUMN shipping_name;
+ALTER TABLE orders DROP COLUMN shipping_method;
+DROP TABLE IF EXISTS cart_shipping;
+DROP INDEX IF EXISTS idx_shipping_rates_zone_id;
+DROP TABLE IF EXISTS shipping_rates;
+DROP TABLE IF EXISTS shipping_zones;
+ALTER TABLE products DROP COLUMN weight;
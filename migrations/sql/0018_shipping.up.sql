@@ -0,0 +1,64 @@
+-- what each product weighs in grams, for weight based shipping rates
+ALTER TABLE products ADD COLUMN weight INTEGER NOT NULL DEFAULT 0;
+UPDATE products SET weight = 5500 WHERE type = 'gate';
+UPDATE products SET weight = 1200 WHERE type = 'extension' AND width >= 60;
+UPDATE products SET weight = 700 WHERE type = 'extension' AND width >= 20 AND width < 60;
+UPDATE products SET weight = 300 WHERE type = 'extension' AND width < 20;
+
+-- where orders can be delivered to. countries are ISO 3166-1 alpha-2 codes
+-- separated by commas. Orders worth free_over or more, after discounts, are
+-- delivered free, 0 for never.
+CREATE TABLE shipping_zones (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    countries TEXT NOT NULL,
+    free_over INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT 'EUR'
+);
+
+-- what delivery to a zone costs. Each service, e.g. "Standard delivery", is
+-- a table of bands by order weight in grams or order value in cents, from
+-- min_value up to but not including max_value, 0 for no upper bound.
+CREATE TABLE shipping_rates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    zone_id INTEGER NOT NULL,
+    service TEXT NOT NULL,
+    basis TEXT NOT NULL,
+    min_value INTEGER NOT NULL DEFAULT 0,
+    max_value INTEGER NOT NULL DEFAULT 0,
+    price INTEGER NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'EUR',
+    FOREIGN KEY (zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_shipping_rates_zone_id ON shipping_rates(zone_id);
+
+INSERT INTO shipping_zones (id, name, countries, free_over) VALUES
+    (1, 'Ireland', 'IE', 10000),
+    (2, 'Northern Ireland and Great Britain', 'GB', 0),
+    (3, 'European Union', 'AT,BE,BG,CY,CZ,DE,DK,EE,ES,FI,FR,GR,HR,HU,IT,LT,LU,LV,MT,NL,PL,PT,RO,SE,SI,SK', 25000);
+
+INSERT INTO shipping_rates (zone_id, service, basis, min_value, max_value, price) VALUES
+    (1, 'Standard delivery', 'weight', 0, 10000, 695),
+    (1, 'Standard delivery', 'weight', 10000, 20000, 995),
+    (1, 'Standard delivery', 'weight', 20000, 0, 1495),
+    (2, 'Standard delivery', 'weight', 0, 10000, 1295),
+    (2, 'Standard delivery', 'weight', 10000, 0, 1995),
+    (3, 'Standard delivery', 'value', 0, 10000, 1995),
+    (3, 'Standard delivery', 'value', 10000, 0, 1495);
+
+-- how the customer chose to get their cart: delivered to a zone by one of
+-- its services, or collected
+CREATE TABLE cart_shipping (
+    cart_id TEXT PRIMARY KEY,
+    method TEXT NOT NULL,
+    zone_id INTEGER,
+    service TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (cart_id) REFERENCES cart(id) ON DELETE CASCADE
+);
+
+-- how an order is getting to the customer and what they paid for it. Orders
+-- from before delivery methods have none.
+ALTER TABLE orders ADD COLUMN shipping_method TEXT;
+ALTER TABLE orders ADD COLUMN shipping_name TEXT;
+ALTER TABLE orders ADD COLUMN shipping_amount INTEGER;
+ALTER TABLE orders ADD COLUMN shipping_currency TEXT;
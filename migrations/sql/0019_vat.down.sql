@@ -0,0 +1,10 @@
+ALTER TABLE orders DROP COLUMN shipping_vat;
+ALTER TABLE orders DROP COLUMN shipping_vat_rate;
+ALTER TABLE orders DROP COLUMN tax_country;
+ALTER TABLE order_item_components DROP COLUMN vat_amount;
+ALTER TABLE order_item_components DROP COLUMN net_amount;
+ALTER TABLE order_item_components DROP COLUMN gross_amount;
+ALTER TABLE order_item_components DROP COLUMN vat_rate;
+ALTER TABLE order_item_components DROP COLUMN tax_class;
+DROP TABLE IF EXISTS tax_rates;
+ALTER TABLE products DROP COLUMN tax_class;
@@ -0,0 +1,57 @@
+-- which of a country's VAT rates each product is sold at
+ALTER TABLE products ADD COLUMN tax_class TEXT NOT NULL DEFAULT 'standard';
+
+-- what each country charges on each tax class, in basis points so 2300 is
+-- 23%. Orders are charged at the rates of the country they are delivered
+-- to, or Ireland's when it has none, e.g. for GB until rates are added.
+CREATE TABLE tax_rates (
+    country TEXT NOT NULL,
+    tax_class TEXT NOT NULL,
+    rate INTEGER NOT NULL,
+    PRIMARY KEY (country, tax_class)
+);
+
+INSERT INTO tax_rates (country, tax_class, rate) VALUES
+    ('IE', 'standard', 2300),
+    ('IE', 'reduced', 1350),
+    ('IE', 'zero', 0),
+    ('AT', 'standard', 2000),
+    ('BE', 'standard', 2100),
+    ('BG', 'standard', 2000),
+    ('CY', 'standard', 1900),
+    ('CZ', 'standard', 2100),
+    ('DE', 'standard', 1900),
+    ('DK', 'standard', 2500),
+    ('EE', 'standard', 2400),
+    ('ES', 'standard', 2100),
+    ('FI', 'standard', 2550),
+    ('FR', 'standard', 2000),
+    ('GR', 'standard', 2400),
+    ('HR', 'standard', 2500),
+    ('HU', 'standard', 2700),
+    ('IT', 'standard', 2200),
+    ('LT', 'standard', 2100),
+    ('LU', 'standard', 1700),
+    ('LV', 'standard', 2100),
+    ('MT', 'standard', 1800),
+    ('NL', 'standard', 2100),
+    ('PL', 'standard', 2300),
+    ('PT', 'standard', 2300),
+    ('RO', 'standard', 2100),
+    ('SE', 'standard', 2500),
+    ('SI', 'standard', 2200),
+    ('SK', 'standard', 2300);
+
+-- the VAT in each order line: gross is what the customer paid for the line,
+-- after its share of the order's discounts, and net and vat are split out of
+-- it. Lines of orders placed before VAT was recorded have none.
+ALTER TABLE order_item_components ADD COLUMN tax_class TEXT;
+ALTER TABLE order_item_components ADD COLUMN vat_rate INTEGER;
+ALTER TABLE order_item_components ADD COLUMN gross_amount INTEGER;
+ALTER TABLE order_item_components ADD COLUMN net_amount INTEGER;
+ALTER TABLE order_item_components ADD COLUMN vat_amount INTEGER;
+
+-- the country an order's VAT was charged at and the VAT in its shipping
+ALTER TABLE orders ADD COLUMN tax_country TEXT;
+ALTER TABLE orders ADD COLUMN shipping_vat_rate INTEGER;
+ALTER TABLE orders ADD COLUMN shipping_vat INTEGER;
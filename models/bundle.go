@@ -1,7 +1,10 @@
 package models
 
 import (
+	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
 )
 
 type Bundle struct {
@@ -20,22 +23,90 @@ func (b *Bundle) ComputeMetaData() {
 	b.setQty()
 	b.setWidth()
 	b.setTolerance()
+	b.setMountingType()
+	b.setMaxSpan()
 }
 
 func (b *Bundle) ToProduct() Product {
 	b.ComputeMetaData()
 	return Product{
-		Type:      "bundle",
-		Name:      b.Name,
-		Width:     b.Width,
-		Price:     b.Price,
-		Img:       b.Img,
-		Color:     b.Color,
-		Tolerance: b.Tolerance,
-		Qty:       b.Qty,
+		Type:         "bundle",
+		Name:         b.Name,
+		Width:        b.Width,
+		Price:        b.Price,
+		Img:          b.Img,
+		Color:        b.Color,
+		Tolerance:    b.Tolerance,
+		Qty:          b.Qty,
+		MountingType: b.MountingType,
+		MaxSpan:      b.MaxSpan,
+		Slug:         b.Slug,
 	}
 }
 
+// Validate reports why the components can't be sold together as a bundle.
+// The gate comes first, once, followed by extensions from compatible, each
+// listed once. There can be no more extensions than the gate takes, and
+// together they have to fit an opening no wider than the gate can safely span.
+func (b Bundle) Validate(compatible []Product) error {
+	if len(b.Components) == 0 {
+		return fmt.Errorf("a bundle needs a gate")
+	}
+	gate := b.Components[0]
+	if gate.Type != ProductTypeGate {
+		return fmt.Errorf("product %d is not a gate", gate.Id)
+	}
+	if gate.Qty != 1 {
+		return fmt.Errorf("a bundle takes one gate, not %d", gate.Qty)
+	}
+
+	fits := map[int]bool{}
+	for _, extension := range compatible {
+		fits[extension.Id] = true
+	}
+	seen := map[int]bool{gate.Id: true}
+	pieces := 0
+	for _, extension := range b.Components[1:] {
+		switch {
+		case extension.Type != ProductTypeExtension:
+			return fmt.Errorf("product %d is not an extension", extension.Id)
+		case extension.Qty < 1:
+			return fmt.Errorf("extension %d needs a quantity", extension.Id)
+		case seen[extension.Id]:
+			return fmt.Errorf("extension %d is listed twice", extension.Id)
+		case !fits[extension.Id]:
+			return fmt.Errorf("extension %d does not fit gate %d", extension.Id, gate.Id)
+		}
+		seen[extension.Id] = true
+		pieces += extension.Qty
+	}
+	if gate.MaxExtensions > 0 && pieces > gate.MaxExtensions {
+		return fmt.Errorf("gate %d takes at most %d extensions, not %d", gate.Id, gate.MaxExtensions, pieces)
+	}
+
+	b.Product = Product{}
+	b.ComputeMetaData()
+	if b.MinWidth() > b.MaxWidth()+fitEpsilon {
+		return fmt.Errorf("gate %d can't safely span the %gcm these extensions need", gate.Id, b.MinWidth())
+	}
+	return nil
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// BundleSlug is the address a saved bundle is shared at, the gate's name and
+// the CartItemKey of the components, e.g. "babydan-premier-gate-1-1-5-4". It
+// only depends on the components, so saving the same bundle twice gives the
+// same slug.
+func BundleSlug(components []Product) string {
+	name := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(components[0].Name), "-"), "-")
+	key := strings.ReplaceAll(CartItemKey(components), "_", "-")
+	if name == "" {
+		return key
+	}
+	return name + "-" + key
+}
+
 /*
 	for all of the below, we assume that product at index 0 is the gate and that there is
 	only one gate per bundle
@@ -57,10 +128,22 @@ func (b *Bundle) setTolerance() {
 	}
 }
 
+func (b *Bundle) setMountingType() {
+	if b.MountingType == "" {
+		b.MountingType = b.Components[0].MountingType
+	}
+}
+
+func (b *Bundle) setMaxSpan() {
+	if b.MaxSpan == 0 {
+		b.MaxSpan = b.Components[0].MaxSpan
+	}
+}
+
 func (b *Bundle) setPrice() {
-	if b.Price == 0 {
+	if b.Price.IsZero() {
 		for i := 0; i < len(b.Components); i++ {
-			b.Price += (b.Components[i].Price * float32(b.Components[i].Qty))
+			b.Price = b.Price.Add(b.Components[i].Price.Mul(b.Components[i].Qty))
 		}
 	}
 }
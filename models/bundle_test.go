@@ -0,0 +1,51 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleSlug(t *testing.T) {
+	components := []Product{
+		{Id: 1, Type: ProductTypeGate, Name: "BabyDan Premier Gate (White)", Qty: 1},
+		{Id: 5, Type: ProductTypeExtension, Name: "7cm extension", Qty: 4},
+	}
+	require.Equal(t, "babydan-premier-gate-white-1-1-5-4", BundleSlug(components))
+
+	components[1].Qty = 3
+	require.Equal(t, "babydan-premier-gate-white-1-1-5-3", BundleSlug(components))
+}
+
+func TestBundleValidate(t *testing.T) {
+	gate := Product{Id: 1, Type: ProductTypeGate, Width: 76, Tolerance: 6, Qty: 1, MaxSpan: 100, MaxExtensions: 3}
+	small := Product{Id: 5, Type: ProductTypeExtension, Width: 7}
+	large := Product{Id: 3, Type: ProductTypeExtension, Width: 64}
+	other := Product{Id: 9, Type: ProductTypeExtension, Width: 7}
+	compatible := []Product{small, large}
+
+	with := func(p Product, qty int) Product {
+		p.Qty = qty
+		return p
+	}
+	bundle := func(components ...Product) Bundle {
+		return Bundle{Components: components}
+	}
+
+	require.NoError(t, bundle(gate).Validate(compatible))
+	require.NoError(t, bundle(gate, with(small, 3)).Validate(compatible))
+
+	for name, b := range map[string]Bundle{
+		"no gate":             bundle(),
+		"extension first":     bundle(with(small, 1), gate),
+		"two gates":           bundle(with(gate, 2), with(small, 1)),
+		"gate as extension":   bundle(gate, gate),
+		"incompatible":        bundle(gate, with(other, 1)),
+		"listed twice":        bundle(gate, with(small, 1), with(small, 1)),
+		"no quantity":         bundle(gate, small),
+		"too many pieces":     bundle(gate, with(small, 4)),
+		"wider than it spans": bundle(gate, with(large, 1)),
+	} {
+		require.Error(t, b.Validate(compatible), name)
+	}
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// BundleGoal is what the bundle builder optimises for when more than one set
+// of extensions gets a gate to the opening.
+type BundleGoal string
+
+const (
+	BundleGoalPrice  BundleGoal = "price"  // cheapest bundle
+	BundleGoalPieces BundleGoal = "pieces" // fewest extensions
+	BundleGoalFit    BundleGoal = "fit"    // as sold, closest to the opening
+)
+
+// BundleGoals lists the goals in the order they are offered.
+var BundleGoals = []BundleGoal{BundleGoalPrice, BundleGoalPieces, BundleGoalFit}
+
+// IsValid checks if g is one of the predefined goals.
+func (g BundleGoal) IsValid() bool {
+	for _, goal := range BundleGoals {
+		if g == goal {
+			return true
+		}
+	}
+	return false
+}
+
+// Label is the goal formatted for people, e.g. "Lowest price".
+func (g BundleGoal) Label() string {
+	switch g {
+	case BundleGoalPrice:
+		return "Lowest price"
+	case BundleGoalPieces:
+		return "Fewest pieces"
+	case BundleGoalFit:
+		return "Tightest fit"
+	}
+	return string(g)
+}
+
+// widths are searched in mm so that summing them is exact
+const mmPerCm = 10
+
+func toMM(cm float32) int {
+	return int(math.Round(float64(cm) * mmPerCm))
+}
+
+// extensionSet is a multiset of extensions, counts[i] of the i-th one.
+type extensionSet struct {
+	counts []int
+	price  int64
+	pieces int
+}
+
+func (s extensionSet) with(i int, extension Product) extensionSet {
+	counts := append([]int(nil), s.counts...)
+	counts[i]++
+	return extensionSet{counts: counts, price: s.price + extension.Price.Amount, pieces: s.pieces + 1}
+}
+
+// better orders sets of the same total width. Fit only depends on the width,
+// so sets that are equally tight are ranked by price.
+func (s extensionSet) better(o extensionSet, goal BundleGoal) bool {
+	if goal == BundleGoalPieces {
+		if s.pieces != o.pieces {
+			return s.pieces < o.pieces
+		}
+		return s.price < o.price
+	}
+	if s.price != o.price {
+		return s.price < o.price
+	}
+	return s.pieces < o.pieces
+}
+
+// keepBest inserts set into sets, which are sorted best first, and drops
+// anything past the n-th.
+func keepBest(sets []extensionSet, set extensionSet, goal BundleGoal, n int) []extensionSet {
+	i := sort.Search(len(sets), func(i int) bool { return set.better(sets[i], goal) })
+	if i >= n {
+		return sets
+	}
+	sets = append(sets, extensionSet{})
+	copy(sets[i+1:], sets[i:])
+	sets[i] = set
+	if len(sets) > n {
+		sets = sets[:n]
+	}
+	return sets
+}
+
+// BestBundles returns up to n bundles of gate and its extensions whose fit
+// range contains opening, best first by goal. None are returned when the gate
+// cannot get there, including when opening is past its Span or it would take
+// more than MaxExtensions extensions.
+//
+// The extensions have to add between opening-MaxWidth and opening-MinWidth
+// to the gate. That is an unbounded knapsack over their widths: extensions are
+// taken one at a time and, for every total width up to the most allowed and
+// every number of pieces up to the most allowed, the n best sets reaching
+// exactly that width with that many pieces are kept. Each set is built once
+// and costs are additive, so the n best per width are exact, and keeping them
+// per number of pieces means a cheap set with too many pieces never pushes
+// out one that is within the limit.
+func BestBundles(gate Product, extensions []Product, opening float32, goal BundleGoal, n int) []Bundle {
+	if n < 1 {
+		n = 1
+	}
+	if opening > gate.Span()+fitEpsilon {
+		return nil
+	}
+	least := max(int(math.Ceil(float64(opening-gate.MaxWidth())*mmPerCm-fitEpsilon)), 0)
+	most := int(math.Floor(float64(opening-gate.MinWidth())*mmPerCm + fitEpsilon))
+	if most < least {
+		return nil
+	}
+
+	var usable []Product
+	for _, extension := range extensions {
+		if width := toMM(extension.Width); width > 0 && width <= most {
+			usable = append(usable, extension)
+		}
+	}
+	// widest first, which is also the order they are listed in the bundle
+	sort.SliceStable(usable, func(i, j int) bool {
+		return usable[i].Width > usable[j].Width
+	})
+
+	maxPieces := 0
+	if len(usable) > 0 {
+		maxPieces = most / toMM(usable[len(usable)-1].Width)
+	}
+	if gate.MaxExtensions > 0 {
+		maxPieces = min(maxPieces, gate.MaxExtensions)
+	}
+
+	// best[total][pieces]
+	best := make([][][]extensionSet, most+1)
+	for total := range best {
+		best[total] = make([][]extensionSet, maxPieces+1)
+	}
+	best[0][0] = []extensionSet{{counts: make([]int, len(usable))}}
+	for i, extension := range usable {
+		width := toMM(extension.Width)
+		for total := width; total <= most; total++ {
+			for pieces := 1; pieces <= maxPieces; pieces++ {
+				for _, set := range best[total-width][pieces-1] {
+					best[total][pieces] = keepBest(best[total][pieces], set.with(i, extension), goal, n)
+				}
+			}
+		}
+	}
+
+	// slack is how far the gate and extensions, as sold, are from the
+	// opening: how much a pressure fit gate is wound in, or the gap a
+	// hardware fit gate's brackets bridge
+	type candidate struct {
+		set   extensionSet
+		slack int
+	}
+	var candidates []candidate
+	for total := least; total <= most; total++ {
+		slack := toMM(gate.Width) + total - toMM(opening)
+		for _, sets := range best[total] {
+			for _, set := range sets {
+				candidates = append(candidates, candidate{set, max(slack, -slack)})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if goal == BundleGoalFit && a.slack != b.slack {
+			return a.slack < b.slack
+		}
+		if a.set.better(b.set, goal) || b.set.better(a.set, goal) {
+			return a.set.better(b.set, goal)
+		}
+		return a.slack < b.slack
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	bundles := make([]Bundle, 0, len(candidates))
+	for _, c := range candidates {
+		bundle := Bundle{Product: Product{Qty: 1}}
+		component := gate
+		component.Qty = 1
+		bundle.Components = append(bundle.Components, component)
+		for i, count := range c.set.counts {
+			if count == 0 {
+				continue
+			}
+			component := usable[i]
+			component.Qty = count
+			bundle.Components = append(bundle.Components, component)
+		}
+		bundle.ComputeMetaData()
+		bundles = append(bundles, bundle)
+	}
+	return bundles
+}
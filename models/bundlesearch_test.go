@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func extensionWidths(bundle Bundle) map[float32]int {
+	widths := map[float32]int{}
+	for _, component := range bundle.Components[1:] {
+		widths[component.Width] += component.Qty
+	}
+	return widths
+}
+
+func TestBestBundles(t *testing.T) {
+	gate := Product{Id: 1, Type: ProductTypeGate, Name: "gate", Width: 76, Tolerance: 6, Price: EUR(5000)}
+	extensions := []Product{
+		{Id: 2, Type: ProductTypeExtension, Width: 7, Price: EUR(1000)},
+		{Id: 3, Type: ProductTypeExtension, Width: 32, Price: EUR(2000)},
+		{Id: 4, Type: ProductTypeExtension, Width: 64, Price: EUR(10000)},
+	}
+
+	cheapest := BestBundles(gate, extensions, 146, BundleGoalPrice, 2)
+	require.Len(t, cheapest, 2)
+	require.Equal(t, map[float32]int{32: 2, 7: 1}, extensionWidths(cheapest[0]))
+	require.Equal(t, EUR(10000), cheapest[0].Price)
+	require.True(t, cheapest[0].Fits(146))
+	require.True(t, cheapest[1].Fits(146))
+	require.True(t, cheapest[1].Price.Amount >= cheapest[0].Price.Amount)
+
+	fewest := BestBundles(gate, extensions, 146, BundleGoalPieces, 1)
+	require.Len(t, fewest, 1)
+	require.Equal(t, map[float32]int{64: 1, 7: 1}, extensionWidths(fewest[0]))
+
+	tightest := BestBundles(gate, extensions, 110, BundleGoalFit, 1)
+	require.Len(t, tightest, 1)
+	require.Equal(t, map[float32]int{7: 5}, extensionWidths(tightest[0]))
+	require.Equal(t, float32(111), tightest[0].Width)
+
+	require.Empty(t, BestBundles(gate, extensions, 69, BundleGoalPrice, 3), "too narrow for the gate")
+	require.Empty(t, BestBundles(gate, extensions[1:], 90, BundleGoalPrice, 3), "no extension is narrow enough")
+
+	alone := BestBundles(gate, extensions, 72, BundleGoalPrice, 3)
+	require.Len(t, alone, 1)
+	require.Len(t, alone[0].Components, 1)
+}
+
+func TestBestBundlesHardwareFit(t *testing.T) {
+	gate := Product{Id: 1, Type: ProductTypeGate, MountingType: MountingTypeHardwareFit, Name: "gate", Width: 76, Tolerance: 6, Price: EUR(5000)}
+	extensions := []Product{{Id: 2, Type: ProductTypeExtension, Width: 7, Price: EUR(1000)}}
+
+	// brackets bridge gaps wider than the gate, never narrower
+	require.Empty(t, BestBundles(gate, extensions, 75, BundleGoalPrice, 1))
+
+	bundles := BestBundles(gate, extensions, 100, BundleGoalPrice, 1)
+	require.Len(t, bundles, 1)
+	require.Equal(t, map[float32]int{7: 3}, extensionWidths(bundles[0]))
+	require.Equal(t, MountingTypeHardwareFit, bundles[0].MountingType)
+	require.True(t, bundles[0].Fits(100))
+}
+
+func TestBestBundlesGateLimits(t *testing.T) {
+	gate := Product{Id: 1, Type: ProductTypeGate, Name: "gate", Width: 76, Tolerance: 6, Price: EUR(5000), MaxSpan: 140, MaxExtensions: 2}
+	extensions := []Product{
+		{Id: 2, Type: ProductTypeExtension, Width: 7, Price: EUR(1000)},
+		{Id: 3, Type: ProductTypeExtension, Width: 32, Price: EUR(5000)},
+	}
+
+	// 4 x 7cm is cheapest, but only two extensions are allowed
+	unlimited := gate
+	unlimited.MaxExtensions = 0
+	require.Equal(t, map[float32]int{7: 4}, extensionWidths(BestBundles(unlimited, extensions, 104, BundleGoalPrice, 1)[0]))
+
+	bundles := BestBundles(gate, extensions, 104, BundleGoalPrice, 5)
+	require.Len(t, bundles, 1)
+	require.Equal(t, map[float32]int{32: 1}, extensionWidths(bundles[0]))
+
+	// 76 + 2 x 32 reaches 140 but no further
+	require.Equal(t, float32(140), bundles[0].MaxSpan)
+	require.NotEmpty(t, BestBundles(gate, extensions, 140, BundleGoalPrice, 1))
+	require.Empty(t, BestBundles(gate, extensions, 141, BundleGoalPrice, 1))
+
+	// a gate with no span of its own stops at MaxOpening
+	unlimited.MaxSpan = 0
+	require.Equal(t, float32(MaxOpening), unlimited.Span())
+	require.NotEmpty(t, BestBundles(unlimited, extensions, MaxOpening, BundleGoalPrice, 1))
+	require.Empty(t, BestBundles(unlimited, extensions, MaxOpening+1, BundleGoalPrice, 1))
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -13,14 +14,27 @@ type Cart struct {
 	CreatedAt     time.Time  `json:"created_at"`      // stored in cart table
 	LastUpdatedAt time.Time  `json:"last_updated_at"` // stored in cart table
 	Items         []CartItem `json:"items"`
-	TotalValue    float32    `json:"total_value"`
+	TotalValue    Money      `json:"total_value"`
+	Discounts     []Discount `json:"discounts"`
+	// PromoCode is the promotion code the customer entered, and
+	// CustomerEmail the address they gave with it, if any.
+	PromoCode     string `json:"promo_code"`
+	CustomerEmail string `json:"customer_email"`
+	// Warnings are what has changed in the catalog since the items were
+	// added, see Check.
+	Warnings []CartWarning `json:"warnings"`
+	// ShippingOptions are the ways the cart can get to the customer, and
+	// Shipping the one they chose, see QuoteShipping.
+	ShippingOptions []ShippingOption `json:"shipping_options"`
+	Shipping        ShippingOption   `json:"shipping"`
+	ShippingTax     TaxLine          `json:"shipping_tax"` // see ApplyVAT
 }
 
 type CartItem struct {
 	ID         string              `json:"id"`      // stored in cart_item table
 	CartID     string              `json:"cart_id"` // stored in cart_item table
 	Name       string              `json:"name"`
-	SalePrice  float32             `json:"sale_price"`
+	SalePrice  Money               `json:"sale_price"`
 	Components []CartItemComponent `json:"components"`
 	Qty        int                 `json:"qty"`        // stored in cart_item table
 	CreatedAt  time.Time           `json:"created_at"` // stored in cart_item table
@@ -30,7 +44,17 @@ type CartItemComponent struct {
 	CartItemID string    `json:"cart_item_id"` // stored in cart_item_component table
 	CartID     string    `json:"cart_id"`      // stored in cart_item_component table
 	CreatedAt  time.Time `json:"created_at"`   // stored in cart_item_component table
-	Product              // only product_id and qty stored in cart_item_component table
+	// QuotedPrice is the unit price the customer was last shown, stored in
+	// cart_item_component. Price is always the live catalog price. Zero for
+	// components added before quoted prices were kept.
+	QuotedPrice Money `json:"quoted_price"`
+	// Unavailable is set when the product has since left the catalog. Only
+	// its id and qty are known then.
+	Unavailable bool `json:"unavailable"`
+	// Tax is the VAT in what the customer pays for the component, see
+	// Cart.ApplyVAT.
+	Tax     TaxLine `json:"tax"`
+	Product         // only product_id, qty and quoted price stored in cart_item_component table
 }
 
 func NewCart() Cart {
@@ -43,12 +67,65 @@ func NewCart() Cart {
 }
 
 func (c *Cart) SetTotalValue() {
-	c.TotalValue = 0
+	c.TotalValue = Money{}
 	for i := range c.Items {
-		c.TotalValue += (c.Items[i].SalePrice * float32(c.Items[i].Qty))
+		c.TotalValue = c.TotalValue.Add(c.Items[i].SalePrice.Mul(c.Items[i].Qty))
 	}
 }
 
+// ApplyDiscounts works out which discounts the cart's items are due, from
+// the multi-gate discount and any promotions that can be used. It replaces
+// any worked out before, so call it again after the items change. Each
+// discount is worked out on the full prices, and together they never take
+// off more than the cart's total.
+func (c *Cart) ApplyDiscounts(multiGate MultiGateDiscount, promotions ...Promotion) {
+	c.Discounts = nil
+	if discount, ok := multiGate.Apply(c.Items); ok {
+		c.Discounts = append(c.Discounts, discount)
+	}
+	for _, promotion := range promotions {
+		if discount, ok := promotion.Apply(c.Items); ok {
+			c.Discounts = append(c.Discounts, discount)
+		}
+	}
+
+	remaining := c.TotalValue.Amount
+	for i := range c.Discounts {
+		c.Discounts[i].Amount.Amount = min(c.Discounts[i].Amount.Amount, remaining)
+		remaining -= c.Discounts[i].Amount.Amount
+	}
+}
+
+// FreeShipping reports whether a discount waives shipping.
+func (c Cart) FreeShipping() bool {
+	for _, discount := range c.Discounts {
+		if discount.FreeShipping {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscountTotal sums the cart's discounts.
+func (c Cart) DiscountTotal() Money {
+	var total Money
+	for _, discount := range c.Discounts {
+		total = total.Add(discount.Amount)
+	}
+	return total
+}
+
+// AmountDue is the cart's total after discounts.
+func (c Cart) AmountDue() Money {
+	return c.TotalValue.Sub(c.DiscountTotal())
+}
+
+// Total is what the customer pays at checkout, the amount due plus
+// shipping.
+func (c Cart) Total() Money {
+	return c.AmountDue().Add(c.Shipping.Price)
+}
+
 func (i *CartItem) SetName() {
 	i.Name = ""
 	i.Name = i.Components[0].Name
@@ -58,20 +135,20 @@ func (i *CartItem) SetName() {
 }
 
 func (i *CartItem) SetPrice() {
-	i.SalePrice = 0
+	i.SalePrice = Money{}
 	for c := range i.Components {
 		component := i.Components[c]
-		i.SalePrice += (component.Price * float32(component.Qty))
+		i.SalePrice = i.SalePrice.Add(component.Price.Mul(component.Qty))
 	}
 }
 
 func NewCartItem(cartID string, components []CartItemComponent) CartItem {
 
-	idParts := []string{}
+	products := make([]Product, 0, len(components))
 	for _, c := range components {
-		idParts = append(idParts, strconv.Itoa(c.Product.Id)+"-"+strconv.Itoa(c.Qty))
+		products = append(products, c.Product)
 	}
-	id := strings.Join(idParts, "_")
+	id := CartItemKey(products)
 
 	item := CartItem{
 		ID:         id,
@@ -91,6 +168,39 @@ func NewCartItem(cartID string, components []CartItemComponent) CartItem {
 	return item
 }
 
+// CartItemKey is the id of a cart item made of products, e.g. "1-1_5-4" for
+// one of product 1 and four of product 5. Adding the same products again
+// finds the same item.
+func CartItemKey(products []Product) string {
+	idParts := []string{}
+	for _, p := range products {
+		idParts = append(idParts, strconv.Itoa(p.Id)+"-"+strconv.Itoa(p.Qty))
+	}
+	return strings.Join(idParts, "_")
+}
+
+// ParseCartItemKey reads the product ids and quantities back out of a
+// CartItemKey. Only Id and Qty are set on the products returned.
+func ParseCartItemKey(key string) ([]Product, error) {
+	var products []Product
+	for _, part := range strings.Split(key, "_") {
+		id, qty, found := strings.Cut(part, "-")
+		if !found {
+			return nil, fmt.Errorf("parse cart item key %q: %q is not id-qty", key, part)
+		}
+		p := Product{}
+		var err error
+		if p.Id, err = strconv.Atoi(id); err != nil || p.Id < 1 {
+			return nil, fmt.Errorf("parse cart item key %q: bad product id %q", key, id)
+		}
+		if p.Qty, err = strconv.Atoi(qty); err != nil || p.Qty < 1 {
+			return nil, fmt.Errorf("parse cart item key %q: bad qty %q", key, qty)
+		}
+		products = append(products, p)
+	}
+	return products, nil
+}
+
 func NewCartItemComponent(cartID string) CartItemComponent {
 	return CartItemComponent{
 		CartID:    cartID,
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCartItemKey(t *testing.T) {
+	products := []Product{{Id: 1, Qty: 1}, {Id: 5, Qty: 4}}
+	key := CartItemKey(products)
+	require.Equal(t, "1-1_5-4", key)
+
+	parsed, err := ParseCartItemKey(key)
+	require.NoError(t, err)
+	require.Equal(t, products, parsed)
+
+	for _, bad := range []string{"", "1", "1-0", "a-1", "1-1_"} {
+		_, err := ParseCartItemKey(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestCartCheck(t *testing.T) {
+	gate := Product{Id: 1, Name: "Gate", Type: ProductTypeGate, Price: EUR(5000), Qty: 1}
+	extension := Product{Id: 5, Name: "Extension", Type: ProductTypeExtension, Price: EUR(1000), Qty: 2}
+	cart := Cart{Items: []CartItem{
+		{Qty: 2, Components: []CartItemComponent{
+			{QuotedPrice: EUR(4500), Product: gate},
+			{QuotedPrice: EUR(1200), Product: extension},
+		}},
+		{Qty: 1, Components: []CartItemComponent{{QuotedPrice: EUR(4500), Product: gate}}},
+		{Qty: 1, Components: []CartItemComponent{{Unavailable: true, Product: Product{Id: 9, Name: "Product 9", Qty: 1}}}},
+	}}
+
+	cart.Warnings = cart.Check(map[int]StockLevel{
+		1: {Available: 2},
+		5: {Available: 0, AllowBackorder: true},
+	})
+	require.Equal(t, []CartWarning{
+		{Kind: CartWarningPriceUp, ProductID: 1, Message: "The price of Gate changed from €45.00 to €50.00."},
+		{Kind: CartWarningPriceDown, ProductID: 5, Message: "The price of Extension changed from €12.00 to €10.00."},
+		{Kind: CartWarningLowStock, ProductID: 1, Message: "Only 2 left of Gate, your cart has 3."},
+		{Kind: CartWarningUnavailable, ProductID: 9, Message: "Product 9 is no longer available. Please remove it to check out."},
+	}, cart.Warnings, "the gate is warned about once, against both items")
+	require.False(t, cart.CanCheckout())
+
+	cart.Items = cart.Items[:1]
+	cart.Warnings = cart.Check(map[int]StockLevel{1: {Available: 0}, 5: {Available: 4}})
+	require.Len(t, cart.Warnings, 3)
+	require.Equal(t, CartWarningSoldOut, cart.Warnings[2].Kind)
+
+	cart.Warnings = cart.Check(map[int]StockLevel{1: {Available: 2}, 5: {Available: 4}})
+	require.Len(t, cart.Warnings, 2)
+	require.True(t, cart.CanCheckout(), "price changes do not block checking out")
+}
@@ -0,0 +1,144 @@
+package models
+
+import "fmt"
+
+// CartWarningKind is what changed about something in a cart since it was
+// added.
+type CartWarningKind string
+
+const (
+	CartWarningPriceUp     CartWarningKind = "price_up"
+	CartWarningPriceDown   CartWarningKind = "price_down"
+	CartWarningLowStock    CartWarningKind = "low_stock"
+	CartWarningSoldOut     CartWarningKind = "sold_out"
+	CartWarningUnavailable CartWarningKind = "unavailable"
+	// CartWarningPromoCode is a promotion code that cannot be used.
+	CartWarningPromoCode CartWarningKind = "promo_code"
+)
+
+var CartWarningKinds = []CartWarningKind{
+	CartWarningPriceUp,
+	CartWarningPriceDown,
+	CartWarningLowStock,
+	CartWarningSoldOut,
+	CartWarningUnavailable,
+	CartWarningPromoCode,
+}
+
+// CartWarning tells the customer a product in their cart no longer matches
+// the catalog. Price changes are only news, stock and availability problems
+// have to be fixed before checking out.
+type CartWarning struct {
+	Kind      CartWarningKind `json:"kind"`
+	ProductID int             `json:"product_id"`
+	Message   string          `json:"message"`
+}
+
+// Blocking reports whether the cart cannot be checked out until the problem
+// is fixed.
+func (w CartWarning) Blocking() bool {
+	switch w.Kind {
+	case CartWarningLowStock, CartWarningSoldOut, CartWarningUnavailable:
+		return true
+	}
+	return false
+}
+
+// PriceChanged reports whether the warning is about a price.
+func (w CartWarning) PriceChanged() bool {
+	return w.Kind == CartWarningPriceUp || w.Kind == CartWarningPriceDown
+}
+
+// PromoCodeWarning tells the customer why the promotion code they entered
+// cannot be used.
+func PromoCodeWarning(code string, err error) CartWarning {
+	return CartWarning{
+		Kind:    CartWarningPromoCode,
+		Message: fmt.Sprintf("The code %s was not applied: %v.", code, err),
+	}
+}
+
+// StockLevel is how many of a product can be sold right now.
+type StockLevel struct {
+	Available      int
+	AllowBackorder bool
+}
+
+// Check compares the cart's components with the live prices they were
+// hydrated with and with stock, keyed by product id, and returns a warning
+// for each product that has changed. A product in several items is warned
+// about once, against the total the cart needs of it. Products missing from
+// stock are not checked for stock.
+func (c Cart) Check(stock map[int]StockLevel) []CartWarning {
+	var warnings []CartWarning
+	seen := map[int]bool{}
+	required := map[int]int{}
+	var order []CartItemComponent
+	for _, item := range c.Items {
+		for _, component := range item.Components {
+			required[component.Id] += component.Qty * item.Qty
+			if seen[component.Id] {
+				continue
+			}
+			seen[component.Id] = true
+			order = append(order, component)
+
+			if component.Unavailable {
+				continue
+			}
+			quoted, price := component.QuotedPrice, component.Price
+			if quoted.IsZero() || quoted == price {
+				continue
+			}
+			kind := CartWarningPriceDown
+			if quoted.Amount < price.Amount {
+				kind = CartWarningPriceUp
+			}
+			warnings = append(warnings, CartWarning{
+				Kind:      kind,
+				ProductID: component.Id,
+				Message:   fmt.Sprintf("The price of %s changed from %s to %s.", component.Name, quoted, price),
+			})
+		}
+	}
+
+	for _, component := range order {
+		if component.Unavailable {
+			warnings = append(warnings, CartWarning{
+				Kind:      CartWarningUnavailable,
+				ProductID: component.Id,
+				Message:   fmt.Sprintf("%s is no longer available. Please remove it to check out.", component.Name),
+			})
+			continue
+		}
+		level, ok := stock[component.Id]
+		if !ok || level.AllowBackorder || level.Available >= required[component.Id] {
+			continue
+		}
+		if level.Available <= 0 {
+			warnings = append(warnings, CartWarning{
+				Kind:      CartWarningSoldOut,
+				ProductID: component.Id,
+				Message:   fmt.Sprintf("%s is sold out. Please remove it to check out.", component.Name),
+			})
+			continue
+		}
+		warnings = append(warnings, CartWarning{
+			Kind:      CartWarningLowStock,
+			ProductID: component.Id,
+			Message:   fmt.Sprintf("Only %d left of %s, your cart has %d.", level.Available, component.Name, required[component.Id]),
+		})
+	}
+	return warnings
+}
+
+// CanCheckout reports whether none of the cart's warnings block checking
+// out.
+func (c Cart) CanCheckout() bool {
+	for _, warning := range c.Warnings {
+		if warning.Blocking() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// BuildOutcome is how well the catalog served a width asked of the builder.
+type BuildOutcome string
+
+const (
+	BuildServed        BuildOutcome = "served"          // In-stock bundles fit, the best of them included
+	BuildPartlyInStock BuildOutcome = "partly_in_stock" // Some bundles that fit were out of stock and left out
+	BuildOutOfStock    BuildOutcome = "out_of_stock"    // Bundles fit but none of them are in stock
+	BuildUnserved      BuildOutcome = "unserved"        // Nothing in the catalog fits
+)
+
+// BuildRequest is a width asked of the builder, stored in bundle_sizes.
+// CartID is empty when the request did not come with a cart, e.g. from the
+// API without a cart token.
+type BuildRequest struct {
+	ID           int
+	CartID       string
+	MountingType MountingType
+	Width        float32
+	Outcome      BuildOutcome
+	AddedToCart  bool
+	CreatedAt    time.Time
+}
+
+// PaidOrderStatuses are the statuses of orders that were paid for, whatever
+// happened to them after.
+var PaidOrderStatuses = []OrderStatus{
+	OrderStatusProcessing,
+	OrderStatusAwaitingFulfillment,
+	OrderStatusAwaitingShipment,
+	OrderStatusPartiallyShipped,
+	OrderStatusShipped,
+	OrderStatusOutForDelivery,
+	OrderStatusAwaitingPickup,
+	OrderStatusCompleted,
+	OrderStatusDelivered,
+	OrderStatusPickedUp,
+	OrderStatusRefunded,
+	OrderStatusPartialRefunded,
+	OrderStatusClosed,
+	OrderStatusChargeback,
+}
+
+// WidthDemand is how often the builder was asked for a width, in whole cm,
+// and how those requests went. Requests made before outcomes were recorded
+// count towards Requests only.
+type WidthDemand struct {
+	MountingType  MountingType
+	Width         int
+	Requests      int
+	Served        int
+	PartlyInStock int
+	OutOfStock    int
+	Unserved      int
+	AddedToCart   int
+	Ordered       int
+}
+
+// Poorly is how many requests for the width were not fully served.
+func (d WidthDemand) Poorly() int {
+	return d.PartlyInStock + d.OutOfStock + d.Unserved
+}
+
+// BuilderFunnel follows the carts that used the builder: how many asked for a
+// width, how many then added a bundle that fits one of their widths, and how
+// many of those paid for an order.
+type BuilderFunnel struct {
+	Carts       int
+	AddedToCart int
+	Ordered     int
+}
+
+// BuilderDemand is what the builder was asked for over a period.
+type BuilderDemand struct {
+	Since  time.Time // zero for all time
+	Widths []WidthDemand
+	Funnel BuilderFunnel
+}
+
+// Requests is the number of widths asked for.
+func (d BuilderDemand) Requests() int {
+	n := 0
+	for _, w := range d.Widths {
+		n += w.Requests
+	}
+	return n
+}
+
+// MostRequests is the count of the most asked-for width, for scaling a
+// histogram.
+func (d BuilderDemand) MostRequests() int {
+	most := 0
+	for _, w := range d.Widths {
+		most = max(most, w.Requests)
+	}
+	return most
+}
+
+// Problems are the widths that were not fully served, most often first.
+func (d BuilderDemand) Problems() []WidthDemand {
+	var problems []WidthDemand
+	for _, w := range d.Widths {
+		if w.Poorly() > 0 {
+			problems = append(problems, w)
+		}
+	}
+	slices.SortStableFunc(problems, func(a, b WidthDemand) int {
+		return b.Poorly() - a.Poorly()
+	})
+	return problems
+}
@@ -0,0 +1,87 @@
+package models
+
+import "fmt"
+
+// Discount is money taken off a cart, and later the order it becomes.
+type Discount struct {
+	Label  string `json:"label"`
+	Amount Money  `json:"amount"`
+	// PromotionID is the promotion that gave it, 0 for the multi-gate
+	// discount.
+	PromotionID int `json:"promotion_id"`
+	// FreeShipping waives shipping. Amount is zero then.
+	FreeShipping bool `json:"free_shipping"`
+}
+
+// shareDiscount takes discount off amounts in proportion to each, the last
+// taking what rounding leaves, and never more than they add up to.
+func shareDiscount(amounts []Money, discount Money) []Money {
+	var subtotal Money
+	for _, amount := range amounts {
+		subtotal = subtotal.Add(amount)
+	}
+	total := min(discount.Amount, subtotal.Amount)
+	remaining := total
+	shared := make([]Money, len(amounts))
+	for i, amount := range amounts {
+		share := remaining
+		if i < len(amounts)-1 && subtotal.Amount > 0 {
+			share = total * amount.Amount / subtotal.Amount
+		}
+		remaining -= share
+		shared[i] = amount.Sub(NewMoney(share, amount.Currency))
+	}
+	return shared
+}
+
+// MultiGateDiscount takes Percent off every cart item with a gate in it once
+// the cart holds at least MinGates gates, e.g. for kitting out a whole house.
+// A zero Percent turns it off.
+type MultiGateDiscount struct {
+	MinGates int
+	Percent  int
+}
+
+// Enabled reports whether the discount is switched on.
+func (d MultiGateDiscount) Enabled() bool {
+	return d.Percent > 0 && d.MinGates > 0
+}
+
+// Label describes the discount to customers, e.g. "10% off 2 or more gates".
+func (d MultiGateDiscount) Label() string {
+	return fmt.Sprintf("%d%% off %d or more gates", d.Percent, d.MinGates)
+}
+
+// Apply works out the discount on items. It is not due when the discount is
+// off or there are too few gates.
+func (d MultiGateDiscount) Apply(items []CartItem) (Discount, bool) {
+	if !d.Enabled() {
+		return Discount{}, false
+	}
+
+	gates := 0
+	var discounted Money
+	for _, item := range items {
+		itemGates := 0
+		for _, component := range item.Components {
+			if component.Type == ProductTypeGate {
+				itemGates += component.Qty
+			}
+		}
+		if itemGates == 0 {
+			continue
+		}
+		gates += itemGates * item.Qty
+		discounted = discounted.Add(item.SalePrice.Mul(item.Qty))
+	}
+	if gates < d.MinGates {
+		return Discount{}, false
+	}
+
+	// rounded down to the cent
+	amount := discounted.Amount * int64(d.Percent) / 100
+	if amount <= 0 {
+		return Discount{}, false
+	}
+	return Discount{Label: d.Label(), Amount: NewMoney(amount, discounted.Currency)}, true
+}
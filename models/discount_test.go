@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiGateDiscount(t *testing.T) {
+	gateItem := CartItem{
+		SalePrice: EUR(10000),
+		Qty:       1,
+		Components: []CartItemComponent{
+			{Product: Product{Type: ProductTypeGate, Qty: 1}},
+			{Product: Product{Type: ProductTypeExtension, Qty: 2}},
+		},
+	}
+	extensionItem := CartItem{
+		SalePrice:  EUR(1500),
+		Qty:        1,
+		Components: []CartItemComponent{{Product: Product{Type: ProductTypeExtension, Qty: 1}}},
+	}
+	discount := MultiGateDiscount{MinGates: 2, Percent: 10}
+
+	_, ok := discount.Apply([]CartItem{gateItem, extensionItem})
+	require.False(t, ok, "one gate is not enough")
+
+	gateItem.Qty = 2
+	cart := Cart{Items: []CartItem{gateItem, extensionItem}}
+	cart.SetTotalValue()
+	cart.ApplyDiscounts(discount)
+	require.Equal(t, []Discount{{Label: "10% off 2 or more gates", Amount: EUR(2000)}}, cart.Discounts, "only items with a gate are discounted")
+	require.Equal(t, EUR(19500), cart.AmountDue())
+
+	cart.ApplyDiscounts(MultiGateDiscount{})
+	require.Empty(t, cart.Discounts)
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency code.
+type Currency string
+
+const (
+	CurrencyEUR Currency = "EUR"
+
+	// DefaultCurrency is what the shop sells in and what prices without a
+	// currency are assumed to be in.
+	DefaultCurrency = CurrencyEUR
+)
+
+var currencySymbols = map[Currency]string{
+	CurrencyEUR: "€",
+	"GBP":       "£",
+	"USD":       "$",
+}
+
+// Money is an amount in a currency's minor units, e.g. cents, so that sums
+// are exact. Every currency we sell in has two decimal places.
+type Money struct {
+	Amount   int64    `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// EUR returns cents euro cents.
+func EUR(cents int64) Money {
+	return Money{Amount: cents, Currency: CurrencyEUR}
+}
+
+// ParseMoney reads a decimal amount in major units such as "12.5" or
+// "12.50" without going through a float.
+func ParseMoney(s string, currency Currency) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	whole, frac, hasFrac := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if whole == "" && !hasFrac {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	if len(frac) > 2 {
+		return Money{}, fmt.Errorf("%w: %q has more than 2 decimal places", ErrInvalidMoney, s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	major, err := strconv.ParseUint(whole, 10, 62)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	minor, err := strconv.ParseUint(frac, 10, 8)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+
+	amount := int64(major)*100 + int64(minor)
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Add sums two amounts. A zero Money with no currency takes on the currency
+// of whatever is added to it, so totals can start from Money{}.
+func (m Money) Add(o Money) Money {
+	currency := m.Currency
+	if currency == "" {
+		currency = o.Currency
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: currency}
+}
+
+// Sub takes o away from m, keeping currencies the way Add does.
+func (m Money) Sub(o Money) Money {
+	return m.Add(Money{Amount: -o.Amount, Currency: o.Currency})
+}
+
+func (m Money) Mul(qty int) Money {
+	return Money{Amount: m.Amount * int64(qty), Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Decimal formats the amount in major units without a symbol, e.g. "12.50",
+// for form inputs.
+func (m Money) Decimal() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+// String formats the amount for display, e.g. "€12.50".
+func (m Money) String() string {
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	symbol, found := currencySymbols[currency]
+	if !found {
+		return m.Decimal() + " " + string(currency)
+	}
+	if m.Amount < 0 {
+		return "-" + symbol + Money{Amount: -m.Amount}.Decimal()
+	}
+	return symbol + m.Decimal()
+}
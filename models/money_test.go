@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	for raw, want := range map[string]int64{
+		"49.99": 4999,
+		"12.5":  1250,
+		"12":    1200,
+		".05":   5,
+		"0.10":  10,
+		"-3.20": -320,
+	} {
+		m, err := ParseMoney(raw, CurrencyEUR)
+		require.NoError(t, err, raw)
+		require.Equal(t, EUR(want), m, raw)
+	}
+
+	for _, raw := range []string{"", "abc", "1.234", "1.2.3", "1e3", "-"} {
+		_, err := ParseMoney(raw, CurrencyEUR)
+		require.ErrorIs(t, err, ErrInvalidMoney, raw)
+	}
+}
+
+func TestMoneyArithmeticAndFormatting(t *testing.T) {
+	// 0.1 + 0.2 in float32 is not 0.3, in cents it is
+	total := Money{}.Add(EUR(10)).Add(EUR(20))
+	require.Equal(t, EUR(30), total)
+
+	require.Equal(t, "€149.97", EUR(4999).Mul(3).String())
+	require.Equal(t, "-€0.05", EUR(-5).String())
+	require.Equal(t, "12.00 SEK", NewMoney(1200, "SEK").String())
+	require.Equal(t, "7.05", EUR(705).Decimal())
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -83,6 +84,113 @@ func (s OrderStatus) Validate() error {
 	return nil
 }
 
+// orderStatusTransitions declares which statuses an order may move to from
+// each status. Anything not listed here is rejected. closed is the only status
+// with no way out.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusDraft:               {OrderStatusPendingPayment, OrderStatusCanceled},
+	OrderStatusPendingPayment:      {OrderStatusAwaitingPayment, OrderStatusProcessing, OrderStatusOnHold, OrderStatusCanceled, OrderStatusFailed, OrderStatusFraud, OrderStatusError},
+	OrderStatusAwaitingPayment:     {OrderStatusProcessing, OrderStatusOnHold, OrderStatusCanceled, OrderStatusFailed, OrderStatusFraud, OrderStatusError},
+	OrderStatusProcessing:          {OrderStatusOnHold, OrderStatusAwaitingFulfillment, OrderStatusAwaitingPickup, OrderStatusCanceled, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusFraud, OrderStatusChargeback, OrderStatusError},
+	OrderStatusOnHold:              {OrderStatusProcessing, OrderStatusAwaitingFulfillment, OrderStatusCanceled, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusFraud, OrderStatusChargeback, OrderStatusError},
+	OrderStatusAwaitingFulfillment: {OrderStatusAwaitingShipment, OrderStatusAwaitingPickup, OrderStatusOnHold, OrderStatusCanceled, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusChargeback},
+	OrderStatusAwaitingShipment:    {OrderStatusPartiallyShipped, OrderStatusShipped, OrderStatusOnHold, OrderStatusCanceled, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusChargeback},
+	OrderStatusPartiallyShipped:    {OrderStatusShipped, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusChargeback},
+	OrderStatusShipped:             {OrderStatusOutForDelivery, OrderStatusDelivered, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusChargeback},
+	OrderStatusOutForDelivery:      {OrderStatusDelivered, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusChargeback},
+	OrderStatusAwaitingPickup:      {OrderStatusPickedUp, OrderStatusCanceled, OrderStatusRefunded, OrderStatusPartialRefunded},
+	OrderStatusDelivered:           {OrderStatusCompleted, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusChargeback},
+	OrderStatusPickedUp:            {OrderStatusCompleted, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusChargeback},
+	OrderStatusCompleted:           {OrderStatusClosed, OrderStatusRefunded, OrderStatusPartialRefunded, OrderStatusChargeback},
+	OrderStatusPartialRefunded:     {OrderStatusRefunded, OrderStatusCompleted, OrderStatusClosed, OrderStatusChargeback},
+	OrderStatusFailed:              {OrderStatusPendingPayment, OrderStatusProcessing, OrderStatusCanceled},
+	OrderStatusCanceled:            {OrderStatusClosed},
+	OrderStatusRefunded:            {OrderStatusClosed},
+	OrderStatusFraud:               {OrderStatusCanceled, OrderStatusRefunded, OrderStatusClosed},
+	OrderStatusChargeback:          {OrderStatusRefunded, OrderStatusClosed},
+	OrderStatusError:               {OrderStatusPendingPayment, OrderStatusProcessing, OrderStatusOnHold, OrderStatusCanceled},
+	OrderStatusClosed:              {},
+}
+
+// CanTransitionTo reports whether an order in status s may move to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatuses lists the statuses an order in status s may move to, in the
+// order they are declared.
+func (s OrderStatus) NextStatuses() []OrderStatus {
+	return append([]OrderStatus(nil), orderStatusTransitions[s]...)
+}
+
+// CanRefund reports whether money can be given back on an order in status s,
+// i.e. it was paid and has not been refunded in full.
+func (s OrderStatus) CanRefund() bool {
+	return s == OrderStatusPartialRefunded || s.CanTransitionTo(OrderStatusPartialRefunded)
+}
+
+// UnsettledOrderStatuses are the statuses of orders that are still waiting to
+// hear from the payment provider whether they were paid. A failed order had a
+// payment attempt declined, but its checkout session stays open for another
+// try until it expires.
+var UnsettledOrderStatuses = []OrderStatus{OrderStatusPendingPayment, OrderStatusAwaitingPayment, OrderStatusFailed}
+
+// IsUnsettled reports whether an order in status s is still waiting on its
+// payment.
+func (s OrderStatus) IsUnsettled() bool {
+	for _, unsettled := range UnsettledOrderStatuses {
+		if s == unsettled {
+			return true
+		}
+	}
+	return false
+}
+
+// Label is the status formatted for people, e.g. "Awaiting payment".
+func (s OrderStatus) Label() string {
+	label := strings.ReplaceAll(string(s), "_", " ")
+	if label == "" {
+		return label
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// StatusTransitionError is returned when an order is asked to move between
+// two statuses the transition graph does not connect.
+type StatusTransitionError struct {
+	OrderID int
+	From    OrderStatus
+	To      OrderStatus
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("order %d cannot move from %s to %s", e.OrderID, e.From, e.To)
+}
+
+// Actors recorded in the order status history for changes nobody typed in.
+const (
+	OrderActorCheckout = "checkout"
+	OrderActorStripe   = "stripe"
+	OrderActorSystem   = "system"
+)
+
+// OrderStatusChange is one row of an order's status history. From is null for
+// the row written when the order is created.
+type OrderStatusChange struct {
+	ID        int
+	OrderID   int
+	From      sql.NullString
+	To        OrderStatus
+	Actor     string
+	Note      sql.NullString
+	CreatedAt time.Time
+}
+
 /*
 session id has been removed for now but I think
 i should keep it so I can associate  orders with abandoned cart recovery
@@ -100,4 +208,34 @@ type Order struct {
 	PaymentMethod   sql.NullString
 	CreatedAt       time.Time
 	StripeRef       sql.NullString
+	PaymentRef      sql.NullString // The provider's payment id, set once paid
+	// ShippingMethod is how the order gets to the customer, ShippingName
+	// the option they chose and ShippingCost what they paid for it. Orders
+	// placed before customers had a choice have none.
+	ShippingMethod ShippingMethod
+	ShippingName   string
+	ShippingCost   Money
+	// TaxCountry is the country whose rates the order's VAT was charged at,
+	// and ShippingTax the VAT in its shipping. Orders placed before VAT was
+	// recorded have none.
+	TaxCountry  string
+	ShippingTax TaxLine
+}
+
+// CanTransitionTo reports whether the order may move to next, going by its
+// status and how it gets to the customer.
+func (o Order) CanTransitionTo(next OrderStatus) bool {
+	return o.Status.CanTransitionTo(next) && o.ShippingMethod.Allows(next)
+}
+
+// NextStatuses lists the statuses the order may move to, leaving out those
+// for shipping a collected order or collecting a delivered one.
+func (o Order) NextStatuses() []OrderStatus {
+	var next []OrderStatus
+	for _, status := range o.Status.NextStatuses() {
+		if o.ShippingMethod.Allows(status) {
+			next = append(next, status)
+		}
+	}
+	return next
 }
@@ -10,15 +10,105 @@ const (
 	ProductTypeBundle    ProductType = "bundle"
 )
 
+// MountingType is how a gate is held in the opening.
+type MountingType string
+
+const (
+	// MountingTypePressureFit gates are wedged in by spindles that wind out
+	// against the walls.
+	MountingTypePressureFit MountingType = "pressure_fit"
+	// MountingTypeHardwareFit gates are screwed to the wall with brackets.
+	// They are needed at the top of stairs.
+	MountingTypeHardwareFit MountingType = "hardware_fit"
+)
+
+// MountingTypes lists the mounting types in the order they are offered.
+var MountingTypes = []MountingType{MountingTypePressureFit, MountingTypeHardwareFit}
+
+// IsValid checks if m is one of the predefined mounting types.
+func (m MountingType) IsValid() bool {
+	for _, mountingType := range MountingTypes {
+		if m == mountingType {
+			return true
+		}
+	}
+	return false
+}
+
+// Label is the mounting type formatted for people, e.g. "Pressure fit".
+func (m MountingType) Label() string {
+	switch m {
+	case MountingTypePressureFit:
+		return "Pressure fit"
+	case MountingTypeHardwareFit:
+		return "Screw fit"
+	}
+	return string(m)
+}
+
 type Product struct {
-	Id             int         `json:"product_id"`
-	Type           ProductType `json:"type"`
-	Name           string      `json:"name"`
-	Width          float32     `json:"width"`
-	Price          float32     `json:"price"`
-	Img            string      `json:"img"`
-	Color          string      `json:"color"`
-	Tolerance      float32     `json:"tolerance"`
-	Qty            int         `json:"qty"`
-	InventoryLevel int         `json:"inventory_level"`
+	Id             int          `json:"product_id"`
+	Type           ProductType  `json:"type"`
+	Name           string       `json:"name"`
+	Width          float32      `json:"width"`
+	Price          Money        `json:"price"`
+	Img            string       `json:"img"`
+	Color          string       `json:"color"`
+	Tolerance      float32      `json:"tolerance"`
+	Qty            int          `json:"qty"`
+	InventoryLevel int          `json:"inventory_level"`
+	AllowBackorder bool         `json:"allow_backorder"`
+	MountingType   MountingType `json:"mounting_type"`  // gates only, bundles take their gate's
+	MaxSpan        float32      `json:"max_span"`       // gates only, 0 for MaxOpening
+	MaxExtensions  int          `json:"max_extensions"` // gates only, 0 for no limit
+	Slug           string       `json:"slug"`           // saved bundles only
+	Weight         int          `json:"weight"`         // grams
+	TaxClass       TaxClass     `json:"tax_class"`
+}
+
+// fitEpsilon absorbs float32 rounding when comparing summed widths in cm.
+const fitEpsilon = 0.001
+
+// MaxOpening is the widest opening, in cm, any gate is sold for, and the span
+// of a gate with no MaxSpan of its own. The bundle search grows with the
+// square of the width, so it needs a limit somewhere, and no stair gate spans
+// five metres.
+const MaxOpening = 500
+
+// Span is the widest opening the gate can safely span with extensions.
+func (p Product) Span() float32 {
+	if p.MaxSpan > 0 {
+		return min(p.MaxSpan, MaxOpening)
+	}
+	return MaxOpening
+}
+
+// MinWidth is the narrowest opening the product fits. A pressure fit gate is
+// Width at full stretch and can be wound in by up to Tolerance. A hardware
+// fit gate is Width across and its wall brackets can take up a gap of up to
+// Tolerance, so it fits from Width up. Extensions have no tolerance. For a
+// bundle the range covers the gate plus every extension.
+func (p Product) MinWidth() float32 {
+	if p.MountingType == MountingTypeHardwareFit {
+		return p.Width
+	}
+	return p.Width - p.Tolerance
+}
+
+// MaxWidth is the widest opening the product fits. See MinWidth. It is never
+// more than MaxSpan, however many extensions are added.
+func (p Product) MaxWidth() float32 {
+	width := p.Width
+	if p.MountingType == MountingTypeHardwareFit {
+		width += p.Tolerance
+	}
+	if p.MaxSpan > 0 {
+		width = min(width, p.MaxSpan)
+	}
+	return width
+}
+
+// Fits reports whether opening, in cm, is within the product's fit range.
+func (p Product) Fits(opening float32) bool {
+	return opening >= p.MinWidth()-fitEpsilon && opening <= p.MaxWidth()+fitEpsilon
 }
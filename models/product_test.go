@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductFits(t *testing.T) {
+	bundle := Bundle{Components: []Product{
+		{Type: ProductTypeGate, Name: "gate", Width: 76, Tolerance: 6, Qty: 1},
+		{Type: ProductTypeExtension, Name: "extension", Width: 7, Qty: 4},
+	}}
+	bundle.ComputeMetaData()
+
+	require.Equal(t, float32(104), bundle.Width)
+	require.Equal(t, float32(98), bundle.MinWidth())
+	require.True(t, bundle.Fits(98))
+	require.True(t, bundle.Fits(104))
+	require.False(t, bundle.Fits(97.9))
+	require.False(t, bundle.Fits(104.1))
+}
+
+func TestProductFitsHardwareFit(t *testing.T) {
+	bundle := Bundle{Components: []Product{
+		{Type: ProductTypeGate, MountingType: MountingTypeHardwareFit, Name: "gate", Width: 76, Tolerance: 6, Qty: 1},
+		{Type: ProductTypeExtension, Name: "extension", Width: 7, Qty: 4},
+	}}
+	bundle.ComputeMetaData()
+
+	require.Equal(t, MountingTypeHardwareFit, bundle.MountingType)
+	require.Equal(t, float32(104), bundle.MinWidth())
+	require.Equal(t, float32(110), bundle.MaxWidth())
+	require.True(t, bundle.Fits(104))
+	require.True(t, bundle.Fits(110))
+	require.False(t, bundle.Fits(103.9))
+	require.False(t, bundle.Fits(110.1))
+}
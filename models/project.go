@@ -0,0 +1,54 @@
+package models
+
+// OpeningLocation is where in the house an opening is, which limits the kind
+// of gate that may go in it.
+type OpeningLocation string
+
+const (
+	OpeningDoorway     OpeningLocation = "doorway"
+	OpeningStairBottom OpeningLocation = "stair_bottom"
+	OpeningStairTop    OpeningLocation = "stair_top"
+)
+
+// OpeningLocations lists the locations in the order they are offered.
+var OpeningLocations = []OpeningLocation{OpeningDoorway, OpeningStairBottom, OpeningStairTop}
+
+// IsValid checks if l is one of the predefined locations.
+func (l OpeningLocation) IsValid() bool {
+	for _, location := range OpeningLocations {
+		if l == location {
+			return true
+		}
+	}
+	return false
+}
+
+// Label is the location formatted for people, e.g. "Bottom of stairs".
+func (l OpeningLocation) Label() string {
+	switch l {
+	case OpeningDoorway:
+		return "Doorway"
+	case OpeningStairBottom:
+		return "Bottom of stairs"
+	case OpeningStairTop:
+		return "Top of stairs"
+	}
+	return string(l)
+}
+
+// MountingType is the kind of gate the opening needs. At the top of stairs
+// a pressure fit gate's bottom bar is a trip hazard and the gate can be
+// pushed out, so it has to be screwed to the wall.
+func (l OpeningLocation) MountingType() MountingType {
+	if l == OpeningStairTop {
+		return MountingTypeHardwareFit
+	}
+	return MountingTypePressureFit
+}
+
+// Opening is one gap in a project that needs a gate, e.g. "Kitchen door".
+type Opening struct {
+	Name     string
+	Width    float32
+	Location OpeningLocation
+}
@@ -0,0 +1,269 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PromotionKind is how a promotion takes money off.
+type PromotionKind string
+
+const (
+	// PromotionPercentOff takes Percent off the items it applies to.
+	PromotionPercentOff PromotionKind = "percent_off"
+	// PromotionAmountOff takes Amount off the items it applies to, never
+	// more than they cost.
+	PromotionAmountOff PromotionKind = "amount_off"
+	// PromotionFreeShipping waives shipping on the order.
+	PromotionFreeShipping PromotionKind = "free_shipping"
+	// PromotionComboPrice sells GateID with ExtensionID for Amount whenever
+	// they are in a cart item together.
+	PromotionComboPrice PromotionKind = "combo_price"
+)
+
+var PromotionKinds = []PromotionKind{
+	PromotionPercentOff,
+	PromotionAmountOff,
+	PromotionFreeShipping,
+	PromotionComboPrice,
+}
+
+func (k PromotionKind) IsValid() bool {
+	for _, kind := range PromotionKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (k PromotionKind) Label() string {
+	switch k {
+	case PromotionPercentOff:
+		return "Percentage off"
+	case PromotionAmountOff:
+		return "Amount off"
+	case PromotionFreeShipping:
+		return "Free shipping"
+	case PromotionComboPrice:
+		return "Gate and extension combo price"
+	}
+	return string(k)
+}
+
+// Why a promotion cannot be used right now. The messages are shown to
+// customers.
+var (
+	ErrPromotionInactive   = errors.New("this code is not active")
+	ErrPromotionNotStarted = errors.New("this code is not valid yet")
+	ErrPromotionExpired    = errors.New("this code has expired")
+	ErrPromotionUsedUp     = errors.New("this code has been used up")
+	ErrPromotionNeedsEmail = errors.New("enter your email address to use this code")
+	ErrPromotionUsedByYou  = errors.New("you have already used this code")
+)
+
+// PromotionUseStatuses are the statuses of orders that count as a use of
+// their promotions: paid for, or still being paid for.
+var PromotionUseStatuses = append([]OrderStatus{
+	OrderStatusPendingPayment,
+	OrderStatusAwaitingPayment,
+}, PaidOrderStatuses...)
+
+// Promotion is a sale. With a Code customers enter it on their cart, without
+// one it applies to every cart by itself.
+type Promotion struct {
+	ID   int           `json:"id"`
+	Name string        `json:"name"` // shown to customers next to what it takes off
+	Code string        `json:"code"` // upper case, empty for automatic promotions
+	Kind PromotionKind `json:"kind"`
+
+	Percent int   `json:"percent"` // percent_off
+	Amount  Money `json:"amount"`  // amount_off, and the price of a combo
+	// MinExtensions limits the promotion to gates bought with at least this
+	// many extensions in the same cart item. 0 applies it to the whole cart.
+	MinExtensions int `json:"min_extensions"`
+	GateID        int `json:"gate_id"`      // combo_price
+	ExtensionID   int `json:"extension_id"` // combo_price
+
+	// StartsAt and EndsAt bound when the promotion can be used, zero for
+	// no bound.
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	// UsageLimit caps the orders placed with the promotion, and
+	// PerCustomerLimit those placed by one email address. 0 for no limit.
+	UsageLimit       int  `json:"usage_limit"`
+	PerCustomerLimit int  `json:"per_customer_limit"`
+	Active           bool `json:"active"`
+
+	// Uses is how many orders have been placed with the promotion, not
+	// counting those canceled or never paid for.
+	Uses      int       `json:"uses"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NormalizePromotionCode is how codes are stored and looked up, so
+// customers do not have to match their case.
+func NormalizePromotionCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Automatic reports whether the promotion applies without a code.
+func (p Promotion) Automatic() bool {
+	return p.Code == ""
+}
+
+// Validate checks the promotion makes sense before it is saved.
+func (p Promotion) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("a promotion needs a name")
+	}
+	if p.Code != NormalizePromotionCode(p.Code) || strings.ContainsAny(p.Code, " \t") {
+		return fmt.Errorf("code %q must be upper case without spaces", p.Code)
+	}
+	switch p.Kind {
+	case PromotionPercentOff:
+		if p.Percent < 1 || p.Percent > 100 {
+			return fmt.Errorf("percent must be from 1 to 100, not %d", p.Percent)
+		}
+	case PromotionAmountOff:
+		if p.Amount.Amount <= 0 {
+			return errors.New("amount off must be more than 0")
+		}
+	case PromotionFreeShipping:
+	case PromotionComboPrice:
+		if p.GateID == 0 || p.ExtensionID == 0 {
+			return errors.New("a combo price needs a gate and an extension")
+		}
+		if p.Amount.Amount <= 0 {
+			return errors.New("a combo price must be more than 0")
+		}
+	default:
+		return fmt.Errorf("unknown kind of promotion %q", p.Kind)
+	}
+	if p.MinExtensions < 0 || p.UsageLimit < 0 || p.PerCustomerLimit < 0 {
+		return errors.New("limits cannot be negative")
+	}
+	if !p.StartsAt.IsZero() && !p.EndsAt.IsZero() && !p.EndsAt.After(p.StartsAt) {
+		return errors.New("a promotion has to end after it starts")
+	}
+	return nil
+}
+
+// Available reports why the promotion cannot be used at now, or nil if it
+// can. customerUses is how many orders the customer has placed with it, and
+// email their address, empty when it is not known.
+func (p Promotion) Available(now time.Time, email string, customerUses int) error {
+	switch {
+	case !p.Active:
+		return ErrPromotionInactive
+	case !p.StartsAt.IsZero() && now.Before(p.StartsAt):
+		return ErrPromotionNotStarted
+	case !p.EndsAt.IsZero() && !now.Before(p.EndsAt):
+		return ErrPromotionExpired
+	case p.UsageLimit > 0 && p.Uses >= p.UsageLimit:
+		return ErrPromotionUsedUp
+	case p.PerCustomerLimit > 0 && email == "" && !p.Automatic():
+		return ErrPromotionNeedsEmail
+	case p.PerCustomerLimit > 0 && customerUses >= p.PerCustomerLimit:
+		return ErrPromotionUsedByYou
+	}
+	return nil
+}
+
+// Describe says what the promotion takes off, e.g. "10% off bundles with 2
+// or more extensions".
+func (p Promotion) Describe() string {
+	var what string
+	switch p.Kind {
+	case PromotionPercentOff:
+		what = fmt.Sprintf("%d%% off", p.Percent)
+	case PromotionAmountOff:
+		what = p.Amount.String() + " off"
+	case PromotionFreeShipping:
+		what = "Free shipping"
+	case PromotionComboPrice:
+		return fmt.Sprintf("Gate %d with extension %d for %s", p.GateID, p.ExtensionID, p.Amount)
+	}
+	if p.MinExtensions > 0 {
+		return fmt.Sprintf("%s bundles with %d or more extensions", what, p.MinExtensions)
+	}
+	return what + " the order"
+}
+
+// appliesTo reports whether the promotion covers item.
+func (p Promotion) appliesTo(item CartItem) bool {
+	if p.MinExtensions == 0 {
+		return true
+	}
+	if len(item.Components) == 0 || item.Components[0].Type != ProductTypeGate {
+		return false
+	}
+	extensions := 0
+	for _, component := range item.Components[1:] {
+		if component.Type == ProductTypeExtension {
+			extensions += component.Qty
+		}
+	}
+	return extensions >= p.MinExtensions
+}
+
+// Apply works out the discount the promotion gives items. It is not due
+// when no item qualifies or it would take nothing off, other than free
+// shipping which takes nothing off the items.
+func (p Promotion) Apply(items []CartItem) (Discount, bool) {
+	discount := Discount{Label: p.Name, PromotionID: p.ID}
+
+	var eligible Money
+	qualifies := false
+	for _, item := range items {
+		if p.appliesTo(item) {
+			qualifies = true
+			eligible = eligible.Add(item.SalePrice.Mul(item.Qty))
+		}
+	}
+
+	switch p.Kind {
+	case PromotionPercentOff:
+		// rounded down to the cent
+		discount.Amount = NewMoney(eligible.Amount*int64(p.Percent)/100, eligible.Currency)
+	case PromotionAmountOff:
+		if eligible.Currency != "" && eligible.Currency != p.Amount.Currency {
+			return Discount{}, false
+		}
+		discount.Amount = NewMoney(min(p.Amount.Amount, eligible.Amount), p.Amount.Currency)
+	case PromotionFreeShipping:
+		discount.FreeShipping = true
+		discount.Amount = NewMoney(0, eligible.Currency)
+		return discount, qualifies
+	case PromotionComboPrice:
+		for _, item := range items {
+			if !p.appliesTo(item) {
+				continue
+			}
+			var gate, extension *CartItemComponent
+			for i := range item.Components {
+				switch item.Components[i].Id {
+				case p.GateID:
+					gate = &item.Components[i]
+				case p.ExtensionID:
+					extension = &item.Components[i]
+				}
+			}
+			if gate == nil || extension == nil || gate.Price.Currency != p.Amount.Currency {
+				continue
+			}
+			saving := gate.Price.Add(extension.Price).Sub(p.Amount)
+			if saving.Amount <= 0 {
+				continue
+			}
+			combos := min(gate.Qty, extension.Qty) * item.Qty
+			discount.Amount = discount.Amount.Add(saving.Mul(combos))
+		}
+	}
+	if discount.Amount.Amount <= 0 {
+		return Discount{}, false
+	}
+	return discount, true
+}
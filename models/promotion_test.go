@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromotionApply(t *testing.T) {
+	gate := Product{Id: 1, Type: ProductTypeGate, Price: EUR(6000), Qty: 1}
+	extension := Product{Id: 5, Type: ProductTypeExtension, Price: EUR(1000), Qty: 2}
+	bundle := CartItem{
+		SalePrice:  EUR(8000),
+		Qty:        1,
+		Components: []CartItemComponent{{Product: gate}, {Product: extension}},
+	}
+	loose := CartItem{
+		SalePrice:  EUR(1000),
+		Qty:        3,
+		Components: []CartItemComponent{{Product: Product{Id: 5, Type: ProductTypeExtension, Price: EUR(1000), Qty: 1}}},
+	}
+	items := []CartItem{bundle, loose}
+
+	bundles := Promotion{ID: 1, Name: "Big openings", Kind: PromotionPercentOff, Percent: 10, MinExtensions: 2}
+	discount, ok := bundles.Apply(items)
+	require.True(t, ok)
+	require.Equal(t, Discount{Label: "Big openings", Amount: EUR(800), PromotionID: 1}, discount, "only the bundle has 2 extensions")
+
+	bundles.MinExtensions = 3
+	_, ok = bundles.Apply(items)
+	require.False(t, ok)
+
+	fiver := Promotion{ID: 2, Name: "Fiver", Kind: PromotionAmountOff, Amount: EUR(500)}
+	discount, ok = fiver.Apply(items)
+	require.True(t, ok)
+	require.Equal(t, EUR(500), discount.Amount)
+
+	combo := Promotion{ID: 3, Name: "Combo", Kind: PromotionComboPrice, GateID: 1, ExtensionID: 5, Amount: EUR(6500)}
+	discount, ok = combo.Apply(items)
+	require.True(t, ok)
+	require.Equal(t, EUR(500), discount.Amount, "the gate and an extension for 65 rather than 70")
+
+	shipping := Promotion{ID: 4, Name: "Free delivery", Kind: PromotionFreeShipping}
+	discount, ok = shipping.Apply(items)
+	require.True(t, ok)
+	require.True(t, discount.FreeShipping)
+	require.True(t, discount.Amount.IsZero())
+
+	cart := Cart{Items: items}
+	cart.SetTotalValue()
+	cart.ApplyDiscounts(MultiGateDiscount{}, Promotion{Name: "Everything", Kind: PromotionPercentOff, Percent: 100}, fiver, shipping)
+	require.Equal(t, EUR(11000), cart.DiscountTotal(), "discounts never take off more than the total")
+	require.True(t, cart.AmountDue().IsZero())
+	require.True(t, cart.FreeShipping())
+}
+
+func TestPromotionAvailable(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	p := Promotion{Code: "SPRING", Active: true, StartsAt: now.AddDate(0, 0, -1), EndsAt: now.AddDate(0, 0, 1)}
+	require.NoError(t, p.Available(now, "", 0))
+
+	require.ErrorIs(t, p.Available(now.AddDate(0, 0, -2), "", 0), ErrPromotionNotStarted)
+	require.ErrorIs(t, p.Available(now.AddDate(0, 0, 1), "", 0), ErrPromotionExpired)
+
+	p.UsageLimit, p.Uses = 10, 10
+	require.ErrorIs(t, p.Available(now, "", 0), ErrPromotionUsedUp)
+
+	p.UsageLimit, p.PerCustomerLimit = 0, 1
+	require.ErrorIs(t, p.Available(now, "", 0), ErrPromotionNeedsEmail)
+	require.NoError(t, p.Available(now, "a@example.com", 0))
+	require.ErrorIs(t, p.Available(now, "a@example.com", 1), ErrPromotionUsedByYou)
+
+	p.Active = false
+	require.ErrorIs(t, p.Available(now, "a@example.com", 0), ErrPromotionInactive)
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// Refund is money given back on an order through the payment provider.
+// ProviderRef is the provider's id for the refund.
+type Refund struct {
+	ID          int
+	OrderID     int
+	Provider    string
+	ProviderRef string
+	Amount      Money
+	Restocked   bool
+	Actor       string
+	Note        sql.NullString
+	CreatedAt   time.Time
+	Lines       []RefundLine
+}
+
+// RefundLine is the part of a refund covering Qty units of one component of
+// an order item. Amount is for all Qty units.
+type RefundLine struct {
+	OrderItemID int
+	ProductID   int
+	Qty         int
+	Amount      Money
+}
+
+// RefundableComponent is one component of an order item and how many units of
+// it have not been refunded yet.
+type RefundableComponent struct {
+	OrderItemID int
+	ItemName    string
+	Product     Product
+	// Ordered is the component's quantity times the item's quantity.
+	Ordered    int
+	Refundable int
+	// Paid is what was paid for all Ordered units, after their share of the
+	// order's discounts.
+	Paid Money
+}
+
+// Key identifies the component within its order.
+func (c RefundableComponent) Key() string {
+	return strconv.Itoa(c.OrderItemID) + "_" + strconv.Itoa(c.Product.Id)
+}
+
+// RefundAmount is what refunding qty more units of the component gives back,
+// their share of Paid. Refunding every unit gives back Paid exactly.
+func (c RefundableComponent) RefundAmount(qty int) Money {
+	if c.Ordered == 0 {
+		return NewMoney(0, c.Paid.Currency)
+	}
+	refunded := int64(c.Ordered - c.Refundable)
+	before := c.Paid.Amount * refunded / int64(c.Ordered)
+	after := c.Paid.Amount * (refunded + int64(qty)) / int64(c.Ordered)
+	return NewMoney(after-before, c.Paid.Currency)
+}
+
+// RefundableComponents lists every component of items with what is left to
+// refund after refunds, and what was paid for it after discounts. Items must
+// carry their order item id in ID, as returned for an order.
+func RefundableComponents(items []CartItem, discounts []Discount, refunds []Refund) []RefundableComponent {
+	type key struct{ item, product int }
+	refunded := map[key]int{}
+	for _, refund := range refunds {
+		for _, line := range refund.Lines {
+			refunded[key{line.OrderItemID, line.ProductID}] += line.Qty
+		}
+	}
+
+	var components []RefundableComponent
+	var grosses []Money
+	for _, item := range items {
+		itemID, _ := strconv.Atoi(item.ID)
+		for _, component := range item.Components {
+			ordered := component.Qty * item.Qty
+			components = append(components, RefundableComponent{
+				OrderItemID: itemID,
+				ItemName:    item.Name,
+				Product:     component.Product,
+				Ordered:     ordered,
+				Refundable:  max(ordered-refunded[key{itemID, component.Id}], 0),
+			})
+			grosses = append(grosses, component.Price.Mul(ordered))
+		}
+	}
+
+	var discount Money
+	for _, d := range discounts {
+		discount = discount.Add(d.Amount)
+	}
+	for i, paid := range shareDiscount(grosses, discount) {
+		components[i].Paid = paid
+	}
+	return components
+}
+
+// OrderTotal is what was charged for an order's items.
+func OrderTotal(items []CartItem) Money {
+	var total Money
+	for _, item := range items {
+		for _, component := range item.Components {
+			total = total.Add(component.Price.Mul(component.Qty * item.Qty))
+		}
+	}
+	return total
+}
+
+// RefundedTotal sums refunds.
+func RefundedTotal(refunds []Refund) Money {
+	var total Money
+	for _, refund := range refunds {
+		total = total.Add(refund.Amount)
+	}
+	return total
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundableComponentsShareDiscounts(t *testing.T) {
+	items := []CartItem{{
+		ID:  "7",
+		Qty: 3,
+		Components: []CartItemComponent{
+			{Product: Product{Id: 1, Price: EUR(6000), Qty: 1}},
+			{Product: Product{Id: 5, Price: EUR(1000), Qty: 2}},
+		},
+	}}
+	discounts := []Discount{{Label: "Sale", Amount: EUR(2500)}}
+
+	components := RefundableComponents(items, discounts, nil)
+	require.Len(t, components, 2)
+	require.Equal(t, EUR(18000-1875), components[0].Paid, "three quarters of the discount")
+	require.Equal(t, EUR(6000-625), components[1].Paid)
+
+	// a partial refund gives back what was paid for the units, not their
+	// list price
+	gate := components[0]
+	require.Equal(t, EUR(5375), gate.RefundAmount(1))
+
+	extension := components[1]
+	var refunded Money
+	refunds := []Refund{}
+	for _, qty := range []int{1, 1, 4} {
+		amount := extension.RefundAmount(qty)
+		refunded = refunded.Add(amount)
+		refunds = append(refunds, Refund{Amount: amount, Lines: []RefundLine{{OrderItemID: 7, ProductID: 5, Qty: qty, Amount: amount}}})
+		extension = RefundableComponents(items, discounts, refunds)[1]
+	}
+	require.Zero(t, extension.Refundable)
+	require.Equal(t, components[1].Paid, refunded, "refunding every unit gives back what was paid, whatever the rounding")
+
+	require.Equal(t, EUR(18000), RefundableComponents(items, nil, nil)[0].Paid)
+}
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ShippingMethod is how an order gets to the customer.
+type ShippingMethod string
+
+const (
+	ShippingDelivery ShippingMethod = "delivery"
+	// ShippingCollect orders are picked up from the shop. Once ready they
+	// wait in OrderStatusAwaitingPickup instead of being shipped.
+	ShippingCollect ShippingMethod = "collect"
+)
+
+func (m ShippingMethod) IsValid() bool {
+	return m == ShippingDelivery || m == ShippingCollect
+}
+
+// deliveryStatuses are only for orders being delivered, and collectStatuses
+// only for those being collected.
+var (
+	deliveryStatuses = []OrderStatus{
+		OrderStatusAwaitingShipment,
+		OrderStatusPartiallyShipped,
+		OrderStatusShipped,
+		OrderStatusOutForDelivery,
+		OrderStatusDelivered,
+	}
+	collectStatuses = []OrderStatus{OrderStatusAwaitingPickup, OrderStatusPickedUp}
+)
+
+// Allows reports whether an order going by m can be in status s. Orders
+// with no method, placed before customers had a choice, can be in any.
+func (m ShippingMethod) Allows(s OrderStatus) bool {
+	switch m {
+	case ShippingDelivery:
+		return !slices.Contains(collectStatuses, s)
+	case ShippingCollect:
+		return !slices.Contains(deliveryStatuses, s)
+	}
+	return true
+}
+
+// ShippingBasis is what a shipping rate's bands measure.
+type ShippingBasis string
+
+const (
+	ShippingByWeight ShippingBasis = "weight" // the order's weight in grams
+	ShippingByValue  ShippingBasis = "value"  // the order's value after discounts, in minor units
+)
+
+func (b ShippingBasis) IsValid() bool {
+	return b == ShippingByWeight || b == ShippingByValue
+}
+
+// ShippingRate is one band of a zone's rate table for a service: orders
+// measuring from Min up to but not including Max cost Price to deliver. Max
+// is 0 for no upper bound.
+type ShippingRate struct {
+	ID      int           `json:"id"`
+	ZoneID  int           `json:"zone_id"`
+	Service string        `json:"service"` // e.g. "Standard delivery"
+	Basis   ShippingBasis `json:"basis"`
+	Min     int64         `json:"min"`
+	Max     int64         `json:"max"`
+	Price   Money         `json:"price"`
+}
+
+// Covers reports whether an order weighing weight grams and worth value
+// falls in the band.
+func (r ShippingRate) Covers(weight int, value Money) bool {
+	measure := int64(weight)
+	if r.Basis == ShippingByValue {
+		measure = value.Amount
+	}
+	return measure >= r.Min && (r.Max == 0 || measure < r.Max)
+}
+
+// Band describes what orders the rate covers, e.g. "2000 g to 5000 g" or
+// "€100.00 and over".
+func (r ShippingRate) Band() string {
+	bound := func(v int64) string {
+		if r.Basis == ShippingByValue {
+			return NewMoney(v, r.Price.Currency).String()
+		}
+		return fmt.Sprintf("%d g", v)
+	}
+	if r.Max == 0 {
+		return bound(r.Min) + " and over"
+	}
+	return bound(r.Min) + " to " + bound(r.Max)
+}
+
+// Validate checks the rate makes sense before it is saved.
+func (r ShippingRate) Validate() error {
+	if strings.TrimSpace(r.Service) == "" {
+		return errors.New("a shipping rate needs a service")
+	}
+	if !r.Basis.IsValid() {
+		return fmt.Errorf("unknown shipping rate basis %q", r.Basis)
+	}
+	if r.Min < 0 || r.Max < 0 || r.Price.Amount < 0 {
+		return errors.New("shipping rates cannot be negative")
+	}
+	if r.Max != 0 && r.Max <= r.Min {
+		return errors.New("a shipping rate's upper bound has to be above its lower bound")
+	}
+	return nil
+}
+
+// ShippingZone is a group of countries delivered to at the same rates.
+type ShippingZone struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Countries []string `json:"countries"` // ISO 3166-1 alpha-2 codes
+	// FreeOver is the order value, after discounts, from which delivery is
+	// free. Zero for never.
+	FreeOver Money          `json:"free_over"`
+	Rates    []ShippingRate `json:"rates"`
+}
+
+// ParseCountries reads a list of country codes separated by commas or
+// spaces, e.g. "ie, gb", upper casing them and dropping duplicates.
+func ParseCountries(raw string) ([]string, error) {
+	var countries []string
+	for _, field := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' }) {
+		code := strings.ToUpper(field)
+		if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+			return nil, fmt.Errorf("%q is not a two letter country code", field)
+		}
+		if !slices.Contains(countries, code) {
+			countries = append(countries, code)
+		}
+	}
+	return countries, nil
+}
+
+// Validate checks the zone makes sense before it is saved. Its rates are
+// checked when they are added.
+func (z ShippingZone) Validate() error {
+	if strings.TrimSpace(z.Name) == "" {
+		return errors.New("a shipping zone needs a name")
+	}
+	if len(z.Countries) == 0 {
+		return errors.New("a shipping zone needs at least one country")
+	}
+	if z.FreeOver.Amount < 0 {
+		return errors.New("the free shipping threshold cannot be negative")
+	}
+	return nil
+}
+
+// Options quotes each of the zone's services for an order weighing weight
+// grams and worth value after discounts, in the order the services first
+// appear in Rates. The first band of a service that covers the order prices
+// it, and services with none do not deliver it. Delivery is free once value
+// reaches FreeOver, or when freeShipping.
+func (z ShippingZone) Options(weight int, value Money, freeShipping bool) []ShippingOption {
+	free := freeShipping || (!z.FreeOver.IsZero() && value.Amount >= z.FreeOver.Amount)
+
+	var options []ShippingOption
+	quoted := map[string]bool{}
+	for _, rate := range z.Rates {
+		if quoted[rate.Service] || !rate.Covers(weight, value) {
+			continue
+		}
+		quoted[rate.Service] = true
+
+		price := rate.Price
+		if free {
+			price = NewMoney(0, rate.Price.Currency)
+		}
+		options = append(options, ShippingOption{
+			Method:    ShippingDelivery,
+			ZoneID:    z.ID,
+			Service:   rate.Service,
+			Name:      z.Name + ", " + rate.Service,
+			Price:     price,
+			Countries: z.Countries,
+		})
+	}
+	return options
+}
+
+// ShippingOption is a way a cart can get to the customer, priced for it.
+type ShippingOption struct {
+	Method  ShippingMethod `json:"method"`
+	ZoneID  int            `json:"zone_id"` // delivery only
+	Service string         `json:"service"` // delivery only
+	// Name is shown to customers, e.g. "Ireland, Standard delivery".
+	Name  string `json:"name"`
+	Price Money  `json:"price"`
+	// Countries are where a delivery can be sent, and Address where a
+	// collection is picked up from.
+	Countries []string `json:"countries"`
+	Address   string   `json:"address"`
+}
+
+// CollectOption is click and collect from address, which is free.
+func CollectOption(address string) ShippingOption {
+	return ShippingOption{
+		Method:  ShippingCollect,
+		Name:    "Click and collect",
+		Price:   NewMoney(0, DefaultCurrency),
+		Address: address,
+	}
+}
+
+// Key identifies the option in forms, e.g. "delivery:1:Standard delivery"
+// or "collect".
+func (o ShippingOption) Key() string {
+	if o.Method == ShippingCollect {
+		return string(ShippingCollect)
+	}
+	return fmt.Sprintf("%s:%d:%s", o.Method, o.ZoneID, o.Service)
+}
+
+// PriceLabel is the price shown to customers, "Free" when there is none.
+func (o ShippingOption) PriceLabel() string {
+	if o.Price.IsZero() {
+		return "Free"
+	}
+	return o.Price.String()
+}
+
+// Weight is what the cart's items weigh in grams.
+func (c Cart) Weight() int {
+	weight := 0
+	for _, item := range c.Items {
+		for _, component := range item.Components {
+			weight += component.Weight * component.Qty * item.Qty
+		}
+	}
+	return weight
+}
+
+// QuoteShipping prices delivery to each of zones for the cart, after its
+// discounts, followed by collect when it is offered, and chooses the option
+// with key. Without one, or when it no longer applies, the first option is
+// chosen.
+func (c *Cart) QuoteShipping(zones []ShippingZone, collect *ShippingOption, key string) {
+	weight, value, free := c.Weight(), c.AmountDue(), c.FreeShipping()
+
+	c.ShippingOptions = nil
+	for _, zone := range zones {
+		c.ShippingOptions = append(c.ShippingOptions, zone.Options(weight, value, free)...)
+	}
+	if collect != nil {
+		c.ShippingOptions = append(c.ShippingOptions, *collect)
+	}
+
+	c.Shipping = ShippingOption{}
+	for _, option := range c.ShippingOptions {
+		if option.Key() == key {
+			c.Shipping = option
+			return
+		}
+	}
+	if len(c.ShippingOptions) > 0 {
+		c.Shipping = c.ShippingOptions[0]
+	}
+}
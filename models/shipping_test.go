@@ -0,0 +1,111 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShippingZoneOptions(t *testing.T) {
+	zone := ShippingZone{
+		ID:        1,
+		Name:      "Ireland",
+		Countries: []string{"IE"},
+		FreeOver:  EUR(10000),
+		Rates: []ShippingRate{
+			{Service: "Standard", Basis: ShippingByWeight, Min: 0, Max: 10000, Price: EUR(695)},
+			{Service: "Standard", Basis: ShippingByWeight, Min: 10000, Price: EUR(995)},
+			{Service: "Next day", Basis: ShippingByValue, Min: 0, Max: 5000, Price: EUR(1500)},
+		},
+	}
+
+	options := zone.Options(5500, EUR(4000), false)
+	require.Len(t, options, 2)
+	require.Equal(t, ShippingOption{
+		Method: ShippingDelivery, ZoneID: 1, Service: "Standard", Name: "Ireland, Standard",
+		Price: EUR(695), Countries: []string{"IE"},
+	}, options[0])
+	require.Equal(t, "delivery:1:Standard", options[0].Key())
+	require.Equal(t, EUR(1500), options[1].Price)
+
+	options = zone.Options(12000, EUR(6000), false)
+	require.Len(t, options, 1, "next day only takes orders under €50")
+	require.Equal(t, EUR(995), options[0].Price, "the heavier band")
+
+	options = zone.Options(12000, EUR(10000), false)
+	require.Equal(t, "Free", options[0].PriceLabel(), "free from the threshold")
+
+	options = zone.Options(5500, EUR(4000), true)
+	require.True(t, options[0].Price.IsZero())
+	require.True(t, options[1].Price.IsZero(), "free shipping covers every service")
+
+	require.Equal(t, "0 g to 10000 g", zone.Rates[0].Band())
+	require.Equal(t, "10000 g and over", zone.Rates[1].Band())
+}
+
+func TestShippingValidation(t *testing.T) {
+	countries, err := ParseCountries(" ie, gb IE")
+	require.NoError(t, err)
+	require.Equal(t, []string{"IE", "GB"}, countries)
+	_, err = ParseCountries("Ireland")
+	require.Error(t, err)
+
+	require.Error(t, ShippingZone{Name: "Nowhere"}.Validate())
+	require.NoError(t, ShippingZone{Name: "Ireland", Countries: []string{"IE"}}.Validate())
+
+	rate := ShippingRate{Service: "Standard", Basis: ShippingByWeight, Min: 1000, Max: 1000, Price: EUR(500)}
+	require.Error(t, rate.Validate(), "the band is empty")
+	rate.Max = 0
+	require.NoError(t, rate.Validate())
+	rate.Basis = "distance"
+	require.Error(t, rate.Validate())
+}
+
+func TestCartQuoteShipping(t *testing.T) {
+	cart := Cart{
+		TotalValue: EUR(7600),
+		Items: []CartItem{{
+			Qty:        2,
+			SalePrice:  EUR(3800),
+			Components: []CartItemComponent{{Product: Product{Weight: 1000, Qty: 3}}},
+		}},
+	}
+	require.Equal(t, 6000, cart.Weight())
+
+	zones := []ShippingZone{{
+		ID: 1, Name: "Ireland", Countries: []string{"IE"},
+		Rates: []ShippingRate{{Service: "Standard", Basis: ShippingByWeight, Price: EUR(695)}},
+	}}
+	collect := CollectOption("Unit 1, Main Street")
+
+	cart.QuoteShipping(zones, &collect, "")
+	require.Len(t, cart.ShippingOptions, 2)
+	require.Equal(t, "delivery:1:Standard", cart.Shipping.Key(), "the first option without a choice")
+	require.Equal(t, EUR(8295), cart.Total())
+
+	cart.QuoteShipping(zones, &collect, "collect")
+	require.Equal(t, ShippingCollect, cart.Shipping.Method)
+	require.Equal(t, EUR(7600), cart.Total())
+
+	cart.QuoteShipping(zones, nil, "collect")
+	require.Equal(t, ShippingDelivery, cart.Shipping.Method, "collect is no longer offered")
+
+	cart.QuoteShipping(nil, nil, "collect")
+	require.Empty(t, cart.ShippingOptions)
+	require.Equal(t, ShippingMethod(""), cart.Shipping.Method)
+}
+
+func TestOrderStatusesByShippingMethod(t *testing.T) {
+	order := Order{Status: OrderStatusAwaitingFulfillment, ShippingMethod: ShippingCollect}
+	require.True(t, order.CanTransitionTo(OrderStatusAwaitingPickup))
+	require.False(t, order.CanTransitionTo(OrderStatusAwaitingShipment))
+	require.NotContains(t, order.NextStatuses(), OrderStatusAwaitingShipment)
+
+	order.ShippingMethod = ShippingDelivery
+	require.False(t, order.CanTransitionTo(OrderStatusAwaitingPickup))
+	require.Contains(t, order.NextStatuses(), OrderStatusAwaitingShipment)
+
+	order.ShippingMethod = ""
+	require.True(t, order.CanTransitionTo(OrderStatusAwaitingPickup), "orders from before shipping methods")
+	require.True(t, order.CanTransitionTo(OrderStatusAwaitingShipment))
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// StockMovementReason records why a product's inventory level changed.
+type StockMovementReason string
+
+const (
+	StockMovementSale       StockMovementReason = "sale"       // Order paid, stock leaves the warehouse
+	StockMovementCancel     StockMovementReason = "cancel"     // Paid order canceled, stock returned
+	StockMovementRefund     StockMovementReason = "refund"     // Paid order refunded, stock returned
+	StockMovementAdjustment StockMovementReason = "adjustment" // Manual change from the admin dashboard
+)
+
+// StockMovement is a single row in the stock ledger. Delta is negative when
+// stock leaves and positive when it comes back. OrderID is null for manual
+// adjustments.
+type StockMovement struct {
+	ID        int                 `json:"id"`
+	ProductID int                 `json:"product_id"`
+	OrderID   sql.NullInt64       `json:"order_id"`
+	Delta     int                 `json:"delta"`
+	Reason    StockMovementReason `json:"reason"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// ReservationStatus tracks a stock reservation from checkout to payment.
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"    // Held for an order that is still in checkout
+	ReservationReleased  ReservationStatus = "released"  // Checkout expired or the order was canceled
+	ReservationConverted ReservationStatus = "converted" // Order paid, reservation became a sale movement
+)
+
+// StockReservation holds stock for an order while the customer pays. Active
+// reservations past ExpiresAt no longer count against availability, even
+// before the sweeper marks them released.
+type StockReservation struct {
+	ID        int               `json:"id"`
+	OrderID   int               `json:"order_id"`
+	ProductID int               `json:"product_id"`
+	Qty       int               `json:"qty"`
+	Status    ReservationStatus `json:"status"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+}
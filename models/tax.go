@@ -0,0 +1,260 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaxClass is which of a country's VAT rates a product is sold at.
+type TaxClass string
+
+const (
+	TaxStandard TaxClass = "standard"
+	TaxReduced  TaxClass = "reduced"
+	TaxZero     TaxClass = "zero"
+)
+
+var TaxClasses = []TaxClass{TaxStandard, TaxReduced, TaxZero}
+
+func (c TaxClass) IsValid() bool {
+	for _, class := range TaxClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (c TaxClass) Label() string {
+	switch c {
+	case TaxStandard:
+		return "Standard rate"
+	case TaxReduced:
+		return "Reduced rate"
+	case TaxZero:
+		return "Zero rate"
+	}
+	return string(c)
+}
+
+// HomeCountry is where the shop is registered for VAT. Its rates apply to
+// orders collected from the shop and to countries without rates of their
+// own.
+const HomeCountry = "IE"
+
+// VATRate is what a country charges on products of a tax class.
+type VATRate struct {
+	Country string   `json:"country"` // ISO 3166-1 alpha-2
+	Class   TaxClass `json:"class"`
+	// Rate is in basis points, so 2300 is 23% and 1350 is 13.5%.
+	Rate int `json:"rate"`
+}
+
+// FormatVATRate shows a rate in basis points as a percentage, e.g. "23%" or
+// "13.5%".
+func FormatVATRate(rate int) string {
+	return strconv.FormatFloat(float64(rate)/100, 'f', -1, 64) + "%"
+}
+
+// ParseVATRate reads a percentage like "23" or "13.5%" into basis points.
+func ParseVATRate(raw string) (int, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(raw), "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse vat rate %q: %w", raw, err)
+	}
+	rate := math.Round(percent * 100)
+	if math.Abs(rate-percent*100) > 1e-6 {
+		return 0, fmt.Errorf("parse vat rate %q: more than two decimal places", raw)
+	}
+	return int(rate), nil
+}
+
+func (r VATRate) Percent() string {
+	return FormatVATRate(r.Rate)
+}
+
+// Validate checks the rate makes sense before it is saved.
+func (r VATRate) Validate() error {
+	if codes, err := ParseCountries(r.Country); err != nil || len(codes) != 1 || codes[0] != r.Country {
+		return fmt.Errorf("%q is not a two letter country code", r.Country)
+	}
+	if !r.Class.IsValid() {
+		return fmt.Errorf("unknown tax class %q", r.Class)
+	}
+	if r.Rate < 0 || r.Rate >= 10000 {
+		return errors.New("a VAT rate must be from 0% up to 100%")
+	}
+	return nil
+}
+
+// VATRates are the rates of every country VAT is charged at.
+type VATRates []VATRate
+
+// Lookup is the rate country charges on class, falling back to the home
+// country's when country has none. Classes no country has a rate for are
+// charged nothing.
+func (rates VATRates) Lookup(country string, class TaxClass) int {
+	home := -1
+	for _, rate := range rates {
+		if rate.Class != class {
+			continue
+		}
+		if rate.Country == country {
+			return rate.Rate
+		}
+		if rate.Country == HomeCountry {
+			home = rate.Rate
+		}
+	}
+	return max(home, 0)
+}
+
+// TaxLine is the VAT in what the customer pays for one line of a cart or
+// order. Prices include VAT, so Gross is what they pay and Net and VAT are
+// split out of it.
+type TaxLine struct {
+	Class TaxClass `json:"class"`
+	Rate  int      `json:"rate"` // basis points
+	Gross Money    `json:"gross"`
+	Net   Money    `json:"net"`
+	VAT   Money    `json:"vat"`
+}
+
+// NewTaxLine splits gross, which includes VAT at rate basis points, into net
+// and VAT, rounding the VAT to the nearest cent.
+func NewTaxLine(class TaxClass, rate int, gross Money) TaxLine {
+	denominator := int64(10000 + rate)
+	vat := (gross.Amount*int64(rate) + denominator/2) / denominator
+	return TaxLine{
+		Class: class,
+		Rate:  rate,
+		Gross: gross,
+		Net:   NewMoney(gross.Amount-vat, gross.Currency),
+		VAT:   NewMoney(vat, gross.Currency),
+	}
+}
+
+// Percent is the line's rate as a percentage, e.g. "23%".
+func (l TaxLine) Percent() string {
+	return FormatVATRate(l.Rate)
+}
+
+// VATBreakdown totals lines by rate, highest first, which is how VAT is shown
+// on receipts and in returns. Lines with nothing in them are left out.
+func VATBreakdown(lines ...TaxLine) []TaxLine {
+	var totals []TaxLine
+	for _, line := range lines {
+		if line.Gross.IsZero() {
+			continue
+		}
+		i := 0
+		for i < len(totals) && totals[i].Rate > line.Rate {
+			i++
+		}
+		if i == len(totals) || totals[i].Rate != line.Rate {
+			totals = slices.Insert(totals, i, TaxLine{Class: line.Class, Rate: line.Rate})
+		}
+		totals[i].Gross = totals[i].Gross.Add(line.Gross)
+		totals[i].Net = totals[i].Net.Add(line.Net)
+		totals[i].VAT = totals[i].VAT.Add(line.VAT)
+	}
+	return totals
+}
+
+// TaxCountry is the country whose VAT rates the cart is charged at: where it
+// is delivered when its shipping zone is a single country, the home country
+// otherwise. Orders delivered to a zone of several countries are charged
+// again at their country's rates once the address is known.
+func (c Cart) TaxCountry() string {
+	if c.Shipping.Method == ShippingDelivery && len(c.Shipping.Countries) == 1 {
+		return c.Shipping.Countries[0]
+	}
+	return HomeCountry
+}
+
+// ApplyVAT works out the VAT in each of the cart's components, after its
+// share of the discounts, and in its shipping. Call it again after the
+// discounts or shipping change.
+func (c *Cart) ApplyVAT(rates VATRates) {
+	country := c.TaxCountry()
+
+	var lines []*CartItemComponent
+	var grosses []Money
+	for i := range c.Items {
+		for j := range c.Items[i].Components {
+			component := &c.Items[i].Components[j]
+			lines = append(lines, component)
+			grosses = append(grosses, component.Price.Mul(component.Qty*c.Items[i].Qty))
+		}
+	}
+
+	// each line takes a share of the discounts in proportion to its price
+	grosses = shareDiscount(grosses, c.DiscountTotal())
+	for i, component := range lines {
+		class := component.TaxClass
+		if class == "" {
+			class = TaxStandard
+		}
+		component.Tax = NewTaxLine(class, rates.Lookup(country, class), grosses[i])
+	}
+
+	// delivery is taxed with the goods it delivers, at the standard rate
+	c.ShippingTax = NewTaxLine(TaxStandard, rates.Lookup(country, TaxStandard), c.Shipping.Price)
+}
+
+// TaxLines are the VAT in each of the cart's components and in its shipping.
+func (c Cart) TaxLines() []TaxLine {
+	var lines []TaxLine
+	for _, item := range c.Items {
+		for _, component := range item.Components {
+			lines = append(lines, component.Tax)
+		}
+	}
+	return append(lines, c.ShippingTax)
+}
+
+// VAT is the VAT included in the cart's total.
+func (c Cart) VAT() Money {
+	var total Money
+	for _, line := range c.TaxLines() {
+		total = total.Add(line.VAT)
+	}
+	return total
+}
+
+// VATBreakdown totals the VAT in the cart's total by rate.
+func (c Cart) VATBreakdown() []TaxLine {
+	return VATBreakdown(c.TaxLines()...)
+}
+
+// OrderVATLine is a line of a paid order, or its shipping, with the VAT in
+// it, as exported for VAT returns.
+type OrderVATLine struct {
+	OrderID     int
+	PlacedAt    time.Time
+	Status      OrderStatus
+	TaxCountry  string
+	Description string // the product's name, or the shipping option's
+	Qty         int
+	Tax         TaxLine
+}
+
+// OrderTaxLines are the VAT in each of an order's lines and in its shipping,
+// none when it was placed before VAT was recorded.
+func OrderTaxLines(order Order, items []CartItem) []TaxLine {
+	if order.TaxCountry == "" {
+		return nil
+	}
+	var lines []TaxLine
+	for _, item := range items {
+		for _, component := range item.Components {
+			lines = append(lines, component.Tax)
+		}
+	}
+	return append(lines, order.ShippingTax)
+}
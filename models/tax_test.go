@@ -0,0 +1,84 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTaxLine(t *testing.T) {
+	line := NewTaxLine(TaxStandard, 2300, EUR(7600))
+	require.Equal(t, EUR(1421), line.VAT, "7600 / 1.23 leaves 1421.14 of VAT")
+	require.Equal(t, EUR(6179), line.Net)
+	require.Equal(t, "23%", line.Percent())
+
+	line = NewTaxLine(TaxZero, 0, EUR(500))
+	require.Equal(t, EUR(500), line.Net)
+	require.True(t, line.VAT.IsZero())
+}
+
+func TestVATRates(t *testing.T) {
+	rate, err := ParseVATRate("13.5%")
+	require.NoError(t, err)
+	require.Equal(t, 1350, rate)
+	require.Equal(t, "13.5%", FormatVATRate(rate))
+	_, err = ParseVATRate("12.345")
+	require.Error(t, err)
+
+	require.NoError(t, VATRate{Country: "DE", Class: TaxStandard, Rate: 1900}.Validate())
+	require.Error(t, VATRate{Country: "de", Class: TaxStandard, Rate: 1900}.Validate())
+	require.Error(t, VATRate{Country: "DE", Class: "luxury", Rate: 1900}.Validate())
+
+	rates := VATRates{
+		{Country: "IE", Class: TaxStandard, Rate: 2300},
+		{Country: "IE", Class: TaxReduced, Rate: 1350},
+		{Country: "DE", Class: TaxStandard, Rate: 1900},
+	}
+	require.Equal(t, 1900, rates.Lookup("DE", TaxStandard))
+	require.Equal(t, 1350, rates.Lookup("DE", TaxReduced), "Ireland's where Germany has none")
+	require.Equal(t, 2300, rates.Lookup("GB", TaxStandard))
+	require.Equal(t, 0, rates.Lookup("DE", TaxZero))
+}
+
+func TestCartApplyVAT(t *testing.T) {
+	gate := Product{Id: 1, Type: ProductTypeGate, Price: EUR(6000), Qty: 1}
+	extension := Product{Id: 5, Type: ProductTypeExtension, Price: EUR(1000), Qty: 2, TaxClass: TaxReduced}
+	cart := Cart{
+		Items: []CartItem{{
+			Qty:        2,
+			SalePrice:  EUR(8000),
+			Components: []CartItemComponent{{Product: gate}, {Product: extension}},
+		}},
+		TotalValue: EUR(16000),
+		Discounts:  []Discount{{Label: "Sale", Amount: EUR(1600)}},
+		Shipping:   ShippingOption{Method: ShippingDelivery, Price: EUR(695), Countries: []string{"IE"}},
+	}
+	rates := VATRates{
+		{Country: "IE", Class: TaxStandard, Rate: 2300},
+		{Country: "IE", Class: TaxReduced, Rate: 1350},
+		{Country: "DE", Class: TaxStandard, Rate: 1900},
+	}
+
+	cart.ApplyVAT(rates)
+	gateTax, extensionTax := cart.Items[0].Components[0].Tax, cart.Items[0].Components[1].Tax
+	require.Equal(t, EUR(10800), gateTax.Gross, "two gates less three quarters of the discount")
+	require.Equal(t, EUR(3600), extensionTax.Gross)
+	require.Equal(t, 1350, extensionTax.Rate)
+	require.Equal(t, cart.AmountDue(), gateTax.Gross.Add(extensionTax.Gross), "the lines add up to what is paid for the items")
+	require.Equal(t, EUR(695), cart.ShippingTax.Gross)
+
+	breakdown := cart.VATBreakdown()
+	require.Len(t, breakdown, 2)
+	require.Equal(t, 2300, breakdown[0].Rate, "highest rate first")
+	require.Equal(t, EUR(10800+695), breakdown[0].Gross)
+	require.Equal(t, breakdown[0].VAT.Add(breakdown[1].VAT), cart.VAT())
+
+	cart.Shipping = ShippingOption{Method: ShippingDelivery, Price: EUR(1995), Countries: []string{"DE", "FR"}}
+	cart.ApplyVAT(rates)
+	require.Equal(t, HomeCountry, cart.TaxCountry(), "the country is not known yet")
+	require.Equal(t, 2300, cart.ShippingTax.Rate)
+
+	cart.Shipping.Countries = []string{"DE"}
+	cart.ApplyVAT(rates)
+	require.Equal(t, 1900, cart.Items[0].Components[0].Tax.Rate)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+)
+
+// WebhookEventStatus tracks a payment provider webhook from delivery to
+// being applied.
+type WebhookEventStatus string
+
+const (
+	WebhookEventReceived   WebhookEventStatus = "received"   // Verified and stored, not yet applied
+	WebhookEventProcessing WebhookEventStatus = "processing" // Being applied right now
+	WebhookEventProcessed  WebhookEventStatus = "processed"  // Applied, later deliveries are skipped
+	WebhookEventIgnored    WebhookEventStatus = "ignored"    // A type the shop does not act on
+	WebhookEventFailed     WebhookEventStatus = "failed"     // Applying it errored, can be retried or replayed
+)
+
+// WebhookEvent is a verified webhook as it was delivered. Payload is the raw
+// body, kept so failed events can be replayed.
+type WebhookEvent struct {
+	ID          int
+	Provider    string
+	EventID     string
+	Type        string
+	OrderID     sql.NullInt64
+	Payload     string
+	Status      WebhookEventStatus
+	Error       sql.NullString
+	Attempts    int
+	ReceivedAt  time.Time
+	ProcessedAt sql.NullTime
+}
+
+// WebhookEventReplayable are the statuses an admin may replay from. An event
+// left processing was interrupted part way, e.g. by a restart.
+var WebhookEventReplayable = []WebhookEventStatus{WebhookEventReceived, WebhookEventFailed, WebhookEventProcessing}
+
+// Replayable reports whether an admin may apply the event again.
+func (e WebhookEvent) Replayable() bool {
+	return slices.Contains(WebhookEventReplayable, e.Status)
+}
@@ -0,0 +1,450 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeSignatureHeader carries the signature on webhooks sent by Fake, in the
+// same "t=<unix>,v1=<hex hmac>" shape Stripe uses.
+const FakeSignatureHeader = "Fake-Signature"
+
+// fakeSignatureTolerance is how old a signed webhook may be before it is
+// rejected as a replay.
+const fakeSignatureTolerance = 5 * time.Minute
+
+// ErrSessionClosed is returned when paying or canceling a fake session that
+// is no longer open.
+var ErrSessionClosed = errors.New("checkout session is no longer open")
+
+type FakeSessionStatus string
+
+const (
+	FakeSessionOpen     FakeSessionStatus = "open"
+	FakeSessionPaid     FakeSessionStatus = "paid"
+	FakeSessionCanceled FakeSessionStatus = "canceled"
+	FakeSessionExpired  FakeSessionStatus = "expired"
+)
+
+// FakeSession is a checkout session held in memory by Fake.
+type FakeSession struct {
+	ID             string
+	PaymentID      string
+	Params         SessionParams
+	Status         FakeSessionStatus
+	Customer       *Customer
+	AmountRefunded int64
+}
+
+// Expired reports whether the session can no longer be paid.
+func (s FakeSession) Expired(now time.Time) bool {
+	return s.Status == FakeSessionOpen && !s.Params.ExpiresAt.IsZero() && now.After(s.Params.ExpiresAt)
+}
+
+// Fake is an in-memory provider for running purchases without a payment
+// processor. CreateSession points customers at a hosted checkout page served
+// by this application under /fake-checkout, and Pay sends signed webhooks to
+// /webhook the way Stripe would. Sessions are lost on restart.
+type Fake struct {
+	baseURL string
+	secret  []byte
+	client  *http.Client
+
+	mu       sync.Mutex
+	sessions map[string]*FakeSession
+}
+
+// NewFake returns a Fake that sends webhooks to baseURL + "/webhook". A random
+// signing secret is used when secret is empty; sender and receiver are the
+// same process so it never has to be shared.
+func NewFake(baseURL, secret string) (*Fake, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("fake payments: generate signing secret: %w", err)
+		}
+	}
+	return &Fake{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		secret:   key,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		sessions: map[string]*FakeSession{},
+	}, nil
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) CreateSession(ctx context.Context, p SessionParams) (Session, error) {
+	id, err := randomID("fake_cs_")
+	if err != nil {
+		return Session{}, fmt.Errorf("fake payments: create session (order_id=%d): %w", p.OrderID, err)
+	}
+
+	f.mu.Lock()
+	f.sessions[id] = &FakeSession{ID: id, Params: p, Status: FakeSessionOpen}
+	f.mu.Unlock()
+
+	return Session{ID: id, URL: f.baseURL + "/fake-checkout/" + id}, nil
+}
+
+// Session returns a copy of a session for the hosted checkout page.
+func (f *Fake) Session(id string) (FakeSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, found := f.sessions[id]
+	if !found {
+		return FakeSession{}, fmt.Errorf("fake payments: session %s: %w", id, ErrNotFound)
+	}
+	return *s, nil
+}
+
+// Pay completes a session as if the customer paid, then delivers
+// checkout.session.completed and payment_intent.succeeded webhooks before
+// returning, so the order is updated by the time the customer lands on the
+// success page.
+func (f *Fake) Pay(ctx context.Context, id string, customer Customer) (FakeSession, error) {
+	paymentID, err := randomID("fake_pi_")
+	if err != nil {
+		return FakeSession{}, fmt.Errorf("fake payments: pay session %s: %w", id, err)
+	}
+
+	f.mu.Lock()
+	s, found := f.sessions[id]
+	if !found {
+		f.mu.Unlock()
+		return FakeSession{}, fmt.Errorf("fake payments: pay session %s: %w", id, ErrNotFound)
+	}
+	if s.Status != FakeSessionOpen || s.Expired(time.Now()) {
+		f.mu.Unlock()
+		return FakeSession{}, fmt.Errorf("fake payments: pay session %s: %w", id, ErrSessionClosed)
+	}
+	s.Status = FakeSessionPaid
+	s.PaymentID = paymentID
+	s.Customer = &customer
+	paid := *s
+	f.mu.Unlock()
+
+	events := []fakeEvent{
+		{Type: EventCheckoutCompleted, Data: fakeEventData{OrderID: paid.Params.OrderID, SessionID: paid.ID, PaymentID: paid.PaymentID, Customer: paid.Customer}},
+		{Type: EventPaymentSucceeded, Data: fakeEventData{OrderID: paid.Params.OrderID, SessionID: paid.ID, PaymentID: paid.PaymentID}},
+	}
+	for _, event := range events {
+		if err := f.send(ctx, event); err != nil {
+			return paid, fmt.Errorf("fake payments: pay session %s: %w", id, err)
+		}
+	}
+	return paid, nil
+}
+
+// Cancel closes an open session as if the customer backed out of checkout.
+func (f *Fake) Cancel(id string) (FakeSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, found := f.sessions[id]
+	if !found {
+		return FakeSession{}, fmt.Errorf("fake payments: cancel session %s: %w", id, ErrNotFound)
+	}
+	if s.Status != FakeSessionOpen {
+		return FakeSession{}, fmt.Errorf("fake payments: cancel session %s: %w", id, ErrSessionClosed)
+	}
+	s.Status = FakeSessionCanceled
+	return *s, nil
+}
+
+// Decline fails a payment attempt the way a declined card would and delivers
+// payment_intent.payment_failed. The session stays open so the customer can
+// try again.
+func (f *Fake) Decline(ctx context.Context, id, reason string) (FakeSession, error) {
+	f.mu.Lock()
+	s, found := f.sessions[id]
+	if !found {
+		f.mu.Unlock()
+		return FakeSession{}, fmt.Errorf("fake payments: decline session %s: %w", id, ErrNotFound)
+	}
+	if s.Status != FakeSessionOpen || s.Expired(time.Now()) {
+		f.mu.Unlock()
+		return FakeSession{}, fmt.Errorf("fake payments: decline session %s: %w", id, ErrSessionClosed)
+	}
+	declined := *s
+	f.mu.Unlock()
+
+	event := fakeEvent{Type: EventPaymentFailed, Data: fakeEventData{OrderID: declined.Params.OrderID, SessionID: declined.ID, Reason: reason}}
+	if err := f.send(ctx, event); err != nil {
+		return declined, fmt.Errorf("fake payments: decline session %s: %w", id, err)
+	}
+	return declined, nil
+}
+
+// Expire closes an open session as if it ran out of time and delivers
+// checkout.session.expired. Sessions past their expiry are not expired on
+// their own; call this to simulate it.
+func (f *Fake) Expire(ctx context.Context, id string) (FakeSession, error) {
+	f.mu.Lock()
+	s, found := f.sessions[id]
+	if !found {
+		f.mu.Unlock()
+		return FakeSession{}, fmt.Errorf("fake payments: expire session %s: %w", id, ErrNotFound)
+	}
+	if s.Status != FakeSessionOpen {
+		f.mu.Unlock()
+		return FakeSession{}, fmt.Errorf("fake payments: expire session %s: %w", id, ErrSessionClosed)
+	}
+	s.Status = FakeSessionExpired
+	expired := *s
+	f.mu.Unlock()
+
+	event := fakeEvent{Type: EventCheckoutExpired, Data: fakeEventData{OrderID: expired.Params.OrderID, SessionID: expired.ID}}
+	if err := f.send(ctx, event); err != nil {
+		return expired, fmt.Errorf("fake payments: expire session %s: %w", id, err)
+	}
+	return expired, nil
+}
+
+// Dispute opens a dispute against a paid session and delivers
+// charge.dispute.created, as if the customer went to their bank.
+func (f *Fake) Dispute(ctx context.Context, paymentID, reason string) error {
+	s, err := f.sessionByPayment(paymentID)
+	if err != nil {
+		return fmt.Errorf("fake payments: dispute (payment_id=%s): %w", paymentID, err)
+	}
+
+	event := fakeEvent{Type: EventDisputeCreated, Data: fakeEventData{
+		OrderID:   s.Params.OrderID,
+		PaymentID: s.PaymentID,
+		Currency:  s.Params.Currency,
+		Amount:    s.Params.Total(),
+		Reason:    reason,
+	}}
+	if err := f.send(ctx, event); err != nil {
+		return fmt.Errorf("fake payments: dispute (payment_id=%s): %w", paymentID, err)
+	}
+	return nil
+}
+
+func (f *Fake) sessionByPayment(paymentID string) (FakeSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.sessions {
+		if s.PaymentID == paymentID && paymentID != "" {
+			return *s, nil
+		}
+	}
+	return FakeSession{}, ErrNotFound
+}
+
+func (f *Fake) VerifyWebhook(payload []byte, header http.Header) (Event, error) {
+	timestamp, signature, err := parseFakeSignature(header.Get(FakeSignatureHeader))
+	if err != nil {
+		return Event{}, fmt.Errorf("fake payments: %w: %v", ErrInvalidSignature, err)
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > fakeSignatureTolerance || age < -fakeSignatureTolerance {
+		return Event{}, fmt.Errorf("fake payments: %w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal(signature, f.sign(timestamp, payload)) {
+		return Event{}, fmt.Errorf("fake payments: %w: signature mismatch", ErrInvalidSignature)
+	}
+	return f.ParseEvent(payload)
+}
+
+func (f *Fake) ParseEvent(payload []byte) (Event, error) {
+	var e fakeEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return Event{}, fmt.Errorf("fake payments: unmarshal event: %w", err)
+	}
+	return Event{
+		ID:        e.ID,
+		Type:      e.Type,
+		OrderID:   e.Data.OrderID,
+		SessionID: e.Data.SessionID,
+		PaymentID: e.Data.PaymentID,
+		Customer:  e.Data.Customer,
+
+		Currency:       e.Data.Currency,
+		Amount:         e.Data.Amount,
+		AmountRefunded: e.Data.AmountRefunded,
+		Reason:         e.Data.Reason,
+	}, nil
+}
+
+func (f *Fake) FetchPayment(ctx context.Context, sessionID string) (Payment, error) {
+	s, err := f.Session(sessionID)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	payment := Payment{
+		SessionID:      s.ID,
+		PaymentID:      s.PaymentID,
+		OrderID:        s.Params.OrderID,
+		Status:         PaymentPending,
+		Currency:       s.Params.Currency,
+		Amount:         s.Params.Total(),
+		AmountRefunded: s.AmountRefunded,
+		Customer:       s.Customer,
+	}
+	switch {
+	case s.Status == FakeSessionPaid:
+		payment.Status = PaymentSucceeded
+		payment.PaymentMethod = "card (fake)"
+	case s.Status == FakeSessionCanceled, s.Status == FakeSessionExpired, s.Expired(time.Now()):
+		payment.Status = PaymentCanceled
+	}
+	return payment, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, paymentID string, amount int64) (Refund, error) {
+	refundID, err := randomID("fake_re_")
+	if err != nil {
+		return Refund{}, fmt.Errorf("fake payments: refund (payment_id=%s): %w", paymentID, err)
+	}
+
+	f.mu.Lock()
+	var refunded *FakeSession
+	for _, s := range f.sessions {
+		if s.PaymentID == paymentID && paymentID != "" {
+			refunded = s
+			break
+		}
+	}
+	if refunded == nil {
+		f.mu.Unlock()
+		return Refund{}, fmt.Errorf("fake payments: refund (payment_id=%s): %w", paymentID, ErrNotFound)
+	}
+	remaining := refunded.Params.Total() - refunded.AmountRefunded
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		f.mu.Unlock()
+		return Refund{}, fmt.Errorf("fake payments: refund (payment_id=%s): amount %d exceeds refundable %d", paymentID, amount, remaining)
+	}
+	refunded.AmountRefunded += amount
+	s := *refunded
+	f.mu.Unlock()
+
+	r := Refund{ID: refundID, Amount: amount, Status: "succeeded"}
+	event := fakeEvent{Type: EventChargeRefunded, Data: fakeEventData{
+		OrderID:        s.Params.OrderID,
+		PaymentID:      s.PaymentID,
+		Currency:       s.Params.Currency,
+		Amount:         s.Params.Total(),
+		AmountRefunded: s.AmountRefunded,
+	}}
+	if err := f.send(ctx, event); err != nil {
+		return r, fmt.Errorf("fake payments: refund (payment_id=%s): %w", paymentID, err)
+	}
+	return r, nil
+}
+
+type fakeEventData struct {
+	OrderID   int       `json:"order_id"`
+	SessionID string    `json:"session_id"`
+	PaymentID string    `json:"payment_id"`
+	Customer  *Customer `json:"customer,omitempty"`
+
+	Currency       string `json:"currency,omitempty"`
+	Amount         int64  `json:"amount,omitempty"`
+	AmountRefunded int64  `json:"amount_refunded,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+type fakeEvent struct {
+	ID   string        `json:"id"`
+	Type EventType     `json:"type"`
+	Data fakeEventData `json:"data"`
+}
+
+func (f *Fake) send(ctx context.Context, event fakeEvent) error {
+	id, err := randomID("fake_evt_")
+	if err != nil {
+		return fmt.Errorf("send webhook %s: %w", event.Type, err)
+	}
+	event.ID = id
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("send webhook %s: marshal: %w", event.Type, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.baseURL+"/webhook", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("send webhook %s: build request: %w", event.Type, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(FakeSignatureHeader, f.SignatureHeader(time.Now(), payload))
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send webhook %s: %w", event.Type, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("send webhook %s: unexpected status %d", event.Type, res.StatusCode)
+	}
+	return nil
+}
+
+// SignatureHeader signs payload as of t. Tests use it to forge valid webhooks.
+func (f *Fake) SignatureHeader(t time.Time, payload []byte) string {
+	timestamp := t.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(f.sign(timestamp, payload)))
+}
+
+func (f *Fake) sign(timestamp int64, payload []byte) []byte {
+	mac := hmac.New(sha256.New, f.secret)
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func parseFakeSignature(header string) (int64, []byte, error) {
+	var timestamp int64
+	var signature []byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, nil, fmt.Errorf("parse timestamp %q: %w", value, err)
+			}
+			timestamp = t
+		case "v1":
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return 0, nil, fmt.Errorf("decode signature: %w", err)
+			}
+			signature = sig
+		}
+	}
+	if timestamp == 0 || signature == nil {
+		return 0, nil, errors.New("missing timestamp or signature")
+	}
+	return timestamp, signature, nil
+}
+
+func randomID(prefix string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
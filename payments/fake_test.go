@@ -0,0 +1,142 @@
+package payments
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeVerifyWebhook(t *testing.T) {
+	f, err := NewFake("http://localhost", "secret")
+	require.NoError(t, err)
+
+	payload := []byte(`{"id":"evt_1","type":"payment_intent.succeeded","data":{"order_id":7,"payment_id":"pi_1"}}`)
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, f.SignatureHeader(time.Now(), payload))
+
+	event, err := f.VerifyWebhook(payload, header)
+	require.NoError(t, err)
+	require.Equal(t, EventPaymentSucceeded, event.Type)
+	require.Equal(t, 7, event.OrderID)
+	require.Equal(t, "pi_1", event.PaymentID)
+
+	_, err = f.VerifyWebhook(append(payload, ' '), header)
+	require.ErrorIs(t, err, ErrInvalidSignature, "tampered payload")
+
+	header.Set(FakeSignatureHeader, f.SignatureHeader(time.Now().Add(-time.Hour), payload))
+	_, err = f.VerifyWebhook(payload, header)
+	require.ErrorIs(t, err, ErrInvalidSignature, "stale timestamp")
+
+	other, err := NewFake("http://localhost", "other")
+	require.NoError(t, err)
+	header.Set(FakeSignatureHeader, other.SignatureHeader(time.Now(), payload))
+	_, err = f.VerifyWebhook(payload, header)
+	require.ErrorIs(t, err, ErrInvalidSignature, "wrong secret")
+}
+
+func TestFakePayDeliversWebhooks(t *testing.T) {
+	var f *Fake
+	var events []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/webhook", r.URL.Path)
+		payload, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		event, err := f.VerifyWebhook(payload, r.Header)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		events = append(events, event)
+	}))
+	defer srv.Close()
+
+	f, err := NewFake(srv.URL, "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	s, err := f.CreateSession(ctx, SessionParams{
+		OrderID:   3,
+		Currency:  "EUR",
+		LineItems: []LineItem{{Name: "gate", UnitAmount: 4999, Quantity: 2}},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, srv.URL+"/fake-checkout/"+s.ID, s.URL)
+
+	paid, err := f.Pay(ctx, s.ID, Customer{Name: "Jane", Email: "jane@example.com"})
+	require.NoError(t, err)
+
+	require.Len(t, events, 2)
+	require.Equal(t, EventCheckoutCompleted, events[0].Type)
+	require.Equal(t, "jane@example.com", events[0].Customer.Email)
+	require.Equal(t, EventPaymentSucceeded, events[1].Type)
+	require.Equal(t, 3, events[1].OrderID)
+	require.Equal(t, paid.PaymentID, events[1].PaymentID)
+
+	_, err = f.Pay(ctx, s.ID, Customer{})
+	require.ErrorIs(t, err, ErrSessionClosed, "a session is paid once")
+
+	payment, err := f.FetchPayment(ctx, s.ID)
+	require.NoError(t, err)
+	require.Equal(t, PaymentSucceeded, payment.Status)
+	require.Equal(t, int64(9998), payment.Amount)
+
+	refund, err := f.Refund(ctx, paid.PaymentID, 1000)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), refund.Amount)
+
+	refund, err = f.Refund(ctx, paid.PaymentID, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(8998), refund.Amount, "0 refunds the remainder")
+
+	_, err = f.Refund(ctx, paid.PaymentID, 1)
+	require.Error(t, err)
+
+	require.Len(t, events, 4)
+	require.Equal(t, EventChargeRefunded, events[2].Type)
+	require.Equal(t, int64(1000), events[2].AmountRefunded)
+	require.Equal(t, int64(9998), events[3].AmountRefunded, "amount refunded is a running total")
+	require.Equal(t, int64(9998), events[3].Amount)
+}
+
+func TestFakeExpireDeliversWebhook(t *testing.T) {
+	var f *Fake
+	var events []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		event, err := f.VerifyWebhook(payload, r.Header)
+		require.NoError(t, err)
+		events = append(events, event)
+	}))
+	defer srv.Close()
+
+	f, err := NewFake(srv.URL, "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	s, err := f.CreateSession(ctx, SessionParams{OrderID: 4, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	_, err = f.Decline(ctx, s.ID, "card declined")
+	require.NoError(t, err)
+	_, err = f.Expire(ctx, s.ID)
+	require.NoError(t, err)
+	_, err = f.Pay(ctx, s.ID, Customer{})
+	require.ErrorIs(t, err, ErrSessionClosed)
+
+	require.Len(t, events, 2)
+	require.Equal(t, EventPaymentFailed, events[0].Type)
+	require.Equal(t, "card declined", events[0].Reason)
+	require.Equal(t, EventCheckoutExpired, events[1].Type)
+	require.Equal(t, 4, events[1].OrderID)
+
+	payment, err := f.FetchPayment(ctx, s.ID)
+	require.NoError(t, err)
+	require.Equal(t, PaymentCanceled, payment.Status)
+}
@@ -0,0 +1,173 @@
+// Package payments hides the payment processor behind a small interface so
+// checkout and webhook handling do not depend on Stripe directly. Stripe is
+// the production implementation; Fake runs a whole purchase offline.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrInvalidSignature is returned by VerifyWebhook when the payload was not
+// signed by the provider.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ErrNotFound is returned when a session or payment does not exist.
+var ErrNotFound = errors.New("payment not found")
+
+type Provider interface {
+	// Name identifies the provider in logs and stored references.
+	Name() string
+	// CreateSession starts a hosted checkout for an order. Customers are sent
+	// to Session.URL to pay.
+	CreateSession(ctx context.Context, params SessionParams) (Session, error)
+	// VerifyWebhook checks the signature on a webhook request and decodes it.
+	// Event types the application does not handle are returned with only ID
+	// and Type set.
+	VerifyWebhook(payload []byte, header http.Header) (Event, error)
+	// ParseEvent decodes a webhook payload without checking its signature.
+	// It is only for payloads that were verified when they were received,
+	// e.g. replaying a stored event.
+	ParseEvent(payload []byte) (Event, error)
+	// FetchPayment looks up the payment made through a checkout session.
+	FetchPayment(ctx context.Context, sessionID string) (Payment, error)
+	// Refund refunds amount minor units of a payment. An amount of 0 refunds
+	// whatever has not been refunded yet.
+	Refund(ctx context.Context, paymentID string, amount int64) (Refund, error)
+}
+
+type LineItem struct {
+	Name string
+	// UnitAmount is the price of one unit in minor units, e.g. cents.
+	UnitAmount int64
+	Quantity   int64
+	Images     []string
+}
+
+// ShippingOption is a delivery method offered at checkout.
+type ShippingOption struct {
+	Name string
+	// Amount is what it costs in minor units, 0 when it is free.
+	Amount int64
+}
+
+type SessionParams struct {
+	OrderID    int
+	Currency   string
+	LineItems  []LineItem
+	SuccessURL string
+	CancelURL  string
+	ExpiresAt  time.Time
+	// AllowedCountries are where the order can be delivered. None means
+	// no shipping address is collected, e.g. for click and collect.
+	AllowedCountries []string
+	// Shipping is the customer's chosen delivery method, offered as the only
+	// shipping option so they pay what the order recorded. Empty Name for
+	// none.
+	Shipping ShippingOption
+	// Discount is taken off the line items, in minor units, and shown to
+	// the customer as DiscountName.
+	Discount     int64
+	DiscountName string
+	// CustomerEmail fills in, and fixes, the customer's email when it is
+	// already known, e.g. given with a promotion code limited per customer.
+	CustomerEmail string
+}
+
+// Subtotal is the sum of every line item in minor units.
+func (p SessionParams) Subtotal() int64 {
+	var total int64
+	for _, item := range p.LineItems {
+		total += item.UnitAmount * item.Quantity
+	}
+	return total
+}
+
+// Total is what the customer pays in minor units, the subtotal less the
+// discount, plus shipping.
+func (p SessionParams) Total() int64 {
+	return max(p.Subtotal()-p.Discount, 0) + p.Shipping.Amount
+}
+
+type Session struct {
+	ID  string
+	URL string
+}
+
+type EventType string
+
+const (
+	EventCheckoutCompleted EventType = "checkout.session.completed"
+	EventCheckoutExpired   EventType = "checkout.session.expired"
+	EventPaymentSucceeded  EventType = "payment_intent.succeeded"
+	EventPaymentFailed     EventType = "payment_intent.payment_failed"
+	EventChargeRefunded    EventType = "charge.refunded"
+	EventDisputeCreated    EventType = "charge.dispute.created"
+)
+
+// Address is encoded with the same field names Stripe uses, which is how
+// addresses are already stored on orders.
+type Address struct {
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+}
+
+type Customer struct {
+	Name    string   `json:"name"`
+	Email   string   `json:"email"`
+	Phone   string   `json:"phone"`
+	Address *Address `json:"address"`
+}
+
+// Event is a verified webhook. OrderID is 0 when the provider did not send
+// one, which is usual for charge events; look the order up by PaymentID.
+type Event struct {
+	ID        string
+	Type      EventType
+	OrderID   int
+	SessionID string
+	PaymentID string
+	Customer  *Customer
+	// Currency, Amount and AmountRefunded are set for charge events, in
+	// minor units. AmountRefunded is the running total, not this refund.
+	Currency       string
+	Amount         int64
+	AmountRefunded int64
+	// Reason is why a payment failed or was disputed, as the provider put it.
+	Reason string
+}
+
+type PaymentStatus string
+
+const (
+	PaymentPending   PaymentStatus = "pending"
+	PaymentSucceeded PaymentStatus = "succeeded"
+	PaymentCanceled  PaymentStatus = "canceled"
+)
+
+type Payment struct {
+	SessionID string
+	// PaymentID is empty until the customer has submitted a payment.
+	PaymentID      string
+	OrderID        int
+	Status         PaymentStatus
+	Currency       string
+	Amount         int64
+	AmountRefunded int64
+	// PaymentMethod describes how the customer paid, e.g. "card (visa 4242)".
+	// It is empty until a payment has been made.
+	PaymentMethod string
+	Customer      *Customer
+}
+
+type Refund struct {
+	ID     string
+	Amount int64
+	Status string
+}
@@ -0,0 +1,310 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/checkout/session"
+	"github.com/stripe/stripe-go/v82/coupon"
+	"github.com/stripe/stripe-go/v82/refund"
+	"github.com/stripe/stripe-go/v82/webhook"
+)
+
+// Stripe takes payments through Stripe Checkout.
+type Stripe struct {
+	webhookSecret string
+}
+
+// NewStripe configures the stripe-go client with apiKey. webhookSecret is the
+// signing secret of the /webhook endpoint.
+func NewStripe(apiKey, webhookSecret string) *Stripe {
+	stripe.Key = apiKey
+	return &Stripe{webhookSecret: webhookSecret}
+}
+
+func (s *Stripe) Name() string {
+	return "stripe"
+}
+
+func (s *Stripe) CreateSession(ctx context.Context, p SessionParams) (Session, error) {
+	lineItems := make([]*stripe.CheckoutSessionLineItemParams, 0, len(p.LineItems))
+	for _, item := range p.LineItems {
+		images := make([]*string, 0, len(item.Images))
+		for _, img := range item.Images {
+			images = append(images, stripe.String(img))
+		}
+		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
+			Quantity: stripe.Int64(item.Quantity),
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				UnitAmount: stripe.Int64(item.UnitAmount),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+					Name:   stripe.String(item.Name),
+					Images: images,
+				},
+				Currency: stripe.String(p.Currency),
+			},
+		})
+	}
+
+	allowedCountries := make([]*string, 0, len(p.AllowedCountries))
+	for _, country := range p.AllowedCountries {
+		allowedCountries = append(allowedCountries, stripe.String(country))
+	}
+
+	orderID := strconv.Itoa(p.OrderID)
+	params := &stripe.CheckoutSessionParams{
+		ClientReferenceID: stripe.String(orderID),
+		LineItems:         lineItems,
+		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
+		SuccessURL:        stripe.String(p.SuccessURL),
+		CancelURL:         stripe.String(p.CancelURL),
+		PhoneNumberCollection: &stripe.CheckoutSessionPhoneNumberCollectionParams{
+			Enabled: stripe.Bool(true),
+		},
+		Currency:  stripe.String(p.Currency),
+		ExpiresAt: stripe.Int64(p.ExpiresAt.Unix()),
+		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{
+			Description: stripe.String(fmt.Sprintf("Order: #%d", p.OrderID)),
+			Metadata: map[string]string{
+				"order_id": orderID,
+			},
+		},
+		Metadata: map[string]string{
+			"order_id": orderID,
+		},
+	}
+	if len(allowedCountries) > 0 {
+		params.ShippingAddressCollection = &stripe.CheckoutSessionShippingAddressCollectionParams{
+			AllowedCountries: allowedCountries,
+		}
+	}
+	if p.Shipping.Name != "" {
+		params.ShippingOptions = []*stripe.CheckoutSessionShippingOptionParams{{
+			ShippingRateData: &stripe.CheckoutSessionShippingOptionShippingRateDataParams{
+				DisplayName: stripe.String(p.Shipping.Name),
+				Type:        stripe.String(string(stripe.ShippingRateTypeFixedAmount)),
+				FixedAmount: &stripe.CheckoutSessionShippingOptionShippingRateDataFixedAmountParams{
+					Amount:   stripe.Int64(p.Shipping.Amount),
+					Currency: stripe.String(p.Currency),
+				},
+			},
+		}}
+	}
+	if p.CustomerEmail != "" {
+		params.CustomerEmail = stripe.String(p.CustomerEmail)
+	}
+	if p.Discount > 0 {
+		// Checkout only takes discounts as coupons, so make one for this
+		// session that cannot be used again
+		cp, err := coupon.New(&stripe.CouponParams{
+			Params:         stripe.Params{Context: ctx},
+			AmountOff:      stripe.Int64(p.Discount),
+			Currency:       stripe.String(p.Currency),
+			Duration:       stripe.String(string(stripe.CouponDurationOnce)),
+			MaxRedemptions: stripe.Int64(1),
+			Name:           stripe.String(p.DiscountName),
+		})
+		if err != nil {
+			return Session{}, fmt.Errorf("stripe: create discount coupon (order_id=%d): %w", p.OrderID, err)
+		}
+		params.Discounts = []*stripe.CheckoutSessionDiscountParams{{Coupon: stripe.String(cp.ID)}}
+	}
+	params.Context = ctx
+
+	cs, err := session.New(params)
+	if err != nil {
+		return Session{}, fmt.Errorf("stripe: create checkout session (order_id=%d): %w", p.OrderID, err)
+	}
+	return Session{ID: cs.ID, URL: cs.URL}, nil
+}
+
+func (s *Stripe) VerifyWebhook(payload []byte, header http.Header) (Event, error) {
+	stripeEvent, err := webhook.ConstructEvent(payload, header.Get("Stripe-Signature"), s.webhookSecret)
+	if err != nil {
+		return Event{}, fmt.Errorf("stripe: %w: %v", ErrInvalidSignature, err)
+	}
+	return decodeStripeEvent(stripeEvent)
+}
+
+func (s *Stripe) ParseEvent(payload []byte) (Event, error) {
+	var stripeEvent stripe.Event
+	if err := json.Unmarshal(payload, &stripeEvent); err != nil {
+		return Event{}, fmt.Errorf("stripe: unmarshal event: %w", err)
+	}
+	return decodeStripeEvent(stripeEvent)
+}
+
+func decodeStripeEvent(stripeEvent stripe.Event) (Event, error) {
+	var err error
+	event := Event{ID: stripeEvent.ID, Type: EventType(stripeEvent.Type)}
+	if stripeEvent.Data == nil {
+		return event, nil
+	}
+
+	switch event.Type {
+	case EventPaymentSucceeded, EventPaymentFailed:
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &pi); err != nil {
+			return Event{}, fmt.Errorf("stripe: unmarshal payment intent (event_id=%s): %w", stripeEvent.ID, err)
+		}
+		event.PaymentID = pi.ID
+		if pi.LastPaymentError != nil {
+			event.Reason = pi.LastPaymentError.Msg
+		}
+		event.OrderID, err = orderIDFromMetadata(pi.Metadata)
+		if err != nil {
+			return Event{}, fmt.Errorf("stripe: payment intent %s: %w", pi.ID, err)
+		}
+
+	case EventChargeRefunded:
+		var ch stripe.Charge
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &ch); err != nil {
+			return Event{}, fmt.Errorf("stripe: unmarshal charge (event_id=%s): %w", stripeEvent.ID, err)
+		}
+		if ch.PaymentIntent != nil {
+			event.PaymentID = ch.PaymentIntent.ID
+		}
+		event.Currency = string(ch.Currency)
+		event.Amount = ch.Amount
+		event.AmountRefunded = ch.AmountRefunded
+		event.OrderID, err = orderIDFromMetadata(ch.Metadata)
+		if err != nil {
+			return Event{}, fmt.Errorf("stripe: charge %s: %w", ch.ID, err)
+		}
+
+	case EventDisputeCreated:
+		var d stripe.Dispute
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &d); err != nil {
+			return Event{}, fmt.Errorf("stripe: unmarshal dispute (event_id=%s): %w", stripeEvent.ID, err)
+		}
+		if d.PaymentIntent != nil {
+			event.PaymentID = d.PaymentIntent.ID
+		}
+		event.Currency = string(d.Currency)
+		event.Amount = d.Amount
+		event.Reason = string(d.Reason)
+
+	case EventCheckoutCompleted, EventCheckoutExpired:
+		var cs stripe.CheckoutSession
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &cs); err != nil {
+			return Event{}, fmt.Errorf("stripe: unmarshal checkout session (event_id=%s): %w", stripeEvent.ID, err)
+		}
+		event.SessionID = cs.ID
+		if cs.PaymentIntent != nil {
+			event.PaymentID = cs.PaymentIntent.ID
+		}
+		event.Customer = customerFromStripe(cs.CustomerDetails)
+		event.OrderID, err = orderIDFromMetadata(cs.Metadata)
+		if err != nil {
+			return Event{}, fmt.Errorf("stripe: checkout session %s: %w", cs.ID, err)
+		}
+	}
+
+	return event, nil
+}
+
+func (s *Stripe) FetchPayment(ctx context.Context, sessionID string) (Payment, error) {
+	params := &stripe.CheckoutSessionParams{}
+	params.Context = ctx
+	params.AddExpand("payment_intent.latest_charge")
+
+	cs, err := session.Get(sessionID, params)
+	if err != nil {
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.HTTPStatusCode == http.StatusNotFound {
+			return Payment{}, fmt.Errorf("stripe: get checkout session (session_id=%s): %w", sessionID, ErrNotFound)
+		}
+		return Payment{}, fmt.Errorf("stripe: get checkout session (session_id=%s): %w", sessionID, err)
+	}
+
+	payment := Payment{
+		SessionID: cs.ID,
+		Currency:  string(cs.Currency),
+		Amount:    cs.AmountTotal,
+		Customer:  customerFromStripe(cs.CustomerDetails),
+		Status:    PaymentPending,
+	}
+	payment.OrderID, _ = orderIDFromMetadata(cs.Metadata)
+
+	switch {
+	case cs.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid:
+		payment.Status = PaymentSucceeded
+	case cs.Status == stripe.CheckoutSessionStatusExpired:
+		payment.Status = PaymentCanceled
+	}
+
+	if pi := cs.PaymentIntent; pi != nil {
+		payment.PaymentID = pi.ID
+		if pi.Status == stripe.PaymentIntentStatusCanceled {
+			payment.Status = PaymentCanceled
+		}
+		if charge := pi.LatestCharge; charge != nil {
+			payment.AmountRefunded = charge.AmountRefunded
+			payment.PaymentMethod = paymentMethodFromStripe(charge.PaymentMethodDetails)
+		}
+	}
+	return payment, nil
+}
+
+func (s *Stripe) Refund(ctx context.Context, paymentID string, amount int64) (Refund, error) {
+	params := &stripe.RefundParams{PaymentIntent: stripe.String(paymentID)}
+	params.Context = ctx
+	if amount > 0 {
+		params.Amount = stripe.Int64(amount)
+	}
+
+	r, err := refund.New(params)
+	if err != nil {
+		return Refund{}, fmt.Errorf("stripe: create refund (payment_id=%s, amount=%d): %w", paymentID, amount, err)
+	}
+	return Refund{ID: r.ID, Amount: r.Amount, Status: string(r.Status)}, nil
+}
+
+func orderIDFromMetadata(metadata map[string]string) (int, error) {
+	raw, found := metadata["order_id"]
+	if !found {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("parse order_id %q: %w", raw, err)
+	}
+	return id, nil
+}
+
+func paymentMethodFromStripe(details *stripe.ChargePaymentMethodDetails) string {
+	if details == nil {
+		return ""
+	}
+	if card := details.Card; card != nil {
+		return fmt.Sprintf("card (%s %s)", card.Brand, card.Last4)
+	}
+	return string(details.Type)
+}
+
+func customerFromStripe(details *stripe.CheckoutSessionCustomerDetails) *Customer {
+	if details == nil {
+		return nil
+	}
+	customer := &Customer{
+		Name:  details.Name,
+		Email: details.Email,
+		Phone: details.Phone,
+	}
+	if a := details.Address; a != nil {
+		customer.Address = &Address{
+			Line1:      a.Line1,
+			Line2:      a.Line2,
+			City:       a.City,
+			State:      a.State,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+		}
+	}
+	return customer
+}
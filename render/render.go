@@ -2,7 +2,6 @@ package render
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"html/template"
 	"maps"
@@ -28,42 +27,15 @@ func (r *Render) tmpl() *template.Template {
 		return r.template
 	}
 	r.template = template.Must(template.New("").Funcs(template.FuncMap{
-		"add":      func(a, b int) int { return a + b },
-		"toString": structToString,
-		"sizeRange": func(width, tolerance float32) float32 {
-			return width - tolerance
-		},
+		"add": func(a, b int) int { return a + b },
 		"title": func(str string) string {
 			return cases.Title(language.AmericanEnglish).String(str)
 		},
-		// expect two different number types int and float32
-		"mul": func(a any, b any) float32 {
-			var f1, f2 float32
-
-			switch v := a.(type) {
-			case int:
-				f1 = float32(v)
-			case float32:
-				f1 = v
-			case float64:
-				f1 = float32(v)
-			default:
-				return 0.0
-			}
-
-			switch v := b.(type) {
-			case int:
-				f2 = float32(v)
-			case float32:
-				f2 = v
-			case float64:
-				f2 = float32(v)
-			default:
-				return 0.0
-			}
-
-			return f1 * f2
+		// line totals, e.g. {{ mul .SalePrice .Qty }}
+		"mul": func(price models.Money, qty int) models.Money {
+			return price.Mul(qty)
 		},
+		"cartItemKey": models.CartItemKey,
 	}).ParseGlob("templates/**/*.tmpl"))
 	return r.template
 }
@@ -102,12 +74,3 @@ func (r *Render) Page(w http.ResponseWriter, templateName string, templateData m
 	}
 	return nil
 }
-
-func structToString(v any) string {
-	b, err := json.Marshal(v)
-	if err != nil {
-		return "error: marshalling struct to string"
-	}
-
-	return string(b)
-}
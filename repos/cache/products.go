@@ -42,10 +42,10 @@ func (r *CachedProductRepo) InsertProduct(product models.Product) (int, error) {
 }
 
 // GetProductPrice checks cache first, otherwise fetches from underlying repo and caches the result.
-func (r *CachedProductRepo) GetProductPrice(id int) (float32, error) {
+func (r *CachedProductRepo) GetProductPrice(id int) (models.Money, error) {
 	cacheKey := fmt.Sprintf("product_price_%d", id)
 	if cachedPrice, found := r.cache.Get(cacheKey); found {
-		if price, ok := cachedPrice.(float32); ok {
+		if price, ok := cachedPrice.(models.Money); ok {
 			return price, nil
 		}
 		// If type assertion fails, treat as cache miss (or log error)
@@ -54,7 +54,7 @@ func (r *CachedProductRepo) GetProductPrice(id int) (float32, error) {
 	price, err := r.productRepo.GetProductPrice(id)
 	if err != nil {
 		// Don't cache errors like "not found"
-		return 0, err
+		return models.Money{}, err
 	}
 
 	r.cache.Set(cacheKey, price, cache.DefaultExpiration)
@@ -86,13 +86,14 @@ func (r *CachedProductRepo) GetProductByName(name string) (models.Product, error
 // Helper function to generate cache key for product list filters
 func generateProductListCacheKey(prefix string, params repos.ProductFilterParams) string {
 	// Ensure consistent key format, handling zero values appropriately
-	return fmt.Sprintf("%s_%s_maxwidth_%.2f_color_%s_invlvl_%d_price_%.2f_limit_%d",
+	return fmt.Sprintf("%s_%s_mounting_%s_maxwidth_%.2f_color_%s_invlvl_%d_price_%d_limit_%d",
 		prefix,
 		params.Type,
+		params.MountingType,
 		params.MaxWidth,
 		params.Color, // Empty string is handled fine
 		params.InventoryLevel,
-		params.Price,
+		params.Price.Amount,
 		params.Limit, // Limit included for GetProducts, ignored logically by CountProducts but part of params
 	)
 }
@@ -176,6 +177,12 @@ func (r *CachedProductRepo) DeleteProductByID(productID int) error {
 	return r.productRepo.DeleteProductByID(productID)
 }
 
+// Flush drops every cached entry. Use it after writes that go around this
+// wrapper, e.g. stock movements, which change inventory levels directly.
+func (r *CachedProductRepo) Flush() {
+	r.cache.Flush()
+}
+
 // GetProductByID checks cache first, otherwise fetches from underlying repo and caches the result.
 func (r *CachedProductRepo) GetProductByID(productID int) (models.Product, error) {
 	cacheKey := fmt.Sprintf("product_by_id_%d", productID)
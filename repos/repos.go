@@ -1,17 +1,32 @@
 package repos
 
 import (
+	"errors"
+
 	"github.com/seanomeara96/gates/models"
 )
 
+// ErrInsufficientStock is returned when committing an order would take a
+// product that does not allow backorders below zero.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrPromotionUnavailable is returned when placing an order would take a
+// promotion past its usage limits.
+var ErrPromotionUnavailable = errors.New("promotion unavailable")
+
+// ErrBundleComponentMissing is returned for a saved bundle made of a product
+// that is no longer in the catalog.
+var ErrBundleComponentMissing = errors.New("bundle component missing")
+
 // ProductFilterParams defines the parameters for filtering product lists.
 type ProductFilterParams struct {
 	MaxWidth       float32
 	Limit          int
 	Color          string
-	InventoryLevel int     // Assumed filter: inventory_level >= ? (if > 0)
-	Price          float32 // Assumed filter: price <= ? (if > 0)
+	InventoryLevel int          // Assumed filter: inventory_level >= ? (if > 0)
+	Price          models.Money // Assumed filter: price <= ? (if > 0)
 	Type           models.ProductType
+	MountingType   models.MountingType
 }
 
 // CustomerDetails holds optional customer-provided data related to an order.
@@ -24,6 +39,16 @@ type CustomerDetails struct {
 	PaymentMethod   string
 }
 
+// BundleLookupRow is one precomputed bundle: the Rank-th the builder returned
+// for an opening of Width cm.
+type BundleLookupRow struct {
+	MountingType models.MountingType
+	Goal         models.BundleGoal
+	Width        int
+	Rank         int
+	Key          string // models.CartItemKey of the gate and extensions
+}
+
 type GetOrdersParams struct {
 	Limit, Offset int
 }
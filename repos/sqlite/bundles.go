@@ -0,0 +1,246 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/repos"
+)
+
+// SaveBundle stores bundle as a product of type bundle, with its components
+// in bundle_components, under models.BundleSlug. A bundle that was saved
+// before is not saved again; the one already stored is returned, unless one
+// of its products has left the catalog, when it is replaced.
+func (r *ProductRepo) SaveBundle(bundle models.Bundle) (models.Bundle, error) {
+	slug := models.BundleSlug(bundle.Components)
+
+	saved, err := r.GetBundleBySlug(slug)
+	if err == nil {
+		return saved, nil
+	}
+	stale := errors.Is(err, repos.ErrBundleComponentMissing)
+	if !stale && !errors.Is(err, sql.ErrNoRows) {
+		return models.Bundle{}, fmt.Errorf("save bundle: look up existing (slug=%s): %w", slug, err)
+	}
+
+	bundle.ComputeMetaData()
+	product := bundle.ToProduct()
+	product.Slug = slug
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Bundle{}, fmt.Errorf("save bundle: begin transaction (slug=%s): %w", slug, err)
+	}
+
+	if stale {
+		if err := deleteSavedBundles(tx, `slug = ?`, slug); err != nil {
+			_ = tx.Rollback()
+			return models.Bundle{}, fmt.Errorf("save bundle: replace stale (slug=%s): %w", slug, err)
+		}
+	}
+
+	res, err := tx.Exec(
+		`INSERT INTO products (
+			type, name, width, price, currency, img, color, tolerance, mounting_type, max_span, slug
+		 ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		product.Type, product.Name, product.Width, product.Price.Amount, priceCurrency(product.Price),
+		product.Img, product.Color, product.Tolerance, mountingType(product), product.MaxSpan, product.Slug,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return models.Bundle{}, fmt.Errorf("save bundle: insert product (slug=%s): %w", slug, err)
+	}
+	bundleID, err := res.LastInsertId()
+	if err != nil {
+		_ = tx.Rollback()
+		return models.Bundle{}, fmt.Errorf("save bundle: last insert id (slug=%s): %w", slug, err)
+	}
+
+	for _, component := range bundle.Components {
+		_, err := tx.Exec(
+			`INSERT INTO bundle_components (product_id, product_type, bundle_id, qty) VALUES (?, ?, ?, ?)`,
+			component.Id, component.Type, bundleID, component.Qty,
+		)
+		if err != nil {
+			_ = tx.Rollback()
+			return models.Bundle{}, fmt.Errorf("save bundle: insert component (slug=%s, productId=%d): %w", slug, component.Id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Bundle{}, fmt.Errorf("save bundle: commit (slug=%s): %w", slug, err)
+	}
+
+	return r.GetBundleBySlug(slug)
+}
+
+// GetBundleBySlug returns the saved bundle with the components it was saved
+// with, as they are in the catalog now, so its price is their current total
+// rather than what it was when it was saved. Returns sql.ErrNoRows if there
+// is no such bundle, and repos.ErrBundleComponentMissing if one of its
+// products has since been deleted.
+func (r *ProductRepo) GetBundleBySlug(slug string) (models.Bundle, error) {
+	product, err := scanProductFromRow(
+		r.db.QueryRow("SELECT "+productColumns("")+" FROM products WHERE slug = ? AND type = ?", slug, models.ProductTypeBundle),
+	)
+	if err != nil {
+		return models.Bundle{}, err
+	}
+	return r.getBundleComponents(product)
+}
+
+// GetBundleByID is GetBundleBySlug by the saved bundle's product id.
+func (r *ProductRepo) GetBundleByID(id int) (models.Bundle, error) {
+	product, err := scanProductFromRow(
+		r.db.QueryRow("SELECT "+productColumns("")+" FROM products WHERE id = ? AND type = ? AND slug != ''", id, models.ProductTypeBundle),
+	)
+	if err != nil {
+		return models.Bundle{}, err
+	}
+	return r.getBundleComponents(product)
+}
+
+// getBundleComponents hydrates the components of the saved bundle product.
+func (r *ProductRepo) getBundleComponents(product models.Product) (models.Bundle, error) {
+	slug := product.Slug
+
+	// only product_id and qty are stored, the rest is hydrated from products
+	rows, err := r.db.Query(`SELECT product_id, qty FROM bundle_components WHERE bundle_id = ? ORDER BY id`, product.Id)
+	if err != nil {
+		return models.Bundle{}, fmt.Errorf("get bundle: query components (slug=%s): %w", slug, err)
+	}
+	defer rows.Close()
+
+	type componentRow struct{ productID, qty int }
+	var componentRows []componentRow
+	for rows.Next() {
+		var row componentRow
+		if err := rows.Scan(&row.productID, &row.qty); err != nil {
+			return models.Bundle{}, fmt.Errorf("get bundle: scan component (slug=%s): %w", slug, err)
+		}
+		componentRows = append(componentRows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Bundle{}, fmt.Errorf("get bundle: iterate components (slug=%s): %w", slug, err)
+	}
+
+	// a saved bundle's own price and metadata are recomputed from its
+	// components below
+	bundle := models.Bundle{Product: models.Product{Id: product.Id, Slug: product.Slug}}
+	for _, row := range componentRows {
+		component, err := r.GetProductByID(row.productID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Bundle{}, fmt.Errorf("get bundle (slug=%s, productId=%d): %w", slug, row.productID, repos.ErrBundleComponentMissing)
+		}
+		if err != nil {
+			return models.Bundle{}, fmt.Errorf("get bundle: get component (slug=%s, productId=%d): %w", slug, row.productID, err)
+		}
+		component.Qty = row.qty
+		bundle.Components = append(bundle.Components, component)
+	}
+	if len(bundle.Components) == 0 {
+		return models.Bundle{}, fmt.Errorf("get bundle (slug=%s): bundle has no components", slug)
+	}
+
+	bundle.ComputeMetaData()
+	return bundle, nil
+}
+
+// deleteSavedBundles deletes the saved bundles matching where, and their
+// components.
+func deleteSavedBundles(tx *sql.Tx, where string, args ...any) error {
+	rows, err := tx.Query(`SELECT id FROM products WHERE slug != '' AND `+where, args...)
+	if err != nil {
+		return fmt.Errorf("delete saved bundles: query: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("delete saved bundles: scan: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("delete saved bundles: iterate: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := tx.Exec(`DELETE FROM bundle_components WHERE bundle_id = ?`, id); err != nil {
+			return fmt.Errorf("delete saved bundle components (bundleId=%d): %w", id, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM products WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("delete saved bundle (bundleId=%d): %w", id, err)
+		}
+	}
+	return nil
+}
+
+// ReplaceBundleLookup swaps the whole bundle lookup for rows.
+func (r *ProductRepo) ReplaceBundleLookup(rows []repos.BundleLookupRow) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("replace bundle lookup: begin transaction: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM bundle_lookup`); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("replace bundle lookup: clear: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO bundle_lookup (mounting_type, goal, width, rank, item_key) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("replace bundle lookup: prepare insert: %w", err)
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.Exec(row.MountingType, row.Goal, row.Width, row.Rank, row.Key); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("replace bundle lookup: insert (mountingType=%s, goal=%s, width=%d, rank=%d): %w", row.MountingType, row.Goal, row.Width, row.Rank, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("replace bundle lookup: commit: %w", err)
+	}
+	return nil
+}
+
+// ClearBundleLookup empties the bundle lookup, e.g. when it no longer matches
+// the catalog.
+func (r *ProductRepo) ClearBundleLookup() error {
+	if _, err := r.db.Exec(`DELETE FROM bundle_lookup`); err != nil {
+		return fmt.Errorf("clear bundle lookup: %w", err)
+	}
+	return nil
+}
+
+// GetBundleLookup returns the keys of the bundles precomputed for an opening
+// of width cm, in the order the builder returned them.
+func (r *ProductRepo) GetBundleLookup(mountingType models.MountingType, goal models.BundleGoal, width int) ([]string, error) {
+	rows, err := r.db.Query(
+		`SELECT item_key FROM bundle_lookup WHERE mounting_type = ? AND goal = ? AND width = ? ORDER BY rank`,
+		mountingType, goal, width,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get bundle lookup (mountingType=%s, goal=%s, width=%d): %w", mountingType, goal, width, err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("get bundle lookup: scan (mountingType=%s, goal=%s, width=%d): %w", mountingType, goal, width, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get bundle lookup: iterate (mountingType=%s, goal=%s, width=%d): %w", mountingType, goal, width, err)
+	}
+	return keys, nil
+}
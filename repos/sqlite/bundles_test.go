@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/repos"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveBundle(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepo(db)
+
+	gate, err := repo.GetProductByID(1)
+	require.NoError(t, err)
+	gate.Qty = 1
+	extension, err := repo.GetProductByID(5)
+	require.NoError(t, err)
+	extension.Qty = 4
+
+	saved, err := repo.SaveBundle(models.Bundle{Components: []models.Product{gate, extension}})
+	require.NoError(t, err)
+	require.NotZero(t, saved.Id)
+	require.Equal(t, models.BundleSlug(saved.Components), saved.Slug)
+	require.Equal(t, gate.Price.Add(extension.Price.Mul(4)), saved.Price)
+	require.Len(t, saved.Components, 2)
+	require.Equal(t, 4, saved.Components[1].Qty)
+
+	// saving the same bundle again gives the one already saved
+	again, err := repo.SaveBundle(models.Bundle{Components: []models.Product{gate, extension}})
+	require.NoError(t, err)
+	require.Equal(t, saved.Id, again.Id)
+
+	// the price follows the catalog
+	_, err = db.Exec(`UPDATE products SET price = price + 100 WHERE id = ?`, gate.Id)
+	require.NoError(t, err)
+	reloaded, err := repo.GetBundleBySlug(saved.Slug)
+	require.NoError(t, err)
+	require.Equal(t, saved.Price.Amount+100, reloaded.Price.Amount)
+
+	_, err = repo.GetBundleBySlug("no-such-bundle")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// saved bundles are not listed with the catalog
+	products, err := repo.GetProducts(repos.ProductFilterParams{})
+	require.NoError(t, err)
+	for _, product := range products {
+		require.NotEqual(t, saved.Id, product.Id)
+	}
+	count, err := repo.CountProducts(models.ProductTypeBundle, repos.ProductFilterParams{Type: models.ProductTypeBundle})
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestSavedBundleWithDeletedProduct(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewProductRepo(db)
+
+	gate, err := repo.GetProductByID(1)
+	require.NoError(t, err)
+	gate.Qty = 1
+	extension, err := repo.GetProductByID(5)
+	require.NoError(t, err)
+	extension.Qty = 2
+	saved, err := repo.SaveBundle(models.Bundle{Components: []models.Product{gate, extension}})
+	require.NoError(t, err)
+
+	// a bundle left behind by a product deleted some other way is replaced
+	// when it is saved again
+	_, err = db.Exec(`UPDATE bundle_components SET product_id = 999 WHERE bundle_id = ? AND product_id = ?`, saved.Id, extension.Id)
+	require.NoError(t, err)
+	_, err = repo.GetBundleBySlug(saved.Slug)
+	require.ErrorIs(t, err, repos.ErrBundleComponentMissing)
+	require.NotErrorIs(t, err, sql.ErrNoRows)
+	replaced, err := repo.SaveBundle(models.Bundle{Components: []models.Product{gate, extension}})
+	require.NoError(t, err)
+	require.Equal(t, saved.Slug, replaced.Slug)
+	require.NotEqual(t, saved.Id, replaced.Id)
+
+	require.NoError(t, repo.DeleteProductByID(extension.Id))
+	_, err = repo.GetBundleBySlug(saved.Slug)
+	require.ErrorIs(t, err, sql.ErrNoRows, "the bundle goes with the product")
+	var components int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM bundle_components WHERE bundle_id IN (?, ?)`, saved.Id, replaced.Id).Scan(&components))
+	require.Zero(t, components)
+}
+
+func TestBundleLookup(t *testing.T) {
+	repo := NewProductRepo(openTestDB(t))
+
+	rows := []repos.BundleLookupRow{
+		{MountingType: models.MountingTypePressureFit, Goal: models.BundleGoalPrice, Width: 100, Rank: 1, Key: "2-1_8-4"},
+		{MountingType: models.MountingTypePressureFit, Goal: models.BundleGoalPrice, Width: 100, Rank: 0, Key: "1-1_5-4"},
+		{MountingType: models.MountingTypePressureFit, Goal: models.BundleGoalFit, Width: 100, Rank: 0, Key: "1-1_4-1"},
+	}
+	require.NoError(t, repo.ReplaceBundleLookup(rows))
+
+	keys, err := repo.GetBundleLookup(models.MountingTypePressureFit, models.BundleGoalPrice, 100)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1-1_5-4", "2-1_8-4"}, keys)
+
+	// replacing drops what was there before
+	require.NoError(t, repo.ReplaceBundleLookup(rows[2:]))
+	keys, err = repo.GetBundleLookup(models.MountingTypePressureFit, models.BundleGoalPrice, 100)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	require.NoError(t, repo.ClearBundleLookup())
+	keys, err = repo.GetBundleLookup(models.MountingTypePressureFit, models.BundleGoalFit, 100)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+}
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,8 +10,13 @@ import (
 )
 
 type CartRepo struct {
-	db          *sql.DB
-	productRepo *ProductRepo
+	db                *sql.DB
+	productRepo       *ProductRepo
+	promotionRepo     *PromotionRepo
+	shippingRepo      *ShippingRepo
+	collectAddress    string
+	taxRepo           *TaxRepo
+	multiGateDiscount models.MultiGateDiscount
 }
 
 func NewCartRepo(db *sql.DB, productRepo *ProductRepo) *CartRepo {
@@ -21,7 +27,115 @@ func NewCartRepo(db *sql.DB, productRepo *ProductRepo) *CartRepo {
 	if productRepo == nil {
 		panic("product repo is nil for cart repo")
 	}
-	return &CartRepo{db, productRepo}
+	return &CartRepo{db: db, productRepo: productRepo}
+}
+
+// SetMultiGateDiscount sets the discount applied to carts as they are read.
+func (r *CartRepo) SetMultiGateDiscount(discount models.MultiGateDiscount) {
+	r.multiGateDiscount = discount
+}
+
+// SetPromotionRepo sets where the promotions applied to carts as they are
+// read come from. Without one only the multi-gate discount applies.
+func (r *CartRepo) SetPromotionRepo(promotionRepo *PromotionRepo) {
+	r.promotionRepo = promotionRepo
+}
+
+// ApplyDiscounts works out the cart's discounts from the multi-gate discount,
+// the automatic promotions and the code the cart entered, as of now. A code
+// that cannot be used is left out with a warning saying why.
+func (r *CartRepo) ApplyDiscounts(cart *models.Cart) error {
+	if r.promotionRepo == nil {
+		cart.ApplyDiscounts(r.multiGateDiscount)
+		return nil
+	}
+	now := time.Now()
+
+	code, email, hasCode, err := r.promotionRepo.GetCartCode(cart.ID)
+	if err != nil {
+		return fmt.Errorf("apply discounts (cartID=%s): %w", cart.ID, err)
+	}
+	cart.PromoCode, cart.CustomerEmail = code.Code, email
+
+	automatic, err := r.promotionRepo.GetAutomaticPromotions()
+	if err != nil {
+		return fmt.Errorf("apply discounts (cartID=%s): %w", cart.ID, err)
+	}
+	var promotions []models.Promotion
+	for _, promotion := range automatic {
+		uses, err := r.promotionRepo.CustomerUses(promotion.ID, email)
+		if err != nil {
+			return fmt.Errorf("apply discounts (cartID=%s): %w", cart.ID, err)
+		}
+		if promotion.Available(now, email, uses) == nil {
+			promotions = append(promotions, promotion)
+		}
+	}
+	if hasCode {
+		uses, err := r.promotionRepo.CustomerUses(code.ID, email)
+		if err != nil {
+			return fmt.Errorf("apply discounts (cartID=%s): %w", cart.ID, err)
+		}
+		if err := code.Available(now, email, uses); err != nil {
+			cart.Warnings = append(cart.Warnings, models.PromoCodeWarning(code.Code, err))
+		} else {
+			promotions = append(promotions, code)
+		}
+	}
+
+	cart.ApplyDiscounts(r.multiGateDiscount, promotions...)
+	return nil
+}
+
+// SetShippingRepo sets where the shipping zones carts are quoted for as
+// they are read come from, and the address orders can be collected from,
+// empty when click and collect is not offered. Without a repo carts are not
+// quoted for shipping.
+func (r *CartRepo) SetShippingRepo(shippingRepo *ShippingRepo, collectAddress string) {
+	r.shippingRepo = shippingRepo
+	r.collectAddress = collectAddress
+}
+
+// QuoteShipping prices the ways the cart can get to the customer, after its
+// discounts, and sets the one the cart chose.
+func (r *CartRepo) QuoteShipping(cart *models.Cart) error {
+	if r.shippingRepo == nil {
+		return nil
+	}
+	zones, err := r.shippingRepo.GetShippingZones()
+	if err != nil {
+		return fmt.Errorf("quote shipping (cartID=%s): %w", cart.ID, err)
+	}
+	key, err := r.shippingRepo.GetCartShipping(cart.ID)
+	if err != nil {
+		return fmt.Errorf("quote shipping (cartID=%s): %w", cart.ID, err)
+	}
+	var collect *models.ShippingOption
+	if r.collectAddress != "" {
+		option := models.CollectOption(r.collectAddress)
+		collect = &option
+	}
+	cart.QuoteShipping(zones, collect, key)
+	return nil
+}
+
+// SetTaxRepo sets where the VAT rates of carts as they are read come from.
+// Without one carts are not taxed.
+func (r *CartRepo) SetTaxRepo(taxRepo *TaxRepo) {
+	r.taxRepo = taxRepo
+}
+
+// ApplyVAT works out the VAT in the cart, after its discounts and shipping.
+func (r *CartRepo) ApplyVAT(cart *models.Cart) error {
+	if r.taxRepo == nil {
+		return nil
+	}
+	rates, err := r.taxRepo.GetVATRates()
+	if err != nil {
+		return fmt.Errorf("apply vat (cartID=%s): %w", cart.ID, err)
+	}
+	cart.ApplyVAT(rates)
+	return nil
 }
 
 func (r *CartRepo) SaveCart(cart models.Cart) (*sql.Result, error) {
@@ -76,6 +190,15 @@ func (r *CartRepo) GetCartByID(id string) (models.Cart, bool, error) {
 	}
 
 	cart.SetTotalValue()
+	if err := r.ApplyDiscounts(&cart); err != nil {
+		return models.Cart{}, found, err
+	}
+	if err := r.QuoteShipping(&cart); err != nil {
+		return models.Cart{}, found, err
+	}
+	if err := r.ApplyVAT(&cart); err != nil {
+		return models.Cart{}, found, err
+	}
 
 	return cart, found, nil
 }
@@ -173,6 +296,8 @@ func (r *CartRepo) selectCartItemComponents(cartID, cartItemID string) ([]models
 		cart_id,
 		product_id,
 		qty,
+		price,
+		currency,
 		created_at
 	FROM
 		cart_item_component
@@ -189,16 +314,31 @@ func (r *CartRepo) selectCartItemComponents(cartID, cartItemID string) ([]models
 	components := []models.CartItemComponent{}
 	for rows.Next() {
 		var component models.CartItemComponent
+		var quotedPrice sql.NullInt64
+		var quotedCurrency sql.NullString
 		if err := rows.Scan(
 			&component.CartItemID,
 			&component.CartID,
 			&component.Product.Id,
 			&component.Product.Qty,
+			&quotedPrice,
+			&quotedCurrency,
 			&component.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan cart item component: %v", err)
 		}
+		if quotedPrice.Valid {
+			component.QuotedPrice = models.NewMoney(quotedPrice.Int64, priceCurrency(models.Money{Currency: models.Currency(quotedCurrency.String)}))
+		}
 		product, err := r.productRepo.GetProductByID(component.Product.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			// taken out of the catalog since it was added, the cart still
+			// has to load so the customer can remove it
+			component.Unavailable = true
+			component.Product.Name = fmt.Sprintf("Product %d", component.Product.Id)
+			components = append(components, component)
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to get product (ID: %d) for cart component: %v", component.Product.Id, err)
 		}
@@ -295,15 +435,19 @@ func (r *CartRepo) SaveCartItemComponents(components []models.CartItemComponent)
 				cart_id,
 				product_id,
 				qty,
+				price,
+				currency,
 				created_at
 			)
 		VALUES
-			(?, ?, ?, ?, ?)`
+			(?, ?, ?, ?, ?, ?, ?)`
 		if _, err := r.db.Exec(q,
 			c.CartItemID,
 			c.CartID,
 			c.Product.Id,
 			c.Product.Qty,
+			c.Product.Price.Amount,
+			priceCurrency(c.Product.Price),
 			c.CreatedAt,
 		); err != nil {
 			return fmt.Errorf("failed to save cart item component (cartItemID: %s, productID: %d): %v",
@@ -313,6 +457,27 @@ func (r *CartRepo) SaveCartItemComponents(components []models.CartItemComponent)
 	return nil
 }
 
+// QuoteCurrentPrices takes the catalog's current prices as the ones the
+// cart's components were last shown at, once the customer has been told
+// about any change.
+func (r *CartRepo) QuoteCurrentPrices(cartID string) error {
+	if _, err := r.db.Exec(`
+		UPDATE
+			cart_item_component
+		SET
+			price = (SELECT p.price FROM products p WHERE p.id = cart_item_component.product_id),
+			currency = (SELECT p.currency FROM products p WHERE p.id = cart_item_component.product_id)
+		WHERE
+			cart_id = ?
+		AND
+			product_id IN (SELECT id FROM products)`,
+		cartID,
+	); err != nil {
+		return fmt.Errorf("failed to quote current prices for cart (cartID: %s): %w", cartID, err)
+	}
+	return nil
+}
+
 func (r *CartRepo) IncrementCartItem(cartID, itemID string) error {
 	if _, err := r.db.Exec(`
 		UPDATE
@@ -345,8 +510,52 @@ func (r *CartRepo) DecrementCartItem(cartID, itemID string) error {
 	return nil
 }
 
+// SetCartItemQty sets how many of a cart item are in the cart.
+func (r *CartRepo) SetCartItemQty(cartID, itemID string, qty int) error {
+	if _, err := r.db.Exec(`UPDATE cart_item SET qty = ? WHERE id = ? AND cart_id = ?`, qty, itemID, cartID); err != nil {
+		return fmt.Errorf("failed to set cart item qty (cartID: %s, itemID: %s, qty: %d): %w", cartID, itemID, qty, err)
+	}
+	return nil
+}
+
+// RemoveCartItem takes an item and its components out of the cart.
 func (r *CartRepo) RemoveCartItem(cartID, itemID string) error {
-	return fmt.Errorf("remove cart item not implemented (cartID: %s, itemID: %s)", cartID, itemID)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("remove cart item: begin transaction (cartID: %s, itemID: %s): %w", cartID, itemID, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM cart_item_component WHERE cart_item_id = ? AND cart_id = ?`, itemID, cartID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("remove cart item: delete components (cartID: %s, itemID: %s): %w", cartID, itemID, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM cart_item WHERE id = ? AND cart_id = ?`, itemID, cartID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("remove cart item: delete item (cartID: %s, itemID: %s): %w", cartID, itemID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("remove cart item: commit (cartID: %s, itemID: %s): %w", cartID, itemID, err)
+	}
+	return nil
+}
+
+// ClearCart takes every item out of the cart.
+func (r *CartRepo) ClearCart(cartID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("clear cart: begin transaction (cartID: %s): %w", cartID, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM cart_item_component WHERE cart_id = ?`, cartID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("clear cart: delete components (cartID: %s): %w", cartID, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM cart_item WHERE cart_id = ?`, cartID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("clear cart: delete items (cartID: %s): %w", cartID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("clear cart: commit (cartID: %s): %w", cartID, err)
+	}
+	return nil
 }
 
 func (r *CartRepo) RemoveCartItemComponents(itemID string) error {
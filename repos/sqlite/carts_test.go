@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/stretchr/testify/require"
+)
+
+func insertTestCartItem(t *testing.T, repo *CartRepo, cartID, itemID string, productID int) {
+	t.Helper()
+	now := time.Now()
+	require.NoError(t, repo.InsertCartItem(models.CartItem{ID: itemID, CartID: cartID, Qty: 1, CreatedAt: now}))
+	require.NoError(t, repo.SaveCartItemComponents([]models.CartItemComponent{
+		{CartItemID: itemID, CartID: cartID, CreatedAt: now, Product: models.Product{Id: productID, Qty: 1}},
+	}))
+}
+
+func TestCartItemQtyAndRemoval(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewCartRepo(db, NewProductRepo(db))
+
+	now := time.Now()
+	_, err := repo.SaveCart(models.Cart{ID: "cart", CreatedAt: now, LastUpdatedAt: now})
+	require.NoError(t, err)
+	insertTestCartItem(t, repo, "cart", "1-1", 1)
+	insertTestCartItem(t, repo, "cart", "2-1", 2)
+
+	require.NoError(t, repo.SetCartItemQty("cart", "1-1", 4))
+	item, err := repo.SelectCartItem("cart", "1-1")
+	require.NoError(t, err)
+	require.Equal(t, 4, item.Qty)
+
+	require.NoError(t, repo.RemoveCartItem("cart", "1-1"))
+	exists, err := repo.DoesCartItemExist("cart", "1-1")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	var components int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM cart_item_component WHERE cart_item_id = '1-1'`).Scan(&components))
+	require.Zero(t, components, "the item's components go with it")
+
+	require.NoError(t, repo.ClearCart("cart"))
+	cart, found, err := repo.GetCartByID("cart")
+	require.NoError(t, err)
+	require.True(t, found, "clearing keeps the cart")
+	require.Empty(t, cart.Items)
+}
+
+func TestCartQuotedPrices(t *testing.T) {
+	db := openTestDB(t)
+	products := NewProductRepo(db)
+	repo := NewCartRepo(db, products)
+
+	now := time.Now()
+	_, err := repo.SaveCart(models.Cart{ID: "cart", CreatedAt: now, LastUpdatedAt: now})
+	require.NoError(t, err)
+	gate, err := products.GetProductByID(1)
+	require.NoError(t, err)
+	gate.Qty = 1
+	require.NoError(t, repo.InsertCartItem(models.CartItem{ID: "1-1", CartID: "cart", Qty: 1, CreatedAt: now}))
+	require.NoError(t, repo.SaveCartItemComponents([]models.CartItemComponent{
+		{CartItemID: "1-1", CartID: "cart", CreatedAt: now, Product: gate},
+	}))
+	insertTestCartItem(t, repo, "cart", "2-1", 2)
+
+	_, err = db.Exec(`UPDATE products SET price = price + 100 WHERE id = 1`)
+	require.NoError(t, err)
+	cart, _, err := repo.GetCartByID("cart")
+	require.NoError(t, err)
+	component := cart.Items[0].Components[0]
+	require.Equal(t, gate.Price, component.QuotedPrice)
+	require.Equal(t, gate.Price.Add(models.EUR(100)), component.Price)
+
+	require.NoError(t, repo.QuoteCurrentPrices("cart"))
+	cart, _, err = repo.GetCartByID("cart")
+	require.NoError(t, err)
+	require.Equal(t, cart.Items[0].Components[0].Price, cart.Items[0].Components[0].QuotedPrice)
+	require.Equal(t, cart.Items[1].Components[0].Price, cart.Items[1].Components[0].QuotedPrice, "components without a quote get one")
+
+	_, err = db.Exec(`DELETE FROM products WHERE id = 2`)
+	require.NoError(t, err)
+	cart, _, err = repo.GetCartByID("cart")
+	require.NoError(t, err, "a cart with a product gone from the catalog still loads")
+	require.True(t, cart.Items[1].Components[0].Unavailable)
+	require.NoError(t, repo.QuoteCurrentPrices("cart"))
+}
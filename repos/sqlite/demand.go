@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+)
+
+// DemandRepo records the widths asked of the builder, in bundle_sizes, and
+// sums them up for purchasing.
+type DemandRepo struct {
+	db *sql.DB
+}
+
+func NewDemandRepo(db *sql.DB) *DemandRepo {
+	return &DemandRepo{db}
+}
+
+// RecordBuildRequest stores a width asked of the builder and how it went.
+func (r *DemandRepo) RecordBuildRequest(req models.BuildRequest) error {
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now()
+	}
+	_, err := r.db.Exec(
+		`INSERT INTO bundle_sizes (type, size, cart_id, outcome, created_at) VALUES (?, ?, ?, ?, ?)`,
+		req.MountingType, req.Width, req.CartID, req.Outcome, req.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("record build request: insert (type=%q, size=%v, cartID=%s): %w", req.MountingType, req.Width, req.CartID, err)
+	}
+	return nil
+}
+
+// MarkAddedToCart marks the cart's requests for widths a bundle it added
+// fits, from minWidth to maxWidth, as converted.
+func (r *DemandRepo) MarkAddedToCart(cartID string, mountingType models.MountingType, minWidth, maxWidth float32) error {
+	if cartID == "" {
+		return nil
+	}
+	_, err := r.db.Exec(
+		`UPDATE bundle_sizes SET added_to_cart = 1
+		 WHERE cart_id = ? AND type = ? AND size BETWEEN ? AND ? AND added_to_cart = 0`,
+		cartID, mountingType, minWidth, maxWidth,
+	)
+	if err != nil {
+		return fmt.Errorf("mark build requests added to cart (cartID=%s, type=%q, widths=%v-%v): %w", cartID, mountingType, minWidth, maxWidth, err)
+	}
+	return nil
+}
+
+// GetBuilderDemand sums up the requests made since since, or all of them
+// when since is zero, by mounting type and whole cm.
+func (r *DemandRepo) GetBuilderDemand(since time.Time) (models.BuilderDemand, error) {
+	demand := models.BuilderDemand{Since: since}
+
+	where := `1 = 1`
+	args := []any{}
+	if !since.IsZero() {
+		where = `b.created_at >= ?`
+		args = append(args, since.UTC())
+	}
+
+	placeholders := make([]string, len(models.PaidOrderStatuses))
+	paidArgs := make([]any, len(models.PaidOrderStatuses))
+	for i, status := range models.PaidOrderStatuses {
+		placeholders[i] = "?"
+		paidArgs[i] = status
+	}
+	paidCarts := `SELECT cart_id FROM orders WHERE status IN (` + strings.Join(placeholders, ", ") + `)`
+
+	rows, err := r.db.Query(
+		`SELECT b.type, CAST(b.size AS INTEGER) AS width, COUNT(*),
+		        COALESCE(SUM(b.outcome = ?), 0), COALESCE(SUM(b.outcome = ?), 0),
+		        COALESCE(SUM(b.outcome = ?), 0), COALESCE(SUM(b.outcome = ?), 0),
+		        COALESCE(SUM(b.added_to_cart), 0),
+		        COALESCE(SUM(b.added_to_cart AND b.cart_id IN (`+paidCarts+`)), 0)
+		 FROM bundle_sizes b
+		 WHERE `+where+`
+		 GROUP BY b.type, width
+		 ORDER BY b.type, width`,
+		append(append([]any{models.BuildServed, models.BuildPartlyInStock, models.BuildOutOfStock, models.BuildUnserved}, paidArgs...), args...)...,
+	)
+	if err != nil {
+		return demand, fmt.Errorf("get builder demand: query widths (since=%v): %w", since, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w models.WidthDemand
+		if err := rows.Scan(
+			&w.MountingType, &w.Width, &w.Requests,
+			&w.Served, &w.PartlyInStock, &w.OutOfStock, &w.Unserved,
+			&w.AddedToCart, &w.Ordered,
+		); err != nil {
+			return demand, fmt.Errorf("get builder demand: scan width: %w", err)
+		}
+		demand.Widths = append(demand.Widths, w)
+	}
+	if err := rows.Err(); err != nil {
+		return demand, fmt.Errorf("get builder demand: iterate widths: %w", err)
+	}
+
+	err = r.db.QueryRow(
+		`SELECT COUNT(DISTINCT b.cart_id),
+		        COUNT(DISTINCT CASE WHEN b.added_to_cart THEN b.cart_id END),
+		        COUNT(DISTINCT CASE WHEN b.added_to_cart AND b.cart_id IN (`+paidCarts+`) THEN b.cart_id END)
+		 FROM bundle_sizes b
+		 WHERE b.cart_id != '' AND `+where,
+		append(paidArgs, args...)...,
+	).Scan(&demand.Funnel.Carts, &demand.Funnel.AddedToCart, &demand.Funnel.Ordered)
+	if err != nil {
+		return demand, fmt.Errorf("get builder demand: query funnel (since=%v): %w", since, err)
+	}
+	return demand, nil
+}
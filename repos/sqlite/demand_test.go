@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderDemand(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewDemandRepo(db)
+
+	pressure := models.MountingTypePressureFit
+	for _, req := range []models.BuildRequest{
+		{CartID: "a", MountingType: pressure, Width: 100, Outcome: models.BuildServed},
+		{CartID: "a", MountingType: pressure, Width: 100.5, Outcome: models.BuildPartlyInStock},
+		{CartID: "b", MountingType: pressure, Width: 100, Outcome: models.BuildServed},
+		{CartID: "b", MountingType: pressure, Width: 300, Outcome: models.BuildUnserved},
+		{CartID: "c", MountingType: models.MountingTypeHardwareFit, Width: 100, Outcome: models.BuildOutOfStock},
+		{MountingType: pressure, Width: 90, Outcome: models.BuildServed, CreatedAt: time.Now().AddDate(0, 0, -60)},
+	} {
+		require.NoError(t, repo.RecordBuildRequest(req))
+	}
+
+	// a added a bundle that fits 98-104, b one that fits neither of its widths
+	require.NoError(t, repo.MarkAddedToCart("a", pressure, 98, 104))
+	require.NoError(t, repo.MarkAddedToCart("b", pressure, 60, 70))
+	require.NoError(t, repo.MarkAddedToCart("c", pressure, 98, 104), "c asked for hardware fit")
+	_, err := db.Exec(`INSERT INTO orders (cart_id, status) VALUES ('a', ?), ('c', ?)`, models.OrderStatusProcessing, models.OrderStatusProcessing)
+	require.NoError(t, err)
+
+	demand, err := repo.GetBuilderDemand(time.Now().AddDate(0, 0, -30))
+	require.NoError(t, err)
+	require.Equal(t, 5, demand.Requests(), "the request from before the period is left out")
+	require.Equal(t, models.BuilderFunnel{Carts: 3, AddedToCart: 1, Ordered: 1}, demand.Funnel)
+
+	require.Equal(t, []models.WidthDemand{
+		{MountingType: models.MountingTypeHardwareFit, Width: 100, Requests: 1, OutOfStock: 1},
+		{MountingType: pressure, Width: 100, Requests: 3, Served: 2, PartlyInStock: 1, AddedToCart: 2, Ordered: 2},
+		{MountingType: pressure, Width: 300, Requests: 1, Unserved: 1},
+	}, demand.Widths)
+
+	problems := demand.Problems()
+	require.Len(t, problems, 3)
+
+	all, err := repo.GetBuilderDemand(time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 6, all.Requests())
+	require.Equal(t, 3, all.Funnel.Carts, "requests without a cart are not in the funnel")
+}
@@ -4,49 +4,16 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/seanomeara96/gates/models"
 	"github.com/seanomeara96/gates/repos"
 )
 
-/*
-SQL statements to create the required tables:
-
-CREATE TABLE orders (
-				id INTEGER PRIMARY KEY AUTOINCREMENT,
-				cart_id INTEGER NOT NULL,
-				session_id TEXT,
-				status TEXT DEFAULT 'pending_payment',
-				customer_name TEXT,
-				customer_email TEXT,
-				customer_phone TEXT,
-				shipping_address TEXT,
-				billing_address TEXT,
-				payment_method TEXT,
-				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-				stripe_ref STRING
-);
-
-CREATE TABLE order_items (
-				id INTEGER PRIMARY KEY AUTOINCREMENT,
-				order_id INTEGER NOT NULL,
-				item_name TEXT NOT NULL,
-				item_quantity INTEGER NOT NULL,
-				FOREIGN KEY (order_id) REFERENCES orders(id)
-);
-
-CREATE TABLE order_item_components (
-				id INTEGER PRIMARY KEY AUTOINCREMENT,
-				order_id INTEGER NOT NULL,
-				order_item_id INTEGER NOT NULL,
-				product_id INTEGER NOT NULL,
-				product_name TEXT NOT NULL,
-				product_price REAL NOT NULL,
-				product_qty INTEGER NOT NULL,
-				FOREIGN KEY (order_id) REFERENCES orders(id),
-				FOREIGN KEY (order_item_id) REFERENCES order_items(id)
-);
-*/
+// The orders, order_items and order_item_components tables are created by
+// the migrations package.
 
 type OrderRepo struct {
 	db *sql.DB
@@ -65,6 +32,37 @@ func NewOrderRepo(db *sql.DB) *OrderRepo {
 	return &OrderRepo{db}
 }
 
+const orderColumns = `id, cart_id, session_id, status, customer_name, customer_email,
+	customer_phone, shipping_address, billing_address, payment_method,
+	created_at, stripe_ref, payment_ref, COALESCE(shipping_method, ''),
+	COALESCE(shipping_name, ''), COALESCE(shipping_amount, 0), COALESCE(shipping_currency, ''),
+	COALESCE(tax_country, ''), shipping_vat_rate, shipping_vat`
+
+func scanOrder(row scannable) (models.Order, error) {
+	var o models.Order
+	var shippingVATRate, shippingVAT sql.NullInt64
+	err := row.Scan(
+		&o.ID, &o.CartID, &o.SessionID, &o.Status, &o.CustomerName, &o.CustomerEmail,
+		&o.CustomerPhone, &o.ShippingAddress, &o.BillingAddress, &o.PaymentMethod,
+		&o.CreatedAt, &o.StripeRef, &o.PaymentRef, &o.ShippingMethod,
+		&o.ShippingName, &o.ShippingCost.Amount, &o.ShippingCost.Currency,
+		&o.TaxCountry, &shippingVATRate, &shippingVAT,
+	)
+	if err != nil {
+		return o, err
+	}
+	if shippingVAT.Valid {
+		o.ShippingTax = models.TaxLine{
+			Class: models.TaxStandard,
+			Rate:  int(shippingVATRate.Int64),
+			Gross: o.ShippingCost,
+			Net:   models.NewMoney(o.ShippingCost.Amount-shippingVAT.Int64, o.ShippingCost.Currency),
+			VAT:   models.NewMoney(shippingVAT.Int64, o.ShippingCost.Currency),
+		}
+	}
+	return o, nil
+}
+
 // Create operations
 func (r *OrderRepo) New(cart models.Cart) (int, error) {
 	if cart.ID == "" {
@@ -79,7 +77,30 @@ func (r *OrderRepo) New(cart models.Cart) (int, error) {
 	// Default status to pending payment
 	defaultStatus := models.OrderStatusPendingPayment
 
-	res, err := tx.Exec(`INSERT INTO orders(cart_id, status) VALUES(?, ?)`, cart.ID, defaultStatus)
+	// the email given with a promotion code, so uses per customer can be
+	// counted before payment fills in the rest of their details
+	email := sql.NullString{String: cart.CustomerEmail, Valid: cart.CustomerEmail != ""}
+	var shippingMethod, shippingName, shippingCurrency sql.NullString
+	var shippingAmount sql.NullInt64
+	if shipping := cart.Shipping; shipping.Method != "" {
+		shippingMethod = sql.NullString{String: string(shipping.Method), Valid: true}
+		shippingName = sql.NullString{String: shipping.Name, Valid: true}
+		shippingAmount = sql.NullInt64{Int64: shipping.Price.Amount, Valid: true}
+		shippingCurrency = sql.NullString{String: string(priceCurrency(shipping.Price)), Valid: true}
+	}
+	// carts that were not taxed leave the order without VAT
+	var taxCountry sql.NullString
+	var shippingVATRate, shippingVAT sql.NullInt64
+	if tax := cart.ShippingTax; tax.Class != "" {
+		taxCountry = sql.NullString{String: cart.TaxCountry(), Valid: true}
+		shippingVATRate = sql.NullInt64{Int64: int64(tax.Rate), Valid: true}
+		shippingVAT = sql.NullInt64{Int64: tax.VAT.Amount, Valid: true}
+	}
+	res, err := tx.Exec(
+		`INSERT INTO orders(cart_id, status, customer_email, shipping_method, shipping_name, shipping_amount, shipping_currency, tax_country, shipping_vat_rate, shipping_vat)
+		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		cart.ID, defaultStatus, email, shippingMethod, shippingName, shippingAmount, shippingCurrency, taxCountry, shippingVATRate, shippingVAT,
+	)
 	if err != nil {
 		_ = tx.Rollback()
 		return 0, fmt.Errorf("new order: insert into orders (cart_id=%s, status=%s): %w", cart.ID, defaultStatus, err)
@@ -93,6 +114,11 @@ func (r *OrderRepo) New(cart models.Cart) (int, error) {
 
 	id := int(_id)
 
+	if err := insertStatusChange(tx, id, sql.NullString{}, defaultStatus, models.OrderActorCheckout, ""); err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("new order: %w", err)
+	}
+
 	for idx, item := range cart.Items {
 		if err := r.InsertItem(tx, id, item); err != nil {
 			_ = tx.Rollback()
@@ -100,6 +126,27 @@ func (r *OrderRepo) New(cart models.Cart) (int, error) {
 		}
 	}
 
+	for _, discount := range cart.Discounts {
+		currency := discount.Amount.Currency
+		if currency == "" {
+			currency = models.DefaultCurrency
+		}
+		promotionID := sql.NullInt64{Int64: int64(discount.PromotionID), Valid: discount.PromotionID != 0}
+		if promotionID.Valid {
+			if err := checkPromotionLimits(tx, discount.PromotionID, cart.CustomerEmail, id); err != nil {
+				_ = tx.Rollback()
+				return 0, fmt.Errorf("new order: discount (order_id=%d, label=%q): %w", id, discount.Label, err)
+			}
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO order_discounts(order_id, label, amount, currency, promotion_id, free_shipping) VALUES (?, ?, ?, ?, ?, ?)`,
+			id, discount.Label, discount.Amount.Amount, currency, promotionID, discount.FreeShipping,
+		); err != nil {
+			_ = tx.Rollback()
+			return 0, fmt.Errorf("new order: insert discount (order_id=%d, label=%q): %w", id, discount.Label, err)
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		_ = tx.Rollback()
 		return 0, fmt.Errorf("new order: commit transaction (order_id=%d, cart_id=%s): %w", id, cart.ID, err)
@@ -108,6 +155,39 @@ func (r *OrderRepo) New(cart models.Cart) (int, error) {
 	return id, nil
 }
 
+// checkPromotionLimits makes sure placing orderID does not take a promotion
+// past its usage limits, counted in the transaction placing it so two
+// checkouts cannot both take the last use. It wraps
+// repos.ErrPromotionUnavailable when it would.
+func checkPromotionLimits(tx *sql.Tx, promotionID int, email string, orderID int) error {
+	uses, args := promotionUsesFilter()
+	var usageLimit, perCustomerLimit, total int
+	err := tx.QueryRow(
+		`SELECT p.usage_limit, p.per_customer_limit,
+		        (SELECT COUNT(DISTINCT d.order_id) FROM order_discounts d
+		           JOIN orders o ON o.id = d.order_id
+		          WHERE d.promotion_id = p.id AND o.id != ? AND `+uses+`)
+		   FROM promotions p WHERE p.id = ?`,
+		append(append([]any{orderID}, args...), promotionID)...,
+	).Scan(&usageLimit, &perCustomerLimit, &total)
+	if err != nil {
+		return fmt.Errorf("check promotion limits (promotion_id=%d): %w", promotionID, err)
+	}
+	if usageLimit > 0 && total >= usageLimit {
+		return fmt.Errorf("check promotion limits (promotion_id=%d, uses=%d): %w", promotionID, total, repos.ErrPromotionUnavailable)
+	}
+	if perCustomerLimit > 0 && email != "" {
+		byCustomer, err := customerUses(tx, promotionID, email, orderID)
+		if err != nil {
+			return err
+		}
+		if byCustomer >= perCustomerLimit {
+			return fmt.Errorf("check promotion limits (promotion_id=%d, email=%q, uses=%d): %w", promotionID, email, byCustomer, repos.ErrPromotionUnavailable)
+		}
+	}
+	return nil
+}
+
 func (r *OrderRepo) InsertItem(tx *sql.Tx, orderID int, item models.CartItem) error {
 	if tx == nil {
 		return errors.New("insert item: transaction cannot be nil")
@@ -141,9 +221,20 @@ func (r *OrderRepo) InsertComponent(tx *sql.Tx, orderID int, orderItemID int, co
 		return errors.New("insert component: transaction cannot be nil")
 	}
 
+	var taxClass sql.NullString
+	var vatRate, gross, net, vat sql.NullInt64
+	if tax := component.Tax; tax.Class != "" {
+		taxClass = sql.NullString{String: string(tax.Class), Valid: true}
+		vatRate = sql.NullInt64{Int64: int64(tax.Rate), Valid: true}
+		gross = sql.NullInt64{Int64: tax.Gross.Amount, Valid: true}
+		net = sql.NullInt64{Int64: tax.Net.Amount, Valid: true}
+		vat = sql.NullInt64{Int64: tax.VAT.Amount, Valid: true}
+	}
 	_, err := tx.Exec(
-		`INSERT INTO order_item_components(order_id, order_item_id, product_id, product_name, product_price, product_qty) VALUES (?,?,?,?,?,?)`,
-		orderID, orderItemID, component.Product.Id, component.Product.Name, component.Product.Price, component.Qty,
+		`INSERT INTO order_item_components(order_id, order_item_id, product_id, product_name, product_price, product_currency, product_qty, tax_class, vat_rate, gross_amount, net_amount, vat_amount)
+		 VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`,
+		orderID, orderItemID, component.Product.Id, component.Product.Name, component.Product.Price.Amount, priceCurrency(component.Product.Price), component.Qty,
+		taxClass, vatRate, gross, net, vat,
 	)
 	if err != nil {
 		return fmt.Errorf(
@@ -156,9 +247,7 @@ func (r *OrderRepo) InsertComponent(tx *sql.Tx, orderID int, orderItemID int, co
 
 // Read operations
 func (r *OrderRepo) GetOrders(params repos.GetOrdersParams) ([]models.Order, error) {
-	query := `SELECT id, cart_id, session_id, status, customer_name, customer_email,
-											customer_phone, shipping_address, billing_address, payment_method,
-											created_at, stripe_ref FROM orders LIMIT ? OFFSET ?`
+	query := `SELECT ` + orderColumns + ` FROM orders LIMIT ? OFFSET ?`
 
 	rows, err := r.db.Query(query, params.Limit, params.Offset)
 	if err != nil {
@@ -168,12 +257,7 @@ func (r *OrderRepo) GetOrders(params repos.GetOrdersParams) ([]models.Order, err
 
 	var orders []models.Order
 	for rows.Next() {
-		var o models.Order
-		err := rows.Scan(
-			&o.ID, &o.CartID, &o.SessionID, &o.Status, &o.CustomerName, &o.CustomerEmail,
-			&o.CustomerPhone, &o.ShippingAddress, &o.BillingAddress, &o.PaymentMethod,
-			&o.CreatedAt, &o.StripeRef,
-		)
+		o, err := scanOrder(rows)
 		if err != nil {
 			return nil, fmt.Errorf("get orders: scan order row: %w", err)
 		}
@@ -188,18 +272,9 @@ func (r *OrderRepo) GetOrders(params repos.GetOrdersParams) ([]models.Order, err
 }
 
 func (r *OrderRepo) GetOrderByID(id int) (*models.Order, error) {
-	query := `SELECT id, cart_id, session_id, status, customer_name, customer_email,
-											customer_phone, shipping_address, billing_address, payment_method,
-											created_at, stripe_ref FROM orders WHERE id = ?`
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = ?`
 
-	row := r.db.QueryRow(query, id)
-
-	var o models.Order
-	err := row.Scan(
-		&o.ID, &o.CartID, &o.SessionID, &o.Status, &o.CustomerName, &o.CustomerEmail,
-		&o.CustomerPhone, &o.ShippingAddress, &o.BillingAddress, &o.PaymentMethod,
-		&o.CreatedAt, &o.StripeRef,
-	)
+	o, err := scanOrder(r.db.QueryRow(query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -211,6 +286,76 @@ func (r *OrderRepo) GetOrderByID(id int) (*models.Order, error) {
 	return &o, nil
 }
 
+// GetOrderByPaymentRef finds the order paid with the provider's paymentRef.
+// The error wraps sql.ErrNoRows when there is none.
+func (r *OrderRepo) GetOrderByPaymentRef(paymentRef string) (*models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE payment_ref = ?`
+
+	o, err := scanOrder(r.db.QueryRow(query, paymentRef))
+	if err != nil {
+		return nil, fmt.Errorf("get order by payment ref (payment_ref=%q): %w", paymentRef, err)
+	}
+	return &o, nil
+}
+
+// GetOrdersByStatus returns every order in one of statuses, oldest first.
+func (r *OrderRepo) GetOrdersByStatus(statuses ...models.OrderStatus) ([]models.Order, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(statuses))
+	args := make([]any, len(statuses))
+	for i, status := range statuses {
+		placeholders[i] = "?"
+		args[i] = status
+	}
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE status IN (` + strings.Join(placeholders, ", ") + `) ORDER BY id`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get orders by status: query orders (statuses=%v): %w", statuses, err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("get orders by status: scan order row: %w", err)
+		}
+		orders = append(orders, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("get orders by status: iterate order rows: %w", err)
+	}
+
+	return orders, nil
+}
+
+// GetOrderDiscounts lists the discounts taken off an order at checkout.
+func (r *OrderRepo) GetOrderDiscounts(orderID int) ([]models.Discount, error) {
+	rows, err := r.db.Query(`SELECT label, amount, currency, COALESCE(promotion_id, 0), free_shipping FROM order_discounts WHERE order_id = ? ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("get order discounts: query order_discounts (order_id=%d): %w", orderID, err)
+	}
+	defer rows.Close()
+
+	var discounts []models.Discount
+	for rows.Next() {
+		var discount models.Discount
+		if err := rows.Scan(&discount.Label, &discount.Amount.Amount, &discount.Amount.Currency, &discount.PromotionID, &discount.FreeShipping); err != nil {
+			return nil, fmt.Errorf("get order discounts: scan row (order_id=%d): %w", orderID, err)
+		}
+		discounts = append(discounts, discount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get order discounts: iterate rows (order_id=%d): %w", orderID, err)
+	}
+	return discounts, nil
+}
+
 func (r *OrderRepo) GetOrderItems(orderID int) ([]models.CartItem, error) {
 	query := `SELECT id, item_name, item_quantity FROM order_items WHERE order_id = ?`
 
@@ -235,6 +380,7 @@ func (r *OrderRepo) GetOrderItems(orderID int) ([]models.CartItem, error) {
 			return nil, fmt.Errorf("get order items: get components (order_id=%d, order_item_id=%d): %w", orderID, itemID, err)
 		}
 
+		item.ID = strconv.Itoa(itemID)
 		item.Components = components
 		items = append(items, item)
 	}
@@ -247,7 +393,9 @@ func (r *OrderRepo) GetOrderItems(orderID int) ([]models.CartItem, error) {
 }
 
 func (r *OrderRepo) GetOrderItemComponents(orderID, itemID int) ([]models.CartItemComponent, error) {
-	query := `SELECT product_id, product_name, product_price, product_qty
+	query := `SELECT product_id, product_name, product_price, product_currency, product_qty,
+											        COALESCE(tax_class, ''), COALESCE(vat_rate, 0), COALESCE(gross_amount, 0),
+											        COALESCE(net_amount, 0), COALESCE(vat_amount, 0)
 											FROM order_item_components
 											WHERE order_id = ? AND order_item_id = ?`
 
@@ -262,17 +410,25 @@ func (r *OrderRepo) GetOrderItemComponents(orderID, itemID int) ([]models.CartIt
 		var component models.CartItemComponent
 		var productID int
 		var productName string
-		var productPrice float32
+		var productPrice models.Money
 
-		if err := rows.Scan(&productID, &productName, &productPrice, &component.Qty); err != nil {
+		tax := &component.Tax
+		if err := rows.Scan(
+			&productID, &productName, &productPrice.Amount, &productPrice.Currency, &component.Qty,
+			&tax.Class, &tax.Rate, &tax.Gross.Amount, &tax.Net.Amount, &tax.VAT.Amount,
+		); err != nil {
 			return nil, fmt.Errorf("get order item components: scan component row (order_id=%d, order_item_id=%d): %w", orderID, itemID, err)
 		}
 
+		tax.Gross.Currency, tax.Net.Currency, tax.VAT.Currency = productPrice.Currency, productPrice.Currency, productPrice.Currency
+
 		// Create product for this component
 		component.Product = models.Product{
-			Id:    productID,
-			Name:  productName,
-			Price: productPrice,
+			Id:       productID,
+			Name:     productName,
+			Price:    productPrice,
+			Qty:      component.Qty,
+			TaxClass: tax.Class,
 		}
 
 		components = append(components, component)
@@ -285,20 +441,158 @@ func (r *OrderRepo) GetOrderItemComponents(orderID, itemID int) ([]models.CartIt
 	return components, nil
 }
 
+// GetVATLines lists the lines of orders paid for, and their shipping, placed
+// from from up to but not including to, with the VAT in each. A zero to has
+// no end. Orders placed before VAT was recorded are left out.
+func (r *OrderRepo) GetVATLines(from, to time.Time) ([]models.OrderVATLine, error) {
+	placeholders := make([]string, len(models.PaidOrderStatuses))
+	paidArgs := make([]any, len(models.PaidOrderStatuses))
+	for i, status := range models.PaidOrderStatuses {
+		placeholders[i] = "?"
+		paidArgs[i] = status
+	}
+	where := `o.status IN (` + strings.Join(placeholders, ", ") + `) AND o.created_at >= ?`
+	args := append(paidArgs, from.UTC().Format(time.DateTime))
+	if !to.IsZero() {
+		where += ` AND o.created_at < ?`
+		args = append(args, to.UTC().Format(time.DateTime))
+	}
+
+	rows, err := r.db.Query(
+		`SELECT o.id, o.created_at, o.status, o.tax_country, c.product_name, c.product_qty * i.item_quantity,
+		        c.tax_class, c.vat_rate, c.gross_amount, c.net_amount, c.vat_amount, c.product_currency, 0 AS shipping
+		   FROM order_item_components c
+		   JOIN order_items i ON i.id = c.order_item_id
+		   JOIN orders o ON o.id = c.order_id
+		  WHERE c.tax_class IS NOT NULL AND `+where+`
+		 UNION ALL
+		 SELECT o.id, o.created_at, o.status, o.tax_country, o.shipping_name, 1,
+		        'standard', o.shipping_vat_rate, o.shipping_amount, o.shipping_amount - o.shipping_vat, o.shipping_vat, o.shipping_currency, 1
+		   FROM orders o
+		  WHERE o.shipping_vat IS NOT NULL AND o.shipping_amount > 0 AND `+where+`
+		  ORDER BY 1, 13`,
+		append(args, args...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get vat lines (from=%s, to=%s): %w", from, to, err)
+	}
+	defer rows.Close()
+
+	var lines []models.OrderVATLine
+	for rows.Next() {
+		var line models.OrderVATLine
+		var currency models.Currency
+		var shipping bool
+		if err := rows.Scan(
+			&line.OrderID, &line.PlacedAt, &line.Status, &line.TaxCountry, &line.Description, &line.Qty,
+			&line.Tax.Class, &line.Tax.Rate, &line.Tax.Gross.Amount, &line.Tax.Net.Amount, &line.Tax.VAT.Amount, &currency, &shipping,
+		); err != nil {
+			return nil, fmt.Errorf("get vat lines: scan: %w", err)
+		}
+		line.Tax.Gross.Currency, line.Tax.Net.Currency, line.Tax.VAT.Currency = currency, currency, currency
+		if shipping {
+			line.Description = "Shipping: " + line.Description
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get vat lines: iterate: %w", err)
+	}
+	return lines, nil
+}
+
 // Update operations
-func (r *OrderRepo) UpdateStatus(orderID int, status models.OrderStatus) error {
+
+// UpdateStatus moves an order to status and records who did it in the status
+// history. Transitions the graph in models does not allow return a
+// *models.StatusTransitionError. Asking for the status the order already has
+// is a no-op, so repeated webhooks do not fill the history with duplicates.
+func (r *OrderRepo) UpdateStatus(orderID int, status models.OrderStatus, actor, note string) error {
 	// Validate the status before updating
 	if err := status.Validate(); err != nil {
 		return fmt.Errorf("update order status: invalid status (order_id=%d, status=%q): %w", orderID, status, err)
 	}
 
-	_, err := r.db.Exec("UPDATE orders SET status = ? WHERE id = ?", status, orderID)
+	tx, err := r.db.Begin()
 	if err != nil {
+		return fmt.Errorf("update order status: begin transaction (order_id=%d): %w", orderID, err)
+	}
+
+	var current models.Order
+	if err := tx.QueryRow("SELECT status, COALESCE(shipping_method, '') FROM orders WHERE id = ?", orderID).Scan(&current.Status, &current.ShippingMethod); err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("update order status: order not found (order_id=%d)", orderID)
+		}
+		return fmt.Errorf("update order status: get current status (order_id=%d): %w", orderID, err)
+	}
+
+	if current.Status == status {
+		_ = tx.Rollback()
+		return nil
+	}
+	if !current.CanTransitionTo(status) {
+		_ = tx.Rollback()
+		return &models.StatusTransitionError{OrderID: orderID, From: current.Status, To: status}
+	}
+
+	if _, err := tx.Exec("UPDATE orders SET status = ? WHERE id = ?", status, orderID); err != nil {
+		_ = tx.Rollback()
 		return fmt.Errorf("update order status: exec update (order_id=%d, status=%s): %w", orderID, status, err)
 	}
+
+	from := sql.NullString{String: string(current.Status), Valid: true}
+	if err := insertStatusChange(tx, orderID, from, status, actor, note); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("update order status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("update order status: commit transaction (order_id=%d): %w", orderID, err)
+	}
+	return nil
+}
+
+func insertStatusChange(tx *sql.Tx, orderID int, from sql.NullString, to models.OrderStatus, actor, note string) error {
+	_, err := tx.Exec(
+		`INSERT INTO order_status_history (order_id, from_status, to_status, actor, note, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		orderID, from, to, actor, sql.NullString{String: note, Valid: note != ""}, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert status change (order_id=%d, from=%s, to=%s): %w", orderID, from.String, to, err)
+	}
 	return nil
 }
 
+// GetStatusHistory returns an order's status changes, oldest first.
+func (r *OrderRepo) GetStatusHistory(orderID int) ([]models.OrderStatusChange, error) {
+	rows, err := r.db.Query(
+		`SELECT id, order_id, from_status, to_status, actor, note, created_at
+		   FROM order_status_history
+		  WHERE order_id = ?
+		  ORDER BY created_at, id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get status history: query (order_id=%d): %w", orderID, err)
+	}
+	defer rows.Close()
+
+	var history []models.OrderStatusChange
+	for rows.Next() {
+		var c models.OrderStatusChange
+		if err := rows.Scan(&c.ID, &c.OrderID, &c.From, &c.To, &c.Actor, &c.Note, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("get status history: scan row (order_id=%d): %w", orderID, err)
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get status history: iterate rows (order_id=%d): %w", orderID, err)
+	}
+	return history, nil
+}
+
 func (r *OrderRepo) UpdateCustomerDetails(orderID int, details CustomerDetails) error {
 	_, err := r.db.Exec(`
 		UPDATE orders
@@ -322,32 +616,28 @@ func (r *OrderRepo) UpdateCustomerDetails(orderID int, details CustomerDetails)
 	return nil
 }
 
+// UpdateOrder saves every order field except the status, which only changes
+// through UpdateStatus so the transition graph and history are never skipped.
 func (r *OrderRepo) UpdateOrder(order *models.Order) error {
 	if order == nil {
 		return errors.New("update order: order cannot be nil")
 	}
 
-	// Validate the status before updating
-	if err := order.Status.Validate(); err != nil {
-		return fmt.Errorf("update order: invalid status (order_id=%d, status=%q): %w", order.ID, order.Status, err)
-	}
-
 	_, err := r.db.Exec(`
 		UPDATE orders
 		SET cart_id = ?,
 						session_id = ?,
-						status = ?,
 						customer_name = ?,
 						customer_email = ?,
 						customer_phone = ?,
 						shipping_address = ?,
 						billing_address = ?,
 						payment_method = ?,
-						stripe_ref = ?
+						stripe_ref = ?,
+						payment_ref = ?
 		WHERE id = ?`,
 		order.CartID,
 		order.SessionID,
-		order.Status,
 		order.CustomerName,
 		order.CustomerEmail,
 		order.CustomerPhone,
@@ -355,6 +645,7 @@ func (r *OrderRepo) UpdateOrder(order *models.Order) error {
 		order.BillingAddress,
 		order.PaymentMethod,
 		order.StripeRef,
+		order.PaymentRef,
 		order.ID)
 
 	if err != nil {
@@ -372,6 +663,14 @@ func (r *OrderRepo) UpdateStripeRef(orderID int, stripeRef string) error {
 	return nil
 }
 
+func (r *OrderRepo) UpdatePaymentRef(orderID int, paymentRef string) error {
+	_, err := r.db.Exec("UPDATE orders SET payment_ref = ? WHERE id = ?", paymentRef, orderID)
+	if err != nil {
+		return fmt.Errorf("update payment reference: exec update (order_id=%d, payment_ref=%q): %w", orderID, paymentRef, err)
+	}
+	return nil
+}
+
 func (r *OrderRepo) UpdateSessionID(orderID int, sessionID string) error {
 	_, err := r.db.Exec("UPDATE orders SET session_id = ? WHERE id = ?", sessionID, orderID)
 	if err != nil {
@@ -380,6 +679,81 @@ func (r *OrderRepo) UpdateSessionID(orderID int, sessionID string) error {
 	return nil
 }
 
+// SetTaxCountry charges the order's VAT at country's rates instead, once
+// where it is delivered is known. What the customer paid for each line stays
+// the same, only how much of it is VAT changes. Orders placed before VAT was
+// recorded are left alone.
+func (r *OrderRepo) SetTaxCountry(orderID int, country string, rates models.VATRates) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("set tax country: begin transaction (order_id=%d): %w", orderID, err)
+	}
+
+	var current sql.NullString
+	var shipping models.Money
+	if err := tx.QueryRow(
+		`SELECT tax_country, COALESCE(shipping_amount, 0), COALESCE(shipping_currency, '') FROM orders WHERE id = ?`, orderID,
+	).Scan(&current, &shipping.Amount, &shipping.Currency); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("set tax country: get order (order_id=%d): %w", orderID, err)
+	}
+	if !current.Valid || current.String == country {
+		_ = tx.Rollback()
+		return nil
+	}
+
+	rows, err := tx.Query(
+		`SELECT id, tax_class, gross_amount, product_currency FROM order_item_components WHERE order_id = ? AND tax_class IS NOT NULL`, orderID,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("set tax country: query lines (order_id=%d): %w", orderID, err)
+	}
+	lines := map[int]models.TaxLine{}
+	for rows.Next() {
+		var id int
+		var class models.TaxClass
+		var gross models.Money
+		if err := rows.Scan(&id, &class, &gross.Amount, &gross.Currency); err != nil {
+			rows.Close()
+			_ = tx.Rollback()
+			return fmt.Errorf("set tax country: scan line (order_id=%d): %w", orderID, err)
+		}
+		lines[id] = models.NewTaxLine(class, rates.Lookup(country, class), gross)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		_ = tx.Rollback()
+		return fmt.Errorf("set tax country: iterate lines (order_id=%d): %w", orderID, err)
+	}
+	rows.Close()
+
+	for id, line := range lines {
+		if _, err := tx.Exec(
+			`UPDATE order_item_components SET vat_rate = ?, net_amount = ?, vat_amount = ? WHERE id = ?`,
+			line.Rate, line.Net.Amount, line.VAT.Amount, id,
+		); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("set tax country: update line (order_id=%d, line_id=%d): %w", orderID, id, err)
+		}
+	}
+
+	shippingTax := models.NewTaxLine(models.TaxStandard, rates.Lookup(country, models.TaxStandard), shipping)
+	if _, err := tx.Exec(
+		`UPDATE orders SET tax_country = ?, shipping_vat_rate = ?, shipping_vat = ? WHERE id = ?`,
+		country, shippingTax.Rate, shippingTax.VAT.Amount, orderID,
+	); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("set tax country: update order (order_id=%d, country=%s): %w", orderID, country, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("set tax country: commit transaction (order_id=%d): %w", orderID, err)
+	}
+	return nil
+}
+
 // Delete operations
 func (r *OrderRepo) DeleteOrder(orderID int) error {
 	tx, err := r.db.Begin()
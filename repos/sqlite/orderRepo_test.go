@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateStatusFollowsTransitionGraph(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewOrderRepo(db)
+
+	orderID, err := repo.New(models.Cart{ID: "cart"})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdateStatus(orderID, models.OrderStatusProcessing, models.OrderActorStripe, "paid"))
+	require.NoError(t, repo.UpdateStatus(orderID, models.OrderStatusProcessing, models.OrderActorStripe, "paid again"), "same status is a no-op")
+
+	err = repo.UpdateStatus(orderID, models.OrderStatusDraft, "admin:test", "")
+	var transitionErr *models.StatusTransitionError
+	require.True(t, errors.As(err, &transitionErr))
+	require.Equal(t, models.OrderStatusProcessing, transitionErr.From)
+	require.Equal(t, models.OrderStatusDraft, transitionErr.To)
+
+	order, err := repo.GetOrderByID(orderID)
+	require.NoError(t, err)
+	require.Equal(t, models.OrderStatusProcessing, order.Status)
+
+	history, err := repo.GetStatusHistory(orderID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.False(t, history[0].From.Valid)
+	require.Equal(t, models.OrderStatusPendingPayment, history[0].To)
+	require.Equal(t, string(models.OrderStatusPendingPayment), history[1].From.String)
+	require.Equal(t, models.OrderStatusProcessing, history[1].To)
+	require.Equal(t, models.OrderActorStripe, history[1].Actor)
+	require.Equal(t, "paid", history[1].Note.String)
+}
+
+func TestGetOrdersByStatus(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewOrderRepo(db)
+
+	pending, err := repo.New(models.Cart{ID: "pending"})
+	require.NoError(t, err)
+	paid, err := repo.New(models.Cart{ID: "paid"})
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateStatus(paid, models.OrderStatusProcessing, models.OrderActorSystem, ""))
+	awaiting, err := repo.New(models.Cart{ID: "awaiting"})
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateStatus(awaiting, models.OrderStatusAwaitingPayment, models.OrderActorSystem, ""))
+
+	orders, err := repo.GetOrdersByStatus(models.UnsettledOrderStatuses...)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	require.Equal(t, pending, orders[0].ID)
+	require.Equal(t, awaiting, orders[1].ID)
+
+	orders, err = repo.GetOrdersByStatus()
+	require.NoError(t, err)
+	require.Empty(t, orders)
+}
+
+func TestGetOrderByPaymentRef(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewOrderRepo(db)
+
+	orderID, err := repo.New(models.Cart{ID: "cart"})
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdatePaymentRef(orderID, "pi_1"))
+
+	order, err := repo.GetOrderByPaymentRef("pi_1")
+	require.NoError(t, err)
+	require.Equal(t, orderID, order.ID)
+	require.Equal(t, "pi_1", order.PaymentRef.String)
+
+	_, err = repo.GetOrderByPaymentRef("pi_other")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestNewOrderRecordsDiscounts(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewOrderRepo(db)
+
+	discount := models.Discount{Label: "10% off 2 or more gates", Amount: models.EUR(1500)}
+	orderID, err := repo.New(models.Cart{ID: "cart", Discounts: []models.Discount{discount}})
+	require.NoError(t, err)
+
+	discounts, err := repo.GetOrderDiscounts(orderID)
+	require.NoError(t, err)
+	require.Equal(t, []models.Discount{discount}, discounts)
+}
@@ -30,6 +30,18 @@ type scannable interface {
 	Scan(dest ...any) error
 }
 
+// productColumns lists the products columns in the order scanProductFromRow
+// expects them. alias prefixes each column for queries that join other tables.
+func productColumns(alias string) string {
+	columns := []string{"id", "type", "name", "width", "price", "currency", "img", "color", "tolerance", "inventory_level", "allow_backorder", "mounting_type", "max_span", "max_extensions", "slug", "weight", "tax_class"}
+	if alias != "" {
+		for i := range columns {
+			columns[i] = alias + "." + columns[i]
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
 // scanProductFromRow scans a single product row into a models.Product struct.
 func scanProductFromRow(row scannable) (models.Product, error) {
 	var product models.Product
@@ -38,11 +50,19 @@ func scanProductFromRow(row scannable) (models.Product, error) {
 		&product.Type,
 		&product.Name,
 		&product.Width,
-		&product.Price,
+		&product.Price.Amount,
+		&product.Price.Currency,
 		&product.Img,
 		&product.Color,
 		&product.Tolerance,
 		&product.InventoryLevel,
+		&product.AllowBackorder,
+		&product.MountingType,
+		&product.MaxSpan,
+		&product.MaxExtensions,
+		&product.Slug,
+		&product.Weight,
+		&product.TaxClass,
 	)
 	if err != nil {
 		// Specifically check for ErrNoRows and return it so callers can distinguish
@@ -67,16 +87,24 @@ func (r *ProductRepo) InsertProduct(product models.Product) (int, error) {
 	// The repository's job is just to execute the INSERT statement.
 	res, err := r.db.Exec(
 		`INSERT INTO products (
-			type, name, width, price, img, color, tolerance, inventory_level
-		 ) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			type, name, width, price, currency, img, color, tolerance, inventory_level, allow_backorder, mounting_type,
+			max_span, max_extensions, weight, tax_class
+		 ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		product.Type,
 		product.Name,
 		product.Width,
-		product.Price,
+		product.Price.Amount,
+		priceCurrency(product.Price),
 		product.Img,
 		product.Color,
 		product.Tolerance,
 		product.InventoryLevel,
+		product.AllowBackorder,
+		mountingType(product),
+		product.MaxSpan,
+		product.MaxExtensions,
+		product.Weight,
+		taxClass(product),
 	)
 	if err != nil {
 		// Handle potential DB constraint errors if needed, or just wrap
@@ -93,22 +121,46 @@ func (r *ProductRepo) InsertProduct(product models.Product) (int, error) {
 }
 
 // GetProductPrice retrieves only the price for a given product ID.
-func (r *ProductRepo) GetProductPrice(id int) (float32, error) {
+func (r *ProductRepo) GetProductPrice(id int) (models.Money, error) {
 	if r.db == nil {
-		return 0, errors.New("database connection is nil")
+		return models.Money{}, errors.New("database connection is nil")
 	}
-	var price float32
-	err := r.db.QueryRow("SELECT price FROM products WHERE id = ?", id).Scan(&price)
+	var price models.Money
+	err := r.db.QueryRow("SELECT price, currency FROM products WHERE id = ?", id).Scan(&price.Amount, &price.Currency)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// Return a clear "not found" error
-			return 0, fmt.Errorf("product with id %d not found", id)
+			return models.Money{}, fmt.Errorf("product with id %d not found", id)
 		}
-		return 0, fmt.Errorf("error getting price for product id %d: %w", id, err)
+		return models.Money{}, fmt.Errorf("error getting price for product id %d: %w", id, err)
 	}
 	return price, nil
 }
 
+// priceCurrency falls back to the shop currency for prices built without one.
+func priceCurrency(price models.Money) models.Currency {
+	if price.Currency == "" {
+		return models.DefaultCurrency
+	}
+	return price.Currency
+}
+
+// mountingType is the product's mounting type, pressure fit unless set.
+func mountingType(product models.Product) models.MountingType {
+	if product.MountingType == "" {
+		return models.MountingTypePressureFit
+	}
+	return product.MountingType
+}
+
+// taxClass is the class product is stored with, standard when it has none.
+func taxClass(product models.Product) models.TaxClass {
+	if product.TaxClass == "" {
+		return models.TaxStandard
+	}
+	return product.TaxClass
+}
+
 // GetProductByName retrieves a product by its unique name.
 // Returns sql.ErrNoRows if no product with that name exists.
 func (r *ProductRepo) GetProductByName(name string) (models.Product, error) {
@@ -116,7 +168,7 @@ func (r *ProductRepo) GetProductByName(name string) (models.Product, error) {
 		return models.Product{}, errors.New("database connection is nil")
 	}
 	product, err := scanProductFromRow(
-		r.db.QueryRow("SELECT id, type, name, width, price, img, color, tolerance, inventory_level FROM products WHERE name = ?", name),
+		r.db.QueryRow("SELECT "+productColumns("")+" FROM products WHERE name = ?", name),
 	)
 	// scanProductFromRow handles wrapping and sql.ErrNoRows detection
 	return product, err
@@ -128,9 +180,10 @@ func (r *ProductRepo) GetProducts(params repos.ProductFilterParams) ([]models.Pr
 		return nil, errors.New("database connection is nil")
 	}
 
-	baseSelect := "SELECT id, type, name, width, price, img, color, tolerance, inventory_level FROM products"
+	baseSelect := "SELECT " + productColumns("") + " FROM products"
 	args := []any{}
-	conditions := []string{}
+	// saved bundles are shared by link, they are not part of the catalog
+	conditions := []string{"slug = ''"}
 
 	if params.Type != "" {
 		conditions = append(conditions, "type = ?")
@@ -141,6 +194,10 @@ func (r *ProductRepo) GetProducts(params repos.ProductFilterParams) ([]models.Pr
 		conditions = append(conditions, "width < ?")
 		args = append(args, params.MaxWidth)
 	}
+	if params.MountingType != "" {
+		conditions = append(conditions, "mounting_type = ?")
+		args = append(args, params.MountingType)
+	}
 	if params.Color != "" {
 		conditions = append(conditions, "color = ?")
 		args = append(args, params.Color)
@@ -149,9 +206,9 @@ func (r *ProductRepo) GetProducts(params repos.ProductFilterParams) ([]models.Pr
 		conditions = append(conditions, "inventory_level >= ?")
 		args = append(args, params.InventoryLevel)
 	}
-	if params.Price > 0 { // Filter if Price is explicitly positive
+	if params.Price.Amount > 0 { // Filter if Price is explicitly positive
 		conditions = append(conditions, "price <= ?")
-		args = append(args, params.Price)
+		args = append(args, params.Price.Amount)
 	}
 
 	if len(conditions) > 0 {
@@ -193,7 +250,8 @@ func (r *ProductRepo) CountProducts(productType models.ProductType, params repos
 
 	baseSelect := "SELECT COUNT(*) FROM products"
 	args := []any{}
-	conditions := []string{}
+	// saved bundles are shared by link, they are not part of the catalog
+	conditions := []string{"slug = ''"}
 
 	if params.Type != "" {
 		conditions = append(conditions, "type = ?")
@@ -204,6 +262,10 @@ func (r *ProductRepo) CountProducts(productType models.ProductType, params repos
 		conditions = append(conditions, "width < ?")
 		args = append(args, params.MaxWidth)
 	}
+	if params.MountingType != "" {
+		conditions = append(conditions, "mounting_type = ?")
+		args = append(args, params.MountingType)
+	}
 	if params.Color != "" {
 		conditions = append(conditions, "color = ?")
 		args = append(args, params.Color)
@@ -212,9 +274,9 @@ func (r *ProductRepo) CountProducts(productType models.ProductType, params repos
 		conditions = append(conditions, "inventory_level >= ?")
 		args = append(args, params.InventoryLevel)
 	}
-	if params.Price > 0 { // Filter if Price is explicitly positive
+	if params.Price.Amount > 0 { // Filter if Price is explicitly positive
 		conditions = append(conditions, "price <= ?")
-		args = append(args, params.Price)
+		args = append(args, params.Price.Amount)
 	}
 
 	query := baseSelect + " WHERE " + strings.Join(conditions, " AND ")
@@ -234,8 +296,7 @@ func (r *ProductRepo) GetCompatibleExtensionsByGateID(gateID int) ([]models.Prod
 	}
 
 	// Explicitly selecting Extension type for clarity and safety
-	query := `SELECT
-				p.id, p.type, p.name, p.width, p.price, p.img, p.color, p.tolerance, p.inventory_level
+	query := `SELECT ` + productColumns("p") + `
 			  FROM products p
 			  INNER JOIN compatibles c ON p.id = c.extension_id
 			  WHERE c.gate_id = ? AND p.type = ?`
@@ -269,11 +330,13 @@ func (r *ProductRepo) UpdateProductByID(productID int, product models.Product) e
 
 	res, err := r.db.Exec(
 		`UPDATE products SET
-			type = ?, name = ?, width = ?, price = ?, img = ?,
-			color = ?, tolerance = ?, inventory_level = ?
+			type = ?, name = ?, width = ?, price = ?, currency = ?, img = ?,
+			color = ?, tolerance = ?, inventory_level = ?, allow_backorder = ?, mounting_type = ?,
+			max_span = ?, max_extensions = ?, weight = ?, tax_class = ?
 		 WHERE id = ?`,
-		product.Type, product.Name, product.Width, product.Price, product.Img,
-		product.Color, product.Tolerance, product.InventoryLevel,
+		product.Type, product.Name, product.Width, product.Price.Amount, priceCurrency(product.Price), product.Img,
+		product.Color, product.Tolerance, product.InventoryLevel, product.AllowBackorder, mountingType(product),
+		product.MaxSpan, product.MaxExtensions, product.Weight, taxClass(product),
 		productID, // Use the passed productID for the WHERE clause
 	)
 	if err != nil {
@@ -292,25 +355,69 @@ func (r *ProductRepo) UpdateProductByID(productID int, product models.Product) e
 	return nil
 }
 
-// DeleteProductByID deletes a product record by its ID.
+// DeleteProductByID deletes a product record by its ID along with its
+// compatibility rows, the saved bundles made with it and any cart items that
+// contain it, since those can no longer be hydrated or checked out.
 func (r *ProductRepo) DeleteProductByID(productID int) error {
 	if r.db == nil {
 		return errors.New("database connection is nil")
 	}
 
-	res, err := r.db.Exec("DELETE FROM products WHERE id = ?", productID)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction deleting product with ID %d: %w", productID, err)
+	}
+
+	res, err := tx.Exec("DELETE FROM products WHERE id = ?", productID)
 	if err != nil {
+		_ = tx.Rollback()
 		return fmt.Errorf("database error deleting product with ID %d: %w", productID, err)
 	}
 
 	// Check if any row was actually deleted
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
+		_ = tx.Rollback()
 		return fmt.Errorf("warning: could not get rows affected after delete for product ID %d: %v", productID, err)
 	} else if rowsAffected == 0 {
+		_ = tx.Rollback()
 		// Return a specific error indicating the product wasn't found
 		return fmt.Errorf("no product found with ID %d to delete", productID) // Or return sql.ErrNoRows
 	}
+
+	if _, err := tx.Exec("DELETE FROM compatibles WHERE gate_id = ? OR extension_id = ?", productID, productID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("database error deleting compatibles for product ID %d: %w", productID, err)
+	}
+
+	if err := deleteSavedBundles(tx, `id IN (SELECT bundle_id FROM bundle_components WHERE product_id = ?)`, productID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("database error deleting saved bundles for product ID %d: %w", productID, err)
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM cart_item
+		WHERE EXISTS (
+			SELECT 1 FROM cart_item_component c
+			WHERE c.cart_item_id = cart_item.id AND c.cart_id = cart_item.cart_id AND c.product_id = ?
+		)`, productID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("database error deleting cart items for product ID %d: %w", productID, err)
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM cart_item_component
+		WHERE (cart_id, cart_item_id) IN (
+			SELECT cart_id, cart_item_id FROM cart_item_component WHERE product_id = ?
+		)`, productID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("database error deleting cart item components for product ID %d: %w", productID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("commit transaction deleting product with ID %d: %w", productID, err)
+	}
 	return nil
 }
 
@@ -321,7 +428,7 @@ func (r *ProductRepo) GetProductByID(productID int) (models.Product, error) {
 		return models.Product{}, errors.New("database connection is nil")
 	}
 	product, err := scanProductFromRow(
-		r.db.QueryRow("SELECT id, type, name, width, price, img, color, tolerance, inventory_level FROM products WHERE id = ?", productID),
+		r.db.QueryRow("SELECT "+productColumns("")+" FROM products WHERE id = ?", productID),
 	)
 	// scanProductFromRow handles wrapping and sql.ErrNoRows detection
 	return product, err
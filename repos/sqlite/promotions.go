@@ -0,0 +1,221 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+)
+
+// PromotionRepo stores promotions, the codes carts have entered and counts
+// the orders placed with them.
+type PromotionRepo struct {
+	db *sql.DB
+}
+
+func NewPromotionRepo(db *sql.DB) *PromotionRepo {
+	return &PromotionRepo{db}
+}
+
+// promotionUsesFilter limits order_discounts d joined to orders o to the
+// orders that count as uses of a promotion.
+func promotionUsesFilter() (string, []any) {
+	placeholders := make([]string, len(models.PromotionUseStatuses))
+	args := make([]any, len(models.PromotionUseStatuses))
+	for i, status := range models.PromotionUseStatuses {
+		placeholders[i] = "?"
+		args[i] = status
+	}
+	return `o.status IN (` + strings.Join(placeholders, ", ") + `)`, args
+}
+
+func promotionSelect(where string) (string, []any) {
+	uses, args := promotionUsesFilter()
+	return `SELECT p.id, p.name, COALESCE(p.code, ''), p.kind, p.percent, p.amount, p.currency,
+	               p.min_extensions, p.gate_id, p.extension_id, p.starts_at, p.ends_at,
+	               p.usage_limit, p.per_customer_limit, p.active, p.created_at,
+	               (SELECT COUNT(DISTINCT d.order_id) FROM order_discounts d
+	                  JOIN orders o ON o.id = d.order_id
+	                 WHERE d.promotion_id = p.id AND ` + uses + `)
+	          FROM promotions p
+	         WHERE ` + where, args
+}
+
+func scanPromotion(row scannable) (models.Promotion, error) {
+	var p models.Promotion
+	var startsAt, endsAt sql.NullTime
+	err := row.Scan(
+		&p.ID, &p.Name, &p.Code, &p.Kind, &p.Percent, &p.Amount.Amount, &p.Amount.Currency,
+		&p.MinExtensions, &p.GateID, &p.ExtensionID, &startsAt, &endsAt,
+		&p.UsageLimit, &p.PerCustomerLimit, &p.Active, &p.CreatedAt,
+		&p.Uses,
+	)
+	p.StartsAt, p.EndsAt = startsAt.Time, endsAt.Time
+	return p, err
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// CreatePromotion saves a new promotion and returns its id.
+func (r *PromotionRepo) CreatePromotion(p models.Promotion) (int, error) {
+	if err := p.Validate(); err != nil {
+		return 0, fmt.Errorf("create promotion (name=%q): %w", p.Name, err)
+	}
+	code := sql.NullString{String: p.Code, Valid: p.Code != ""}
+	res, err := r.db.Exec(
+		`INSERT INTO promotions (name, code, kind, percent, amount, currency, min_extensions, gate_id, extension_id,
+		                         starts_at, ends_at, usage_limit, per_customer_limit, active, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.Name, code, p.Kind, p.Percent, p.Amount.Amount, priceCurrency(p.Amount), p.MinExtensions, p.GateID, p.ExtensionID,
+		nullTime(p.StartsAt), nullTime(p.EndsAt), p.UsageLimit, p.PerCustomerLimit, p.Active, time.Now().UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("create promotion: insert (name=%q, code=%q): %w", p.Name, p.Code, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("create promotion: last insert id (name=%q): %w", p.Name, err)
+	}
+	return int(id), nil
+}
+
+// GetPromotions lists every promotion, newest first.
+func (r *PromotionRepo) GetPromotions() ([]models.Promotion, error) {
+	q, args := promotionSelect(`1 = 1 ORDER BY p.id DESC`)
+	return r.queryPromotions("get promotions", q, args)
+}
+
+// GetAutomaticPromotions lists the promotions without a code that are
+// switched on. Whether they can be used right now is up to
+// models.Promotion.Available.
+func (r *PromotionRepo) GetAutomaticPromotions() ([]models.Promotion, error) {
+	q, args := promotionSelect(`p.code IS NULL AND p.active = 1 ORDER BY p.id`)
+	return r.queryPromotions("get automatic promotions", q, args)
+}
+
+func (r *PromotionRepo) queryPromotions(action, q string, args []any) ([]models.Promotion, error) {
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: query: %w", action, err)
+	}
+	defer rows.Close()
+
+	var promotions []models.Promotion
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", action, err)
+		}
+		promotions = append(promotions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate: %w", action, err)
+	}
+	return promotions, nil
+}
+
+// GetPromotionByID returns sql.ErrNoRows when there is no such promotion.
+func (r *PromotionRepo) GetPromotionByID(id int) (models.Promotion, error) {
+	q, args := promotionSelect(`p.id = ?`)
+	p, err := scanPromotion(r.db.QueryRow(q, append(args, id)...))
+	if err != nil {
+		return p, fmt.Errorf("get promotion (id=%d): %w", id, err)
+	}
+	return p, nil
+}
+
+// GetPromotionByCode finds a promotion by the code a customer entered, in any
+// case. It returns sql.ErrNoRows when there is no such code.
+func (r *PromotionRepo) GetPromotionByCode(code string) (models.Promotion, error) {
+	code = models.NormalizePromotionCode(code)
+	q, args := promotionSelect(`p.code = ?`)
+	p, err := scanPromotion(r.db.QueryRow(q, append(args, code)...))
+	if err != nil {
+		return p, fmt.Errorf("get promotion (code=%q): %w", code, err)
+	}
+	return p, nil
+}
+
+// SetPromotionActive switches a promotion on or off.
+func (r *PromotionRepo) SetPromotionActive(id int, active bool) error {
+	res, err := r.db.Exec(`UPDATE promotions SET active = ? WHERE id = ?`, active, id)
+	if err != nil {
+		return fmt.Errorf("set promotion active (id=%d, active=%t): %w", id, active, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("set promotion active (id=%d): %w", id, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// CustomerUses counts the orders email has placed with a promotion.
+func (r *PromotionRepo) CustomerUses(promotionID int, email string) (int, error) {
+	if email == "" {
+		return 0, nil
+	}
+	return customerUses(r.db, promotionID, email, 0)
+}
+
+// customerUses counts the orders, other than exceptOrderID, email has placed
+// with a promotion.
+func customerUses(q interface {
+	QueryRow(string, ...any) *sql.Row
+}, promotionID int, email string, exceptOrderID int) (int, error) {
+	uses, args := promotionUsesFilter()
+	var n int
+	err := q.QueryRow(
+		`SELECT COUNT(DISTINCT d.order_id) FROM order_discounts d
+		   JOIN orders o ON o.id = d.order_id
+		  WHERE d.promotion_id = ? AND o.id != ? AND LOWER(o.customer_email) = LOWER(?) AND `+uses,
+		append([]any{promotionID, exceptOrderID, email}, args...)...,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count customer uses of promotion (id=%d, email=%q): %w", promotionID, email, err)
+	}
+	return n, nil
+}
+
+// SetCartCode puts a promotion code on a cart in place of any it had, with
+// the email the customer gave for it.
+func (r *PromotionRepo) SetCartCode(cartID string, promotionID int, email string) error {
+	_, err := r.db.Exec(
+		`INSERT INTO cart_promotion_codes (cart_id, promotion_id, email, created_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (cart_id) DO UPDATE SET promotion_id = excluded.promotion_id, email = excluded.email, created_at = excluded.created_at`,
+		cartID, promotionID, strings.TrimSpace(email), time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("set cart promotion code (cartID=%s, promotionID=%d): %w", cartID, promotionID, err)
+	}
+	return nil
+}
+
+// GetCartCode returns the promotion whose code the cart entered and the
+// email given with it. found is false when it has none.
+func (r *PromotionRepo) GetCartCode(cartID string) (promotion models.Promotion, email string, found bool, err error) {
+	var promotionID int
+	err = r.db.QueryRow(`SELECT promotion_id, email FROM cart_promotion_codes WHERE cart_id = ?`, cartID).Scan(&promotionID, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Promotion{}, "", false, nil
+	}
+	if err != nil {
+		return models.Promotion{}, "", false, fmt.Errorf("get cart promotion code (cartID=%s): %w", cartID, err)
+	}
+	promotion, err = r.GetPromotionByID(promotionID)
+	if err != nil {
+		return models.Promotion{}, "", false, fmt.Errorf("get cart promotion code (cartID=%s): %w", cartID, err)
+	}
+	return promotion, email, true, nil
+}
+
+// RemoveCartCode takes the promotion code off a cart.
+func (r *PromotionRepo) RemoveCartCode(cartID string) error {
+	if _, err := r.db.Exec(`DELETE FROM cart_promotion_codes WHERE cart_id = ?`, cartID); err != nil {
+		return fmt.Errorf("remove cart promotion code (cartID=%s): %w", cartID, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/repos"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromotionCodesAndLimits(t *testing.T) {
+	db := openTestDB(t)
+	promotions := NewPromotionRepo(db)
+	orders := NewOrderRepo(db)
+	carts := NewCartRepo(db, NewProductRepo(db))
+	carts.SetPromotionRepo(promotions)
+
+	id, err := promotions.CreatePromotion(models.Promotion{
+		Name: "Spring sale", Code: "SPRING", Kind: models.PromotionPercentOff, Percent: 10,
+		UsageLimit: 2, PerCustomerLimit: 1, Active: true,
+	})
+	require.NoError(t, err)
+	_, err = promotions.CreatePromotion(models.Promotion{Name: "Free delivery", Kind: models.PromotionFreeShipping, Active: true})
+	require.NoError(t, err)
+
+	spring, err := promotions.GetPromotionByCode(" spring ")
+	require.NoError(t, err, "codes are found in any case")
+	require.Equal(t, id, spring.ID)
+	_, err = promotions.GetPromotionByCode("AUTUMN")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	now := time.Now()
+	_, err = carts.SaveCart(models.Cart{ID: "cart", CreatedAt: now, LastUpdatedAt: now})
+	require.NoError(t, err)
+	insertTestCartItem(t, carts, "cart", "1-1", 1)
+	require.NoError(t, promotions.SetCartCode("cart", id, "a@example.com"))
+
+	cart, _, err := carts.GetCartByID("cart")
+	require.NoError(t, err)
+	require.Equal(t, "SPRING", cart.PromoCode)
+	require.Len(t, cart.Discounts, 2)
+	require.True(t, cart.FreeShipping())
+
+	// a, then b, use the code up
+	orderID, err := orders.New(cart)
+	require.NoError(t, err)
+	discounts, err := orders.GetOrderDiscounts(orderID)
+	require.NoError(t, err)
+	require.Equal(t, cart.Discounts, discounts, "the promotions are recorded on the order")
+
+	_, err = orders.New(cart)
+	require.ErrorIs(t, err, repos.ErrPromotionUnavailable, "a has used their one")
+
+	cart.CustomerEmail = "b@example.com"
+	_, err = orders.New(cart)
+	require.NoError(t, err)
+
+	spring, err = promotions.GetPromotionByID(id)
+	require.NoError(t, err)
+	require.Equal(t, 2, spring.Uses)
+	cart.CustomerEmail = "c@example.com"
+	_, err = orders.New(cart)
+	require.ErrorIs(t, err, repos.ErrPromotionUnavailable, "the code is used up")
+
+	cart, _, err = carts.GetCartByID("cart")
+	require.NoError(t, err)
+	require.Len(t, cart.Discounts, 1, "a used up code is not applied")
+	require.Len(t, cart.Warnings, 1)
+	require.Equal(t, models.CartWarningPromoCode, cart.Warnings[0].Kind)
+
+	// canceled orders give their use back
+	require.NoError(t, orders.UpdateStatus(orderID, models.OrderStatusCanceled, models.OrderActorCheckout, ""))
+	spring, err = promotions.GetPromotionByID(id)
+	require.NoError(t, err)
+	require.Equal(t, 1, spring.Uses)
+
+	require.NoError(t, promotions.RemoveCartCode("cart"))
+	cart, _, err = carts.GetCartByID("cart")
+	require.NoError(t, err)
+	require.Empty(t, cart.PromoCode)
+}
@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/seanomeara96/gates/models"
+)
+
+// RefundRepo records refunds issued through the payment provider.
+type RefundRepo struct {
+	db *sql.DB
+}
+
+func NewRefundRepo(db *sql.DB) *RefundRepo {
+	return &RefundRepo{db}
+}
+
+// Create stores a refund and its lines, returning the refund's id.
+func (r *RefundRepo) Create(refund models.Refund) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("create refund: begin transaction (order_id=%d): %w", refund.OrderID, err)
+	}
+
+	res, err := tx.Exec(
+		`INSERT INTO refunds (order_id, provider, provider_ref, amount, currency, restocked, actor, note)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		refund.OrderID, refund.Provider, refund.ProviderRef, refund.Amount.Amount, refund.Amount.Currency,
+		refund.Restocked, refund.Actor, refund.Note,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("create refund: insert (order_id=%d, provider_ref=%s): %w", refund.OrderID, refund.ProviderRef, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("create refund: get last insert id (order_id=%d): %w", refund.OrderID, err)
+	}
+
+	for _, line := range refund.Lines {
+		if _, err := tx.Exec(
+			`INSERT INTO refund_lines (refund_id, order_item_id, product_id, qty, amount) VALUES (?, ?, ?, ?, ?)`,
+			id, line.OrderItemID, line.ProductID, line.Qty, line.Amount.Amount,
+		); err != nil {
+			_ = tx.Rollback()
+			return 0, fmt.Errorf("create refund: insert line (refund_id=%d, order_item_id=%d, product_id=%d): %w", id, line.OrderItemID, line.ProductID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("create refund: commit transaction (order_id=%d): %w", refund.OrderID, err)
+	}
+	return int(id), nil
+}
+
+// GetByOrderID returns an order's refunds with their lines, oldest first.
+func (r *RefundRepo) GetByOrderID(orderID int) ([]models.Refund, error) {
+	rows, err := r.db.Query(
+		`SELECT id, order_id, provider, provider_ref, amount, currency, restocked, actor, note, created_at
+		   FROM refunds
+		  WHERE order_id = ?
+		  ORDER BY id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get refunds: query (order_id=%d): %w", orderID, err)
+	}
+	defer rows.Close()
+
+	var refunds []models.Refund
+	index := map[int]int{}
+	for rows.Next() {
+		var refund models.Refund
+		if err := rows.Scan(
+			&refund.ID, &refund.OrderID, &refund.Provider, &refund.ProviderRef, &refund.Amount.Amount, &refund.Amount.Currency,
+			&refund.Restocked, &refund.Actor, &refund.Note, &refund.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("get refunds: scan row (order_id=%d): %w", orderID, err)
+		}
+		index[refund.ID] = len(refunds)
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get refunds: iterate rows (order_id=%d): %w", orderID, err)
+	}
+
+	lineRows, err := r.db.Query(
+		`SELECT l.refund_id, l.order_item_id, l.product_id, l.qty, l.amount, r.currency
+		   FROM refund_lines l
+		   JOIN refunds r ON r.id = l.refund_id
+		  WHERE r.order_id = ?
+		  ORDER BY l.id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get refunds: query lines (order_id=%d): %w", orderID, err)
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var refundID int
+		var line models.RefundLine
+		if err := lineRows.Scan(&refundID, &line.OrderItemID, &line.ProductID, &line.Qty, &line.Amount.Amount, &line.Amount.Currency); err != nil {
+			return nil, fmt.Errorf("get refunds: scan line (order_id=%d): %w", orderID, err)
+		}
+		if i, found := index[refundID]; found {
+			refunds[i].Lines = append(refunds[i].Lines, line)
+		}
+	}
+	if err := lineRows.Err(); err != nil {
+		return nil, fmt.Errorf("get refunds: iterate lines (order_id=%d): %w", orderID, err)
+	}
+	return refunds, nil
+}
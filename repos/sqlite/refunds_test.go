@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAndGetRefunds(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewRefundRepo(db)
+	orderID := insertTestOrder(t, db, 1, 2, 3)
+
+	items, err := NewOrderRepo(db).GetOrderItems(orderID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.NotEmpty(t, items[0].ID)
+
+	components := models.RefundableComponents(items, nil, nil)
+	require.Len(t, components, 1)
+	require.Equal(t, 6, components[0].Refundable)
+
+	_, err = repo.Create(models.Refund{
+		OrderID:     orderID,
+		Provider:    "fake",
+		ProviderRef: "re_1",
+		Amount:      models.EUR(20),
+		Restocked:   true,
+		Actor:       "admin:test",
+		Note:        sql.NullString{String: "damaged", Valid: true},
+		Lines:       []models.RefundLine{{OrderItemID: components[0].OrderItemID, ProductID: 1, Qty: 2, Amount: models.EUR(20)}},
+	})
+	require.NoError(t, err)
+
+	refunds, err := repo.GetByOrderID(orderID)
+	require.NoError(t, err)
+	require.Len(t, refunds, 1)
+	require.Equal(t, models.EUR(20), refunds[0].Amount)
+	require.True(t, refunds[0].Restocked)
+	require.Equal(t, "damaged", refunds[0].Note.String)
+	require.Len(t, refunds[0].Lines, 1)
+	require.Equal(t, 2, refunds[0].Lines[0].Qty)
+
+	components = models.RefundableComponents(items, nil, refunds)
+	require.Equal(t, 4, components[0].Refundable)
+	require.Equal(t, models.EUR(60), models.OrderTotal(items))
+	require.Equal(t, models.EUR(20), models.RefundedTotal(refunds))
+}
@@ -0,0 +1,189 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/seanomeara96/gates/models"
+)
+
+// ShippingRepo stores the zones delivered to, their rate tables and how each
+// cart chose to get its order.
+type ShippingRepo struct {
+	db *sql.DB
+}
+
+func NewShippingRepo(db *sql.DB) *ShippingRepo {
+	return &ShippingRepo{db}
+}
+
+// GetShippingZones returns every zone with its rates, in the order they were
+// added. Rates are ordered by service, then band.
+func (r *ShippingRepo) GetShippingZones() ([]models.ShippingZone, error) {
+	rows, err := r.db.Query(`SELECT id, name, countries, free_over, currency FROM shipping_zones ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("get shipping zones: query: %w", err)
+	}
+	defer rows.Close()
+
+	var zones []models.ShippingZone
+	index := map[int]int{}
+	for rows.Next() {
+		var zone models.ShippingZone
+		var countries string
+		if err := rows.Scan(&zone.ID, &zone.Name, &countries, &zone.FreeOver.Amount, &zone.FreeOver.Currency); err != nil {
+			return nil, fmt.Errorf("get shipping zones: scan zone: %w", err)
+		}
+		zone.Countries = strings.Split(countries, ",")
+		index[zone.ID] = len(zones)
+		zones = append(zones, zone)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get shipping zones: iterate zones: %w", err)
+	}
+
+	rates, err := r.db.Query(
+		`SELECT id, zone_id, service, basis, min_value, max_value, price, currency
+		   FROM shipping_rates
+		  ORDER BY zone_id, MIN(id) OVER (PARTITION BY zone_id, service), min_value`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get shipping zones: query rates: %w", err)
+	}
+	defer rates.Close()
+	for rates.Next() {
+		var rate models.ShippingRate
+		if err := rates.Scan(&rate.ID, &rate.ZoneID, &rate.Service, &rate.Basis, &rate.Min, &rate.Max, &rate.Price.Amount, &rate.Price.Currency); err != nil {
+			return nil, fmt.Errorf("get shipping zones: scan rate: %w", err)
+		}
+		if i, ok := index[rate.ZoneID]; ok {
+			zones[i].Rates = append(zones[i].Rates, rate)
+		}
+	}
+	if err := rates.Err(); err != nil {
+		return nil, fmt.Errorf("get shipping zones: iterate rates: %w", err)
+	}
+	return zones, nil
+}
+
+// CreateShippingZone adds a zone without rates and returns its id.
+func (r *ShippingRepo) CreateShippingZone(zone models.ShippingZone) (int, error) {
+	if err := zone.Validate(); err != nil {
+		return 0, fmt.Errorf("create shipping zone: %w", err)
+	}
+	res, err := r.db.Exec(
+		`INSERT INTO shipping_zones (name, countries, free_over, currency) VALUES (?, ?, ?, ?)`,
+		zone.Name, strings.Join(zone.Countries, ","), zone.FreeOver.Amount, priceCurrency(zone.FreeOver),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("create shipping zone (name=%q): %w", zone.Name, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("create shipping zone (name=%q): get last insert id: %w", zone.Name, err)
+	}
+	return int(id), nil
+}
+
+// UpdateShippingZone saves a zone's name, countries and free shipping
+// threshold. Its rates are changed on their own.
+func (r *ShippingRepo) UpdateShippingZone(zone models.ShippingZone) error {
+	if err := zone.Validate(); err != nil {
+		return fmt.Errorf("update shipping zone (id=%d): %w", zone.ID, err)
+	}
+	res, err := r.db.Exec(
+		`UPDATE shipping_zones SET name = ?, countries = ?, free_over = ?, currency = ? WHERE id = ?`,
+		zone.Name, strings.Join(zone.Countries, ","), zone.FreeOver.Amount, priceCurrency(zone.FreeOver), zone.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update shipping zone (id=%d): %w", zone.ID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("update shipping zone (id=%d): %w", zone.ID, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// DeleteShippingZone removes a zone and its rates. Carts that chose it are
+// given the first option left when they are next read.
+func (r *ShippingRepo) DeleteShippingZone(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("delete shipping zone: begin transaction (id=%d): %w", id, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM shipping_rates WHERE zone_id = ?`, id); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("delete shipping zone: delete rates (id=%d): %w", id, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM shipping_zones WHERE id = ?`, id); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("delete shipping zone (id=%d): %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("delete shipping zone: commit transaction (id=%d): %w", id, err)
+	}
+	return nil
+}
+
+// AddShippingRate adds a band to a zone's rate table and returns its id.
+func (r *ShippingRepo) AddShippingRate(rate models.ShippingRate) (int, error) {
+	if err := rate.Validate(); err != nil {
+		return 0, fmt.Errorf("add shipping rate (zone_id=%d): %w", rate.ZoneID, err)
+	}
+	res, err := r.db.Exec(
+		`INSERT INTO shipping_rates (zone_id, service, basis, min_value, max_value, price, currency)
+		 SELECT id, ?, ?, ?, ?, ?, ? FROM shipping_zones WHERE id = ?`,
+		strings.TrimSpace(rate.Service), rate.Basis, rate.Min, rate.Max, rate.Price.Amount, priceCurrency(rate.Price), rate.ZoneID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("add shipping rate (zone_id=%d): %w", rate.ZoneID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return 0, fmt.Errorf("add shipping rate: zone not found (zone_id=%d): %w", rate.ZoneID, sql.ErrNoRows)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("add shipping rate (zone_id=%d): get last insert id: %w", rate.ZoneID, err)
+	}
+	return int(id), nil
+}
+
+// DeleteShippingRate removes a band from a rate table.
+func (r *ShippingRepo) DeleteShippingRate(id int) error {
+	if _, err := r.db.Exec(`DELETE FROM shipping_rates WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete shipping rate (id=%d): %w", id, err)
+	}
+	return nil
+}
+
+// SetCartShipping records the option the cart chose.
+func (r *ShippingRepo) SetCartShipping(cartID string, option models.ShippingOption) error {
+	_, err := r.db.Exec(
+		`INSERT INTO cart_shipping (cart_id, method, zone_id, service) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (cart_id) DO UPDATE SET method = excluded.method, zone_id = excluded.zone_id, service = excluded.service`,
+		cartID, option.Method, sql.NullInt64{Int64: int64(option.ZoneID), Valid: option.ZoneID != 0}, option.Service,
+	)
+	if err != nil {
+		return fmt.Errorf("set cart shipping (cartID=%s, option=%q): %w", cartID, option.Key(), err)
+	}
+	return nil
+}
+
+// GetCartShipping returns the key of the option the cart chose, empty when
+// it has not chosen one.
+func (r *ShippingRepo) GetCartShipping(cartID string) (string, error) {
+	var option models.ShippingOption
+	var zoneID sql.NullInt64
+	err := r.db.QueryRow(`SELECT method, zone_id, service FROM cart_shipping WHERE cart_id = ?`, cartID).Scan(&option.Method, &zoneID, &option.Service)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get cart shipping (cartID=%s): %w", cartID, err)
+	}
+	option.ZoneID = int(zoneID.Int64)
+	return option.Key(), nil
+}
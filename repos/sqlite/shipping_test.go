@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShippingZonesAndRates(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewShippingRepo(db)
+
+	zones, err := repo.GetShippingZones()
+	require.NoError(t, err)
+	require.Len(t, zones, 3, "Ireland, the UK and the EU are seeded")
+	require.Equal(t, []string{"IE"}, zones[0].Countries)
+	require.NotEmpty(t, zones[0].Rates)
+
+	id, err := repo.CreateShippingZone(models.ShippingZone{Name: "Switzerland", Countries: []string{"CH"}})
+	require.NoError(t, err)
+	_, err = repo.AddShippingRate(models.ShippingRate{ZoneID: id, Service: "Express", Basis: models.ShippingByWeight, Price: models.EUR(4000)})
+	require.NoError(t, err)
+	_, err = repo.AddShippingRate(models.ShippingRate{ZoneID: id, Service: "Standard", Basis: models.ShippingByWeight, Min: 5000, Price: models.EUR(3000)})
+	require.NoError(t, err)
+	standard, err := repo.AddShippingRate(models.ShippingRate{ZoneID: id, Service: "Standard", Basis: models.ShippingByWeight, Max: 5000, Price: models.EUR(2000)})
+	require.NoError(t, err)
+	_, err = repo.AddShippingRate(models.ShippingRate{ZoneID: 999, Service: "Standard", Basis: models.ShippingByWeight, Price: models.EUR(2000)})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, repo.UpdateShippingZone(models.ShippingZone{ID: id, Name: "Switzerland", Countries: []string{"CH", "LI"}, FreeOver: models.EUR(50000)}))
+	require.ErrorIs(t, repo.UpdateShippingZone(models.ShippingZone{ID: 999, Name: "Nowhere", Countries: []string{"AQ"}}), sql.ErrNoRows)
+
+	zones, err = repo.GetShippingZones()
+	require.NoError(t, err)
+	swiss := zones[len(zones)-1]
+	require.Equal(t, []string{"CH", "LI"}, swiss.Countries)
+	require.Equal(t, models.EUR(50000), swiss.FreeOver)
+	require.Len(t, swiss.Rates, 3)
+	require.Equal(t, "Express", swiss.Rates[0].Service, "services stay in the order they were added")
+	require.Equal(t, standard, swiss.Rates[1].ID, "bands in order")
+
+	require.NoError(t, repo.DeleteShippingRate(standard))
+	require.NoError(t, repo.DeleteShippingZone(id))
+	zones, err = repo.GetShippingZones()
+	require.NoError(t, err)
+	require.Len(t, zones, 3)
+	var rates int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM shipping_rates WHERE zone_id = ?`, id).Scan(&rates))
+	require.Zero(t, rates, "the zone's rates go with it")
+}
+
+func TestCartShippingAndOrders(t *testing.T) {
+	db := openTestDB(t)
+	shipping := NewShippingRepo(db)
+	orders := NewOrderRepo(db)
+	carts := NewCartRepo(db, NewProductRepo(db))
+	carts.SetShippingRepo(shipping, "Unit 1, Main Street")
+
+	now := time.Now()
+	_, err := carts.SaveCart(models.Cart{ID: "cart", CreatedAt: now, LastUpdatedAt: now})
+	require.NoError(t, err)
+	insertTestCartItem(t, carts, "cart", "1-1", 1)
+
+	cart, _, err := carts.GetCartByID("cart")
+	require.NoError(t, err)
+	require.Equal(t, 1, cart.Shipping.ZoneID, "Ireland until another is chosen")
+	require.False(t, cart.Shipping.Price.IsZero())
+	require.Equal(t, models.ShippingCollect, cart.ShippingOptions[len(cart.ShippingOptions)-1].Method)
+
+	key, err := shipping.GetCartShipping("cart")
+	require.NoError(t, err)
+	require.Empty(t, key)
+
+	delivered, err := orders.New(cart)
+	require.NoError(t, err)
+	order, err := orders.GetOrderByID(delivered)
+	require.NoError(t, err)
+	require.Equal(t, models.ShippingDelivery, order.ShippingMethod)
+	require.Equal(t, cart.Shipping.Name, order.ShippingName)
+	require.Equal(t, cart.Shipping.Price, order.ShippingCost)
+
+	require.NoError(t, shipping.SetCartShipping("cart", models.CollectOption("Unit 1, Main Street")))
+	key, err = shipping.GetCartShipping("cart")
+	require.NoError(t, err)
+	require.Equal(t, "collect", key)
+
+	cart, _, err = carts.GetCartByID("cart")
+	require.NoError(t, err)
+	require.Equal(t, models.ShippingCollect, cart.Shipping.Method)
+	require.Equal(t, cart.AmountDue(), cart.Total())
+
+	collected, err := orders.New(cart)
+	require.NoError(t, err)
+	for _, status := range []models.OrderStatus{models.OrderStatusProcessing, models.OrderStatusAwaitingFulfillment} {
+		require.NoError(t, orders.UpdateStatus(collected, status, "admin", ""))
+	}
+	var transitionErr *models.StatusTransitionError
+	err = orders.UpdateStatus(collected, models.OrderStatusAwaitingShipment, "admin", "")
+	require.True(t, errors.As(err, &transitionErr), "a collected order is not shipped")
+	require.NoError(t, orders.UpdateStatus(collected, models.OrderStatusAwaitingPickup, "admin", ""))
+}
@@ -0,0 +1,469 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/repos"
+)
+
+// StockRepo keeps products.inventory_level and the stock_movements ledger in
+// step. Every change to an inventory level goes through here so the ledger
+// always explains the current level.
+type StockRepo struct {
+	db *sql.DB
+}
+
+func NewStockRepo(db *sql.DB) *StockRepo {
+	return &StockRepo{db}
+}
+
+// CommitOrder takes stock for every component of a paid order. The whole order
+// is taken in one transaction: if any product without backorders would go
+// below zero nothing is taken and the returned error wraps
+// repos.ErrInsufficientStock.
+//
+// Any active reservations for the order are marked converted. Committing an
+// order that already has sale movements is a no-op, so webhook retries are
+// safe.
+func (r *StockRepo) CommitOrder(orderID int) ([]models.StockMovement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("commit order stock: begin transaction (order_id=%d): %w", orderID, err)
+	}
+
+	var existing int
+	err = tx.QueryRow(
+		`SELECT COUNT(*) FROM stock_movements WHERE order_id = ? AND reason = ?`,
+		orderID, models.StockMovementSale,
+	).Scan(&existing)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("commit order stock: count existing sale movements (order_id=%d): %w", orderID, err)
+	}
+	if existing > 0 {
+		_ = tx.Rollback()
+		return nil, nil
+	}
+
+	productIDs, quantities, err := orderQuantities(tx, orderID)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("commit order stock: %w", err)
+	}
+
+	var movements []models.StockMovement
+	for _, productID := range productIDs {
+		qty := quantities[productID]
+		if qty <= 0 {
+			continue
+		}
+
+		res, err := tx.Exec(
+			`UPDATE products SET inventory_level = inventory_level - ?
+			  WHERE id = ? AND (inventory_level >= ? OR allow_backorder = 1)`,
+			qty, productID, qty,
+		)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("commit order stock: decrement inventory (order_id=%d, product_id=%d, qty=%d): %w", orderID, productID, qty, err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("commit order stock: rows affected (order_id=%d, product_id=%d): %w", orderID, productID, err)
+		}
+		if affected == 0 {
+			var available int
+			err := tx.QueryRow(`SELECT inventory_level FROM products WHERE id = ?`, productID).Scan(&available)
+			_ = tx.Rollback()
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("commit order stock: product no longer exists (order_id=%d, product_id=%d)", orderID, productID)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("commit order stock: get inventory level (order_id=%d, product_id=%d): %w", orderID, productID, err)
+			}
+			return nil, fmt.Errorf("commit order stock (order_id=%d, product_id=%d, requested=%d, available=%d): %w", orderID, productID, qty, available, repos.ErrInsufficientStock)
+		}
+
+		movement, err := insertStockMovement(tx, productID, sql.NullInt64{Int64: int64(orderID), Valid: true}, -qty, models.StockMovementSale)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("commit order stock: %w", err)
+		}
+		movements = append(movements, movement)
+	}
+
+	// the sale movements above now account for the held stock
+	if _, err := tx.Exec(
+		`UPDATE stock_reservations SET status = ? WHERE order_id = ? AND status = ?`,
+		models.ReservationConverted, orderID, models.ReservationActive,
+	); err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("commit order stock: convert reservations (order_id=%d): %w", orderID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("commit order stock: commit transaction (order_id=%d): %w", orderID, err)
+	}
+	return movements, nil
+}
+
+// RestockOrder puts back whatever stock an order still holds according to the
+// ledger and releases any reservation it still has. Orders that were never
+// committed, or were already restocked, have nothing outstanding, so calling it
+// twice is harmless.
+func (r *StockRepo) RestockOrder(orderID int, reason models.StockMovementReason) ([]models.StockMovement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("restock order: begin transaction (order_id=%d): %w", orderID, err)
+	}
+
+	outstanding, productIDs, err := outstandingStock(tx, orderID)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("restock order: %w", err)
+	}
+
+	var movements []models.StockMovement
+	for _, productID := range productIDs {
+		movement, err := returnStock(tx, orderID, productID, outstanding[productID], reason)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("restock order: %w", err)
+		}
+		movements = append(movements, movement)
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE stock_reservations SET status = ? WHERE order_id = ? AND status = ?`,
+		models.ReservationReleased, orderID, models.ReservationActive,
+	); err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("restock order: release reservations (order_id=%d): %w", orderID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("restock order: commit transaction (order_id=%d): %w", orderID, err)
+	}
+	return movements, nil
+}
+
+// RestockProducts puts back qtys, keyed by product id, of the stock an order
+// holds, e.g. for the items returned with a partial refund. Quantities are
+// capped at what the order still holds, so stock is never returned twice.
+func (r *StockRepo) RestockProducts(orderID int, qtys map[int]int, reason models.StockMovementReason) ([]models.StockMovement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("restock products: begin transaction (order_id=%d): %w", orderID, err)
+	}
+
+	outstanding, productIDs, err := outstandingStock(tx, orderID)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("restock products: %w", err)
+	}
+
+	var movements []models.StockMovement
+	for _, productID := range productIDs {
+		qty := min(qtys[productID], outstanding[productID])
+		if qty <= 0 {
+			continue
+		}
+		movement, err := returnStock(tx, orderID, productID, qty, reason)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("restock products: %w", err)
+		}
+		movements = append(movements, movement)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("restock products: commit transaction (order_id=%d): %w", orderID, err)
+	}
+	return movements, nil
+}
+
+// outstandingStock returns how much of each product an order has taken and
+// not yet given back, along with the product ids in order.
+func outstandingStock(tx *sql.Tx, orderID int) (map[int]int, []int, error) {
+	rows, err := tx.Query(
+		`SELECT product_id, SUM(delta)
+		   FROM stock_movements
+		  WHERE order_id = ?
+		  GROUP BY product_id
+		 HAVING SUM(delta) < 0
+		  ORDER BY product_id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query outstanding movements (order_id=%d): %w", orderID, err)
+	}
+	defer rows.Close()
+
+	outstanding := map[int]int{}
+	var productIDs []int
+	for rows.Next() {
+		var productID, net int
+		if err := rows.Scan(&productID, &net); err != nil {
+			return nil, nil, fmt.Errorf("scan outstanding movement (order_id=%d): %w", orderID, err)
+		}
+		outstanding[productID] = -net
+		productIDs = append(productIDs, productID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterate outstanding movements (order_id=%d): %w", orderID, err)
+	}
+	return outstanding, productIDs, nil
+}
+
+func returnStock(tx *sql.Tx, orderID, productID, qty int, reason models.StockMovementReason) (models.StockMovement, error) {
+	// the product may have been deleted since the sale, the ledger row is
+	// still written so the order's history balances
+	if _, err := tx.Exec(`UPDATE products SET inventory_level = inventory_level + ? WHERE id = ?`, qty, productID); err != nil {
+		return models.StockMovement{}, fmt.Errorf("increment inventory (order_id=%d, product_id=%d, qty=%d): %w", orderID, productID, qty, err)
+	}
+	return insertStockMovement(tx, productID, sql.NullInt64{Int64: int64(orderID), Valid: true}, qty, reason)
+}
+
+// RecordAdjustment writes a ledger row for a manual change to a product's
+// inventory level. The level itself is saved with the rest of the product.
+func (r *StockRepo) RecordAdjustment(productID, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("record stock adjustment: begin transaction (product_id=%d): %w", productID, err)
+	}
+	if _, err := insertStockMovement(tx, productID, sql.NullInt64{}, delta, models.StockMovementAdjustment); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("record stock adjustment: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("record stock adjustment: commit transaction (product_id=%d): %w", productID, err)
+	}
+	return nil
+}
+
+// GetMovementsByOrderID returns an order's ledger rows, oldest first.
+func (r *StockRepo) GetMovementsByOrderID(orderID int) ([]models.StockMovement, error) {
+	rows, err := r.db.Query(
+		`SELECT id, product_id, order_id, delta, reason, created_at
+		   FROM stock_movements
+		  WHERE order_id = ?
+		  ORDER BY id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get stock movements: query (order_id=%d): %w", orderID, err)
+	}
+	defer rows.Close()
+
+	var movements []models.StockMovement
+	for rows.Next() {
+		var m models.StockMovement
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.OrderID, &m.Delta, &m.Reason, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("get stock movements: scan row (order_id=%d): %w", orderID, err)
+		}
+		movements = append(movements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get stock movements: iterate rows (order_id=%d): %w", orderID, err)
+	}
+	return movements, nil
+}
+
+// ReserveOrder holds stock for every component of an order until expiresAt.
+// Each product is checked against its inventory level less the other live
+// reservations on it, inside the insert itself, so two checkouts racing for
+// the last unit cannot both succeed. If any product falls short nothing is
+// reserved and the returned error wraps repos.ErrInsufficientStock.
+func (r *StockRepo) ReserveOrder(orderID int, expiresAt time.Time) ([]models.StockReservation, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("reserve order stock: begin transaction (order_id=%d): %w", orderID, err)
+	}
+
+	productIDs, quantities, err := orderQuantities(tx, orderID)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("reserve order stock: %w", err)
+	}
+
+	now := time.Now().UTC()
+	expiresAt = expiresAt.UTC()
+
+	var reservations []models.StockReservation
+	for _, productID := range productIDs {
+		qty := quantities[productID]
+		if qty <= 0 {
+			continue
+		}
+
+		res, err := tx.Exec(
+			`INSERT INTO stock_reservations (order_id, product_id, qty, status, expires_at, created_at)
+			 SELECT ?, p.id, ?, ?, ?, ?
+			   FROM products p
+			  WHERE p.id = ?
+			    AND (p.allow_backorder = 1 OR p.inventory_level - (
+			        SELECT COALESCE(SUM(qty), 0) FROM stock_reservations
+			         WHERE product_id = p.id AND status = ? AND expires_at > ?
+			    ) >= ?)`,
+			orderID, qty, models.ReservationActive, expiresAt, now,
+			productID,
+			models.ReservationActive, now,
+			qty,
+		)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("reserve order stock: insert reservation (order_id=%d, product_id=%d, qty=%d): %w", orderID, productID, qty, err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("reserve order stock: rows affected (order_id=%d, product_id=%d): %w", orderID, productID, err)
+		}
+		if affected == 0 {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("reserve order stock (order_id=%d, product_id=%d, requested=%d): %w", orderID, productID, qty, repos.ErrInsufficientStock)
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("reserve order stock: get last insert id (order_id=%d, product_id=%d): %w", orderID, productID, err)
+		}
+		reservations = append(reservations, models.StockReservation{
+			ID:        int(id),
+			OrderID:   orderID,
+			ProductID: productID,
+			Qty:       qty,
+			Status:    models.ReservationActive,
+			ExpiresAt: expiresAt,
+			CreatedAt: now,
+		})
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("reserve order stock: commit transaction (order_id=%d): %w", orderID, err)
+	}
+	return reservations, nil
+}
+
+// ReleaseExpiredReservations marks every active reservation that expired
+// before now as released and returns the IDs of the orders they belonged to.
+func (r *StockRepo) ReleaseExpiredReservations(now time.Time) ([]int, error) {
+	rows, err := r.db.Query(
+		`UPDATE stock_reservations SET status = ?
+		  WHERE status = ? AND expires_at <= ?
+		 RETURNING order_id`,
+		models.ReservationReleased, models.ReservationActive, now.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("release expired reservations: update: %w", err)
+	}
+	defer rows.Close()
+
+	seen := map[int]bool{}
+	var orderIDs []int
+	for rows.Next() {
+		var orderID int
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("release expired reservations: scan order id: %w", err)
+		}
+		if !seen[orderID] {
+			seen[orderID] = true
+			orderIDs = append(orderIDs, orderID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("release expired reservations: iterate rows: %w", err)
+	}
+	return orderIDs, nil
+}
+
+// AvailableQty returns how many units of a product can still be sold: its
+// inventory level less live reservations. allowBackorder reports whether the
+// product may be sold past zero anyway.
+func (r *StockRepo) AvailableQty(productID int) (available int, allowBackorder bool, err error) {
+	now := time.Now().UTC()
+	err = r.db.QueryRow(
+		`SELECT p.inventory_level - (
+		        SELECT COALESCE(SUM(qty), 0) FROM stock_reservations
+		         WHERE product_id = p.id AND status = ? AND expires_at > ?
+		        ), p.allow_backorder
+		   FROM products p
+		  WHERE p.id = ?`,
+		models.ReservationActive, now, productID,
+	).Scan(&available, &allowBackorder)
+	if err != nil {
+		return 0, false, fmt.Errorf("available qty (product_id=%d): %w", productID, err)
+	}
+	return available, allowBackorder, nil
+}
+
+// orderQuantities totals how many of each product an order needs. Quantities
+// are product_qty multiplied by the order item's quantity, since a cart item
+// can hold several of the same bundle.
+func orderQuantities(tx *sql.Tx, orderID int) ([]int, map[int]int, error) {
+	rows, err := tx.Query(
+		`SELECT c.product_id, SUM(c.product_qty * i.item_quantity)
+		   FROM order_item_components c
+		   INNER JOIN order_items i ON i.id = c.order_item_id
+		  WHERE c.order_id = ?
+		  GROUP BY c.product_id
+		  ORDER BY c.product_id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query order quantities (order_id=%d): %w", orderID, err)
+	}
+	defer rows.Close()
+
+	quantities := map[int]int{}
+	var productIDs []int
+	for rows.Next() {
+		var productID, qty int
+		if err := rows.Scan(&productID, &qty); err != nil {
+			return nil, nil, fmt.Errorf("scan order quantity (order_id=%d): %w", orderID, err)
+		}
+		quantities[productID] = qty
+		productIDs = append(productIDs, productID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterate order quantities (order_id=%d): %w", orderID, err)
+	}
+	return productIDs, quantities, nil
+}
+
+func insertStockMovement(tx *sql.Tx, productID int, orderID sql.NullInt64, delta int, reason models.StockMovementReason) (models.StockMovement, error) {
+	movement := models.StockMovement{
+		ProductID: productID,
+		OrderID:   orderID,
+		Delta:     delta,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	res, err := tx.Exec(
+		`INSERT INTO stock_movements (product_id, order_id, delta, reason, created_at) VALUES (?, ?, ?, ?, ?)`,
+		movement.ProductID, movement.OrderID, movement.Delta, movement.Reason, movement.CreatedAt,
+	)
+	if err != nil {
+		return models.StockMovement{}, fmt.Errorf("insert stock movement (product_id=%d, delta=%d, reason=%s): %w", productID, delta, reason, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.StockMovement{}, fmt.Errorf("insert stock movement: get last insert id (product_id=%d): %w", productID, err)
+	}
+	movement.ID = int(id)
+	return movement, nil
+}
@@ -0,0 +1,180 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/seanomeara96/gates/migrations"
+	"github.com/seanomeara96/gates/models"
+	"github.com/seanomeara96/gates/repos"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, migrations.Run(db))
+	return db
+}
+
+func insertTestOrder(t *testing.T, db *sql.DB, productID, componentQty, itemQty int) int {
+	t.Helper()
+	res, err := db.Exec(`INSERT INTO orders (cart_id) VALUES ('cart')`)
+	require.NoError(t, err)
+	orderID, err := res.LastInsertId()
+	require.NoError(t, err)
+
+	res, err = db.Exec(`INSERT INTO order_items (order_id, item_name, item_quantity) VALUES (?, 'item', ?)`, orderID, itemQty)
+	require.NoError(t, err)
+	itemID, err := res.LastInsertId()
+	require.NoError(t, err)
+
+	_, err = db.Exec(
+		`INSERT INTO order_item_components (order_id, order_item_id, product_id, product_name, product_price, product_qty) VALUES (?, ?, ?, 'component', 10, ?)`,
+		orderID, itemID, productID, componentQty,
+	)
+	require.NoError(t, err)
+	return int(orderID)
+}
+
+func inventoryLevel(t *testing.T, db *sql.DB, productID int) int {
+	t.Helper()
+	var level int
+	require.NoError(t, db.QueryRow(`SELECT inventory_level FROM products WHERE id = ?`, productID).Scan(&level))
+	return level
+}
+
+func TestCommitAndRestockOrder(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewStockRepo(db)
+	_, err := db.Exec(`UPDATE products SET inventory_level = 10 WHERE id = 1`)
+	require.NoError(t, err)
+
+	orderID := insertTestOrder(t, db, 1, 2, 3)
+
+	movements, err := repo.CommitOrder(orderID)
+	require.NoError(t, err)
+	require.Len(t, movements, 1)
+	require.Equal(t, -6, movements[0].Delta)
+	require.Equal(t, 4, inventoryLevel(t, db, 1))
+
+	movements, err = repo.CommitOrder(orderID)
+	require.NoError(t, err)
+	require.Empty(t, movements, "committing twice takes stock once")
+	require.Equal(t, 4, inventoryLevel(t, db, 1))
+
+	movements, err = repo.RestockOrder(orderID, models.StockMovementRefund)
+	require.NoError(t, err)
+	require.Len(t, movements, 1)
+	require.Equal(t, 10, inventoryLevel(t, db, 1))
+
+	movements, err = repo.RestockOrder(orderID, models.StockMovementCancel)
+	require.NoError(t, err)
+	require.Empty(t, movements, "restocking twice returns stock once")
+	require.Equal(t, 10, inventoryLevel(t, db, 1))
+}
+
+func TestRestockProductsCapsAtOutstanding(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewStockRepo(db)
+	_, err := db.Exec(`UPDATE products SET inventory_level = 10 WHERE id = 1`)
+	require.NoError(t, err)
+
+	orderID := insertTestOrder(t, db, 1, 2, 3)
+	_, err = repo.CommitOrder(orderID)
+	require.NoError(t, err)
+	require.Equal(t, 4, inventoryLevel(t, db, 1))
+
+	movements, err := repo.RestockProducts(orderID, map[int]int{1: 2}, models.StockMovementRefund)
+	require.NoError(t, err)
+	require.Len(t, movements, 1)
+	require.Equal(t, 2, movements[0].Delta)
+	require.Equal(t, 6, inventoryLevel(t, db, 1))
+
+	movements, err = repo.RestockProducts(orderID, map[int]int{1: 10, 2: 1}, models.StockMovementRefund)
+	require.NoError(t, err)
+	require.Len(t, movements, 1, "products the order never took are skipped")
+	require.Equal(t, 4, movements[0].Delta)
+	require.Equal(t, 10, inventoryLevel(t, db, 1))
+}
+
+func TestCommitOrderInsufficientStock(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewStockRepo(db)
+	_, err := db.Exec(`UPDATE products SET inventory_level = 1, allow_backorder = 0 WHERE id = 1`)
+	require.NoError(t, err)
+
+	orderID := insertTestOrder(t, db, 1, 2, 1)
+
+	_, err = repo.CommitOrder(orderID)
+	require.ErrorIs(t, err, repos.ErrInsufficientStock)
+	require.Equal(t, 1, inventoryLevel(t, db, 1))
+
+	movements, err := repo.GetMovementsByOrderID(orderID)
+	require.NoError(t, err)
+	require.Empty(t, movements)
+
+	_, err = db.Exec(`UPDATE products SET allow_backorder = 1 WHERE id = 1`)
+	require.NoError(t, err)
+
+	_, err = repo.CommitOrder(orderID)
+	require.NoError(t, err)
+	require.Equal(t, -1, inventoryLevel(t, db, 1))
+}
+
+func TestReserveOrder(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewStockRepo(db)
+	_, err := db.Exec(`UPDATE products SET inventory_level = 1, allow_backorder = 0 WHERE id = 1`)
+	require.NoError(t, err)
+
+	first := insertTestOrder(t, db, 1, 1, 1)
+	second := insertTestOrder(t, db, 1, 1, 1)
+
+	reservations, err := repo.ReserveOrder(first, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, reservations, 1)
+
+	_, err = repo.ReserveOrder(second, time.Now().Add(time.Hour))
+	require.ErrorIs(t, err, repos.ErrInsufficientStock, "the last unit is already held")
+
+	available, _, err := repo.AvailableQty(1)
+	require.NoError(t, err)
+	require.Equal(t, 0, available)
+
+	_, err = repo.CommitOrder(first)
+	require.NoError(t, err)
+	require.Equal(t, 0, inventoryLevel(t, db, 1))
+
+	var status models.ReservationStatus
+	require.NoError(t, db.QueryRow(`SELECT status FROM stock_reservations WHERE order_id = ?`, first).Scan(&status))
+	require.Equal(t, models.ReservationConverted, status)
+}
+
+func TestReleaseExpiredReservations(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewStockRepo(db)
+	_, err := db.Exec(`UPDATE products SET inventory_level = 1, allow_backorder = 0 WHERE id = 1`)
+	require.NoError(t, err)
+
+	expired := insertTestOrder(t, db, 1, 1, 1)
+	_, err = repo.ReserveOrder(expired, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	available, _, err := repo.AvailableQty(1)
+	require.NoError(t, err)
+	require.Equal(t, 1, available, "expired reservations do not hold stock")
+
+	orderIDs, err := repo.ReleaseExpiredReservations(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, []int{expired}, orderIDs)
+
+	orderIDs, err = repo.ReleaseExpiredReservations(time.Now())
+	require.NoError(t, err)
+	require.Empty(t, orderIDs)
+}
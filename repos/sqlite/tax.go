@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/seanomeara96/gates/models"
+)
+
+// TaxRepo stores the VAT rates each country charges on each tax class.
+type TaxRepo struct {
+	db *sql.DB
+}
+
+func NewTaxRepo(db *sql.DB) *TaxRepo {
+	return &TaxRepo{db}
+}
+
+// GetVATRates returns every rate, the home country's first, then by country
+// and class.
+func (r *TaxRepo) GetVATRates() (models.VATRates, error) {
+	rows, err := r.db.Query(
+		`SELECT country, tax_class, rate FROM tax_rates
+		  ORDER BY country != ?, country, CASE tax_class WHEN 'standard' THEN 0 WHEN 'reduced' THEN 1 ELSE 2 END`,
+		models.HomeCountry,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get vat rates: query: %w", err)
+	}
+	defer rows.Close()
+
+	var rates models.VATRates
+	for rows.Next() {
+		var rate models.VATRate
+		if err := rows.Scan(&rate.Country, &rate.Class, &rate.Rate); err != nil {
+			return nil, fmt.Errorf("get vat rates: scan: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get vat rates: iterate: %w", err)
+	}
+	return rates, nil
+}
+
+// SetVATRate adds the rate, or changes it when the country already has one
+// for the class.
+func (r *TaxRepo) SetVATRate(rate models.VATRate) error {
+	if err := rate.Validate(); err != nil {
+		return fmt.Errorf("set vat rate: %w", err)
+	}
+	_, err := r.db.Exec(
+		`INSERT INTO tax_rates (country, tax_class, rate) VALUES (?, ?, ?)
+		 ON CONFLICT (country, tax_class) DO UPDATE SET rate = excluded.rate`,
+		rate.Country, rate.Class, rate.Rate,
+	)
+	if err != nil {
+		return fmt.Errorf("set vat rate (country=%s, class=%s): %w", rate.Country, rate.Class, err)
+	}
+	return nil
+}
+
+// DeleteVATRate removes a country's rate for class, so the home country's
+// applies instead.
+func (r *TaxRepo) DeleteVATRate(country string, class models.TaxClass) error {
+	if _, err := r.db.Exec(`DELETE FROM tax_rates WHERE country = ? AND tax_class = ?`, country, class); err != nil {
+		return fmt.Errorf("delete vat rate (country=%s, class=%s): %w", country, class, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVATRates(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTaxRepo(db)
+
+	rates, err := repo.GetVATRates()
+	require.NoError(t, err)
+	require.Equal(t, models.VATRate{Country: "IE", Class: models.TaxStandard, Rate: 2300}, rates[0], "the home country comes first")
+	require.Equal(t, 1900, rates.Lookup("DE", models.TaxStandard))
+
+	require.NoError(t, repo.SetVATRate(models.VATRate{Country: "DE", Class: models.TaxReduced, Rate: 700}))
+	require.NoError(t, repo.SetVATRate(models.VATRate{Country: "DE", Class: models.TaxStandard, Rate: 1600}))
+	require.Error(t, repo.SetVATRate(models.VATRate{Country: "Germany", Class: models.TaxStandard, Rate: 1900}))
+	rates, err = repo.GetVATRates()
+	require.NoError(t, err)
+	require.Equal(t, 700, rates.Lookup("DE", models.TaxReduced))
+	require.Equal(t, 1600, rates.Lookup("DE", models.TaxStandard))
+
+	require.NoError(t, repo.DeleteVATRate("DE", models.TaxReduced))
+	rates, err = repo.GetVATRates()
+	require.NoError(t, err)
+	require.Equal(t, 1350, rates.Lookup("DE", models.TaxReduced), "back to Ireland's")
+}
+
+func TestOrderVAT(t *testing.T) {
+	db := openTestDB(t)
+	orders := NewOrderRepo(db)
+	taxes := NewTaxRepo(db)
+	carts := NewCartRepo(db, NewProductRepo(db))
+	carts.SetShippingRepo(NewShippingRepo(db), "Unit 1, Main Street")
+	carts.SetTaxRepo(taxes)
+
+	now := time.Now()
+	_, err := carts.SaveCart(models.Cart{ID: "cart", CreatedAt: now, LastUpdatedAt: now})
+	require.NoError(t, err)
+	insertTestCartItem(t, carts, "cart", "1-1", 1)
+
+	cart, _, err := carts.GetCartByID("cart")
+	require.NoError(t, err)
+	line := cart.Items[0].Components[0].Tax
+	require.Equal(t, 2300, line.Rate)
+	require.Equal(t, cart.Items[0].Components[0].Price, line.Gross)
+	require.Equal(t, 2300, cart.ShippingTax.Rate)
+	require.False(t, cart.VAT().IsZero())
+
+	id, err := orders.New(cart)
+	require.NoError(t, err)
+	order, err := orders.GetOrderByID(id)
+	require.NoError(t, err)
+	require.Equal(t, "IE", order.TaxCountry)
+	require.Equal(t, cart.ShippingTax, order.ShippingTax)
+	items, err := orders.GetOrderItems(id)
+	require.NoError(t, err)
+	require.Equal(t, line, items[0].Components[0].Tax)
+
+	lines, err := orders.GetVATLines(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Empty(t, lines, "only paid orders are exported")
+
+	rates, err := taxes.GetVATRates()
+	require.NoError(t, err)
+	require.NoError(t, orders.SetTaxCountry(id, "DE", rates))
+	require.NoError(t, orders.UpdateStatus(id, models.OrderStatusProcessing, "admin", ""))
+
+	order, err = orders.GetOrderByID(id)
+	require.NoError(t, err)
+	require.Equal(t, "DE", order.TaxCountry)
+	require.Equal(t, 1900, order.ShippingTax.Rate)
+	require.Equal(t, cart.ShippingTax.Gross, order.ShippingTax.Gross, "what was paid does not change")
+
+	lines, err = orders.GetVATLines(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, lines, 2, "the gate and its shipping")
+	require.Equal(t, id, lines[0].OrderID)
+	require.Equal(t, "DE", lines[0].TaxCountry)
+	require.Equal(t, models.NewTaxLine(models.TaxStandard, 1900, line.Gross), lines[0].Tax)
+	require.Equal(t, "Shipping: "+order.ShippingName, lines[1].Description)
+
+	lines, err = orders.GetVATLines(now.Add(24*time.Hour), time.Time{})
+	require.NoError(t, err)
+	require.Empty(t, lines)
+}
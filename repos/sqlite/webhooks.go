@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/seanomeara96/gates/models"
+)
+
+// WebhookEventRepo stores every verified payment webhook so deliveries can be
+// deduplicated and failed ones inspected and replayed.
+type WebhookEventRepo struct {
+	db *sql.DB
+}
+
+func NewWebhookEventRepo(db *sql.DB) *WebhookEventRepo {
+	return &WebhookEventRepo{db}
+}
+
+const webhookEventColumns = `id, provider, event_id, type, order_id, payload, status, error, attempts, received_at, processed_at`
+
+func scanWebhookEvent(row scannable) (models.WebhookEvent, error) {
+	var e models.WebhookEvent
+	err := row.Scan(
+		&e.ID, &e.Provider, &e.EventID, &e.Type, &e.OrderID, &e.Payload,
+		&e.Status, &e.Error, &e.Attempts, &e.ReceivedAt, &e.ProcessedAt,
+	)
+	return e, err
+}
+
+// Record stores a delivery and returns the stored event. Redelivering an
+// event the provider already sent returns the existing row unchanged, so
+// callers can tell a retry from its status. orderID may be 0 when the event
+// is not about an order.
+func (r *WebhookEventRepo) Record(provider, eventID, eventType string, orderID int, payload []byte) (models.WebhookEvent, error) {
+	order := sql.NullInt64{Int64: int64(orderID), Valid: orderID != 0}
+	_, err := r.db.Exec(
+		`INSERT INTO webhook_events (provider, event_id, type, order_id, payload, status)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (provider, event_id) DO NOTHING`,
+		provider, eventID, eventType, order, string(payload), models.WebhookEventReceived,
+	)
+	if err != nil {
+		return models.WebhookEvent{}, fmt.Errorf("record webhook event: insert (provider=%s, event_id=%s): %w", provider, eventID, err)
+	}
+
+	e, err := scanWebhookEvent(r.db.QueryRow(
+		`SELECT `+webhookEventColumns+` FROM webhook_events WHERE provider = ? AND event_id = ?`,
+		provider, eventID,
+	))
+	if err != nil {
+		return models.WebhookEvent{}, fmt.Errorf("record webhook event: select (provider=%s, event_id=%s): %w", provider, eventID, err)
+	}
+	return e, nil
+}
+
+// Claim marks an event as processing if it is currently in one of from,
+// counting the attempt. It reports false when another delivery got there
+// first or the event is already settled, in which case the caller must not
+// apply it.
+func (r *WebhookEventRepo) Claim(id int, from ...models.WebhookEventStatus) (bool, error) {
+	if len(from) == 0 {
+		return false, nil
+	}
+	placeholders := make([]string, len(from))
+	args := []any{models.WebhookEventProcessing, id}
+	for i, status := range from {
+		placeholders[i] = "?"
+		args = append(args, status)
+	}
+
+	res, err := r.db.Exec(
+		`UPDATE webhook_events SET status = ?, attempts = attempts + 1
+		 WHERE id = ? AND status IN (`+strings.Join(placeholders, ", ")+`)`,
+		args...,
+	)
+	if err != nil {
+		return false, fmt.Errorf("claim webhook event (id=%d): %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim webhook event: rows affected (id=%d): %w", id, err)
+	}
+	return n == 1, nil
+}
+
+// Finish records the outcome of applying a claimed event. errMsg is stored
+// for failed events and cleared otherwise.
+func (r *WebhookEventRepo) Finish(id int, status models.WebhookEventStatus, errMsg string) error {
+	_, err := r.db.Exec(
+		`UPDATE webhook_events SET status = ?, error = ?, processed_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		status, sql.NullString{String: errMsg, Valid: errMsg != ""}, id,
+	)
+	if err != nil {
+		return fmt.Errorf("finish webhook event (id=%d, status=%s): %w", id, status, err)
+	}
+	return nil
+}
+
+func (r *WebhookEventRepo) GetByID(id int) (models.WebhookEvent, error) {
+	e, err := scanWebhookEvent(r.db.QueryRow(`SELECT `+webhookEventColumns+` FROM webhook_events WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebhookEvent{}, fmt.Errorf("get webhook event: not found (id=%d): %w", id, err)
+		}
+		return models.WebhookEvent{}, fmt.Errorf("get webhook event (id=%d): %w", id, err)
+	}
+	return e, nil
+}
+
+// List returns the most recent events first, optionally only those in status.
+func (r *WebhookEventRepo) List(status models.WebhookEventStatus, limit int) ([]models.WebhookEvent, error) {
+	query := `SELECT ` + webhookEventColumns + ` FROM webhook_events`
+	var args []any
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook events (status=%s): %w", status, err)
+	}
+	defer rows.Close()
+
+	var events []models.WebhookEvent
+	for rows.Next() {
+		e, err := scanWebhookEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list webhook events: scan row: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list webhook events: iterate rows: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/seanomeara96/gates/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookEventRecordIsIdempotent(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewWebhookEventRepo(db)
+
+	first, err := repo.Record("fake", "evt_1", "payment_intent.succeeded", 7, []byte(`{"id":"evt_1"}`))
+	require.NoError(t, err)
+	require.Equal(t, models.WebhookEventReceived, first.Status)
+	require.Equal(t, int64(7), first.OrderID.Int64)
+
+	claimed, err := repo.Claim(first.ID, models.WebhookEventReceived, models.WebhookEventFailed)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.NoError(t, repo.Finish(first.ID, models.WebhookEventProcessed, ""))
+
+	again, err := repo.Record("fake", "evt_1", "payment_intent.succeeded", 7, []byte(`{"id":"evt_1"}`))
+	require.NoError(t, err)
+	require.Equal(t, first.ID, again.ID)
+	require.Equal(t, models.WebhookEventProcessed, again.Status)
+	require.Equal(t, 1, again.Attempts)
+	require.True(t, again.ProcessedAt.Valid)
+
+	claimed, err = repo.Claim(again.ID, models.WebhookEventReceived, models.WebhookEventFailed)
+	require.NoError(t, err)
+	require.False(t, claimed, "a processed event must not be applied twice")
+
+	other, err := repo.Record("stripe", "evt_1", "payment_intent.succeeded", 0, []byte(`{}`))
+	require.NoError(t, err)
+	require.NotEqual(t, first.ID, other.ID, "event ids are scoped to the provider")
+	require.False(t, other.OrderID.Valid)
+}
+
+func TestWebhookEventFailedCanBeRetried(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewWebhookEventRepo(db)
+
+	e, err := repo.Record("fake", "evt_1", "payment_intent.succeeded", 1, []byte(`{}`))
+	require.NoError(t, err)
+	claimed, err := repo.Claim(e.ID, models.WebhookEventReceived, models.WebhookEventFailed)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	claimed, err = repo.Claim(e.ID, models.WebhookEventReceived, models.WebhookEventFailed)
+	require.NoError(t, err)
+	require.False(t, claimed, "only one delivery may process an event at a time")
+
+	require.NoError(t, repo.Finish(e.ID, models.WebhookEventFailed, "boom"))
+	failed, err := repo.List(models.WebhookEventFailed, 10)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	require.Equal(t, "boom", failed[0].Error.String)
+	require.True(t, failed[0].Replayable())
+
+	claimed, err = repo.Claim(e.ID, models.WebhookEventReplayable...)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.NoError(t, repo.Finish(e.ID, models.WebhookEventProcessed, ""))
+
+	e, err = repo.GetByID(e.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, e.Attempts)
+	require.False(t, e.Error.Valid)
+	require.False(t, e.Replayable())
+}
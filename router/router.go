@@ -43,6 +43,9 @@ func DefaultRouter(cfg *config.Config) (*Router, error) {
 	if err != nil {
 		return nil, err
 	}
+	// the catalog may have changed while the server was down, e.g. by a
+	// migration
+	go r.handler.RefreshBundleLookup()
 
 	r.middleware = append(r.middleware, r.handler.GetCartFromRequest) // last one added gets called first?
 	r.middleware = append(r.middleware, func(next handlers.CustomHandleFunc) handlers.CustomHandleFunc {
@@ -76,8 +79,18 @@ func DefaultRouter(cfg *config.Config) (*Router, error) {
 	r.Get("/checkout", r.handler.GetCheckoutPage)
 	r.Get("/cart", r.handler.GetCartPage)
 	r.Get("/success", r.handler.GetSuccessPage)
-
-	r.Post("/webhook", r.handler.StripeWebhook)
+	r.Get("/build/project", r.handler.GetProjectPage)
+	r.Get("/build/project/opening", r.handler.GetProjectOpeningRow)
+	r.Get("/bundles/{slug}", r.handler.GetBundlePage)
+
+	r.Post("/webhook", r.handler.PaymentWebhook)
+	if cfg.PaymentProvider == config.PaymentProviderFake {
+		r.Get("/fake-checkout/{id}", r.handler.GetFakeCheckoutPage)
+		r.Post("/fake-checkout/{id}/pay", r.handler.PayFakeCheckout)
+		r.Post("/fake-checkout/{id}/cancel", r.handler.CancelFakeCheckout)
+		r.Post("/fake-checkout/{id}/decline", r.handler.DeclineFakeCheckout)
+		r.Post("/fake-checkout/{id}/expire", r.handler.ExpireFakeCheckout)
+	}
 
 	/*
 		admin endpoints
@@ -92,24 +105,64 @@ func DefaultRouter(cfg *config.Config) (*Router, error) {
 		user actions
 	*/
 	r.Post("/build", r.handler.BuildBundle)
+	r.Post("/build/project", r.handler.BuildProject)
+	r.Post("/build/project/quote", r.handler.QuoteProject)
+	r.Post("/bundles", r.handler.SaveBundle)
 	r.Post("/contact", r.handler.ProcessContactFormSumbission)
 
 	/*
 		admin actions
 	*/
+	r.Get("/admin/products/new", r.handler.MustBeAdmin(r.handler.GetNewProductForm))
+	r.Post("/admin/products", r.handler.MustBeAdmin(r.handler.CreateProduct))
+	r.Get("/admin/products/edit/{id}", r.handler.MustBeAdmin(r.handler.GetEditProductForm))
 	r.Put("/admin/products/{id}", r.handler.MustBeAdmin(r.handler.UpdateProduct))
+	r.Delete("/admin/products/delete/{id}", r.handler.MustBeAdmin(r.handler.DeleteProduct))
 	r.Put("/admin/orders/{id}", r.handler.MustBeAdmin(r.handler.UpdateOrder))
 	r.Put("/admin/orders/update-status/{id}", r.handler.MustBeAdmin(r.handler.UpdateOrderStatus))
+	r.Get("/admin/orders/view/{id}", r.handler.MustBeAdmin(r.handler.GetOrderView))
+	r.Put("/admin/orders/view/{id}/status", r.handler.MustBeAdmin(r.handler.UpdateOrderStatusFromView))
+	r.Post("/admin/orders/view/{id}/refund", r.handler.MustBeAdmin(r.handler.RefundOrder))
 	r.Get("/admin/orders/refresh-stripe/{id}", r.handler.MustBeAdmin(r.handler.FetchOrderDetailsFromStripe))
+	r.Get("/admin/webhooks", r.handler.MustBeAdmin(r.handler.GetWebhookEventsPage))
+	r.Post("/admin/webhooks/{id}/replay", r.handler.MustBeAdmin(r.handler.ReplayWebhookEvent))
+	r.Get("/admin/demand", r.handler.MustBeAdmin(r.handler.GetDemandPage))
+	r.Get("/admin/promotions", r.handler.MustBeAdmin(r.handler.GetPromotionsPage))
+	r.Post("/admin/promotions", r.handler.MustBeAdmin(r.handler.CreatePromotion))
+	r.Post("/admin/promotions/{id}/active", r.handler.MustBeAdmin(r.handler.SetPromotionActive))
+	r.Get("/admin/shipping", r.handler.MustBeAdmin(r.handler.GetShippingPage))
+	r.Post("/admin/shipping/zones", r.handler.MustBeAdmin(r.handler.CreateShippingZone))
+	r.Post("/admin/shipping/zones/{id}", r.handler.MustBeAdmin(r.handler.UpdateShippingZone))
+	r.Post("/admin/shipping/zones/{id}/delete", r.handler.MustBeAdmin(r.handler.DeleteShippingZone))
+	r.Post("/admin/shipping/zones/{id}/rates", r.handler.MustBeAdmin(r.handler.AddShippingRate))
+	r.Post("/admin/shipping/rates/{id}/delete", r.handler.MustBeAdmin(r.handler.DeleteShippingRate))
+	r.Get("/admin/tax", r.handler.MustBeAdmin(r.handler.GetTaxPage))
+	r.Post("/admin/tax/rates", r.handler.MustBeAdmin(r.handler.SetVATRate))
+	r.Post("/admin/tax/rates/delete", r.handler.MustBeAdmin(r.handler.DeleteVATRate))
+	r.Get("/admin/orders/export/vat", r.handler.MustBeAdmin(r.handler.ExportOrderVAT))
 	if cfg.Mode == config.Development {
 		r.Handle("/test", r.handler.Test)
 		r.Get("/cart/json", r.handler.GetCartJSON)
 	}
 
 	r.Post("/cart/add", r.handler.AddItemToCart)
+	r.Post("/cart/add/project", r.handler.AddProjectToCart)
+	r.Post("/bundles/{slug}/cart", r.handler.AddSavedBundleToCart)
 	r.Post("/cart/item/{mode}", r.handler.AdjustCartItemQty)
 	r.Delete("/cart/item", r.handler.RemoveItemFromCart)
 	r.Post("/cart/clear", r.handler.ClearItemsFromCart)
+	r.Post("/cart/promo-code", r.handler.ApplyPromoCode)
+	r.Delete("/cart/promo-code", r.handler.RemovePromoCode)
+	r.Post("/cart/shipping", r.handler.ChooseShipping)
+
+	/*
+		json api, carts are picked by token rather than cookie
+	*/
+	for _, route := range r.handler.APIRoutes() {
+		r.mux.HandleFunc(route.Method+" "+handlers.APIPrefix+route.Path, r.handler.ServeAPI(route))
+	}
+	r.mux.HandleFunc("GET "+handlers.APIPrefix+"/openapi.json", r.handler.ServeOpenAPI)
+	r.mux.HandleFunc(handlers.APIPrefix+"/", r.handler.APINotFound)
 
 	// Initialize assets dir.
 	assetsDirPath := "/assets/"
@@ -155,5 +208,5 @@ func (r *Router) Put(path string, fn handlers.CustomHandleFunc) {
 }
 
 func (r *Router) Delete(path string, fn handlers.CustomHandleFunc) {
-	r.Handle("DELETE"+path, fn)
+	r.Handle("DELETE "+path, fn)
 }
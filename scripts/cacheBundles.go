@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/seanomeara96/gates/config"
+	"github.com/seanomeara96/gates/handlers"
+)
+
+const usage = `usage: go run scripts/cacheBundles.go
+
+Works out the bundle builder's bundles for every whole centimetre the catalog
+supports and stores them in the bundle lookup, which /build answers from. The
+server does this itself whenever the catalog changes through the admin; run
+this after changing products or compatibles any other way. Reads the same
+.env as the server.
+`
+
+func run() error {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	h, err := handlers.DefaultHandler(cfg)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	n, err := h.PrecomputeBundles()
+	if err != nil {
+		return err
+	}
+	log.Printf("stored %d bundles", n)
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1 +0,0 @@
-// bundle components schema goes here
\ No newline at end of file
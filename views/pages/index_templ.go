@@ -50,12 +50,84 @@ func Home(props HomeProps) templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<main><section class=\" bg-gray-100 mx-auto relative flex flex-col md:flex-row items-center gap-4\"><style>\r\n      .hero-vid {\r\n        aspect-ratio:16/9;\r\n      }\r\n    @media(min-width: 750px){\r\n      .hero-vid {\r\n        aspect-ratio:9/9;\r\n      }\r\n    }\r\n    </style><video class=\"hero-vid h-full w-full md:w-1/4 object-cover\" src=\"https://replicate.delivery/xezq/cBCrM0QneJWfg0qsA5O9fZqnngEGIlU4e0iDbkQL1Lx2FIvRB/tmp74g77w30.mp4\" preload=\"auto\" autoplay=\"\" playsinline=\"\" webkit-playsinline=\"\" x5-playsinline=\"\" loop=\"\" muted></video><div class=\"px-4 pb-4\"><h1 class=\"text-2xl md:text-5xl font-bold mb-2\">Build Your Custom Pressure Gate</h1><p class=\"text-gray-600 mb-8\">Just enter your desired width and we'll sort the rest out for you.</p><form id=\"build-gate\" hx-post=\"/build\" hx-target=\"#build-results\" hx-indicator=\"#build-button\" hx-swap=\"outerHTML\"><label for=\"desired-width\" class=\"block mb-2\">Your desired Width in cm</label><div class=\"flex gap-4 items-center\"><input id=\"desired-width\" name=\"desired-width\" class=\"py-2 px-4 rounded\" placeholder=\"e.g. 100\" type=\"number\"><style>\r\n      .htmx-request #button-text {\r\ndisplay: none;\r\n      }\r\n\r\n    .htmx-request #spinner {\r\ndisplay: flex;\r\n    }\r\n    </style><button type=\"submit\" style=\"background-color: #271d16\" class=\"hover:bg-gray-700 text-white font-bold py-2 px-4 rounded relative\" id=\"build-button\"><span id=\"button-text\">Build Gate</span><div id=\"spinner\" class=\"hidden inset-0 flex items-center justify-center\"><div class=\"animate-spin h-6 w-6 border-4 border-gray-300 border-t-white rounded-full\"></div></div></button></div></form></div></section></main><div class=\"h-4\"></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<main><section class=\" bg-gray-100 mx-auto relative flex flex-col md:flex-row items-center gap-4\"><style>\n      .hero-vid {\n        aspect-ratio:16/9;\n      }\n    @media(min-width: 750px){\n      .hero-vid {\n        aspect-ratio:9/9;\n      }\n    }\n    </style><video class=\"hero-vid h-full w-full md:w-1/4 object-cover\" src=\"https://replicate.delivery/xezq/cBCrM0QneJWfg0qsA5O9fZqnngEGIlU4e0iDbkQL1Lx2FIvRB/tmp74g77w30.mp4\" preload=\"auto\" autoplay=\"\" playsinline=\"\" webkit-playsinline=\"\" x5-playsinline=\"\" loop=\"\" muted></video><div class=\"px-4 pb-4\"><h1 class=\"text-2xl md:text-5xl font-bold mb-2\">Build Your Custom Pressure Gate</h1><p class=\"text-gray-600 mb-8\">Just enter your desired width and we'll sort the rest out for you.</p><form id=\"build-gate\" hx-post=\"/build\" hx-target=\"#build-results\" hx-indicator=\"#build-button\" hx-swap=\"outerHTML\"><label for=\"desired-width\" class=\"block mb-2\">Your desired Width in cm</label><div class=\"flex gap-4 items-center\"><input id=\"desired-width\" name=\"desired-width\" class=\"py-2 px-4 rounded\" placeholder=\"e.g. 100\" type=\"number\"> <select id=\"mounting-type\" name=\"mounting-type\" class=\"py-2 px-4 rounded\" aria-label=\"Mounting\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			for _, mountingType := range models.MountingTypes {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<option value=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var3 string
+				templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(string(mountingType))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/pages/index.templ`, Line: 63, Col: 45}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var4 string
+				templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(mountingType.Label())
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/pages/index.templ`, Line: 63, Col: 70}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</option>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</select> <select id=\"goal\" name=\"goal\" class=\"py-2 px-4 rounded\" aria-label=\"Optimise for\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			for _, goal := range models.BundleGoals {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<option value=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var5 string
+				templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(string(goal))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/pages/index.templ`, Line: 68, Col: 37}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var6 string
+				templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(goal.Label())
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/pages/index.templ`, Line: 68, Col: 54}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</option>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "</select><style>\n      .htmx-request #button-text {\ndisplay: none;\n      }\n\n    .htmx-request #spinner {\ndisplay: flex;\n    }\n    </style><button type=\"submit\" style=\"background-color: #271d16\" class=\"hover:bg-gray-700 text-white font-bold py-2 px-4 rounded relative\" id=\"build-button\"><span id=\"button-text\">Build Gate</span><div id=\"spinner\" class=\"hidden inset-0 flex items-center justify-center\"><div class=\"animate-spin h-6 w-6 border-4 border-gray-300 border-t-white rounded-full\"></div></div></button></div></form><p class=\"mt-4 text-gray-600\">Need gates for more than one opening? <a href=\"/build/project\" class=\"underline\">Plan your whole house</a></p></div></section></main><div class=\"h-4\"></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 			/*probably better to cache the most commonly searched width*/
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<section id=\"build-results\" class=\"container mx-auto p-4\"><h2 class=\"text-4xl font-bold mb-4\">Bundles to fit: 80cm</h2><div id=\"build-results-content\" class=\"md:flex gap-4\"><div style=\"animation: fadeIn; border: 1px solid gray\" class=\"bg-white rounded-lg p-4 mb-8\"><h2 class=\"text-2xl font-bold mb-4\">BabyDan Premier True Pressure Fit Safety Gate and 1 extension. White</h2><ul><li>Total Bundle Price €83</li><li>Width: 77 - 83cm</li></ul><strong class=\"py-4 font-medium mt-4 block\">Bundle Includes:</strong><div class=\"flex flex-col\"><div class=\"px-2 mb-4 relative inline-block\"><div class=\"flex justify-between items-centerbg-white rounded-lg overflow-hidden shadow-md\"><a class=\"relative\" href=\"/gates/1\"><img class=\"h-full aspect-square max-w-16\" src=\"https://cdn11.bigcommerce.com/s-egiahb/images/stencil/640w/products/347/1003/baby-dan-premier-60114-white-no-child__43601.1559815258.jpg?c=2\" alt=\"Baby Safety Gate\"></a><div class=\"p-4\"><a href=\"/gates/1\"><h3 class=\"font-bold mb-2 text-xs md:text-base\">BabyDan Premier True Pressure Fit Safety Gate White</h3></a></div><span style=\"background-color: #683b1c\" class=\"p-4 z-10 text-white rounded text-xs md:text-base text-nowrap\">x 1</span></div></div><div class=\"px-2 mb-4 relative inline-block\"><div class=\"flex justify-between items-centerbg-white rounded-lg overflow-hidden shadow-md\"><a class=\"relative\" href=\"/extensions/5\"><img class=\"h-full aspect-square max-w-16\" src=\"https://cdn11.bigcommerce.com/s-egiahb/images/stencil/640w/products/347/1003/baby-dan-premier-60114-white-no-child__43601.1559815258.jpg?c=2\" alt=\"Baby Safety Gate\"></a><div class=\"p-4\"><a href=\"/gates/5\"><h3 class=\"font-bold mb-2 text-xs md:text-base\">BabyDan Premier Gate Extension Small White</h3></a></div><span style=\"background-color: #683b1c\" class=\"p-4 z-10 text-white rounded text-xs md:text-base text-nowrap\">x 1</span></div></div></div><form hx-trigger=\"submit\" hx-target=\"#cart-modal\" hx-swap=\"outerHTML\" hx-post=\"/cart/add\" class=\"flex justify-end\"><input type=\"hidden\" name=\"data\" value='{\"product_id\":1,\"qty\":1}'> <input type=\"hidden\" name=\"data\" value='{\"product_id\":5,\"qty\":1}'> <button class=\"hover:bg-gray-700 text-white font-bold py-2 px-4 rounded\" style=\"background-color: #683b1c\">Add Bundle To Cart</button></form></div><div style=\"animation: fadeIn; border: 1px solid gray\" class=\"bg-white rounded-lg p-4 mb-8\"><h2 class=\"text-2xl font-bold mb-4\">BabyDan Premier True Pressure Fit Safety Gate and 1 extension. Black</h2><ul><li>Total Bundle Price €83</li><li>Width: 77 - 83cm</li></ul><strong class=\"py-4 font-medium mt-4 block\">Bundle Includes:</strong><div class=\"flex flex-col\"><div class=\"px-2 mb-4 relative inline-block\"><div class=\"flex justify-between items-centerbg-white rounded-lg overflow-hidden shadow-md\"><a class=\"relative\" href=\"/gates/2\"><img class=\"h-full aspect-square max-w-16\" src=\"https://cdn11.bigcommerce.com/s-egiahb/images/stencil/640w/products/347/1003/baby-dan-premier-60114-white-no-child__43601.1559815258.jpg?c=2\" alt=\"Baby Safety Gate\"></a><div class=\"p-4\"><a href=\"/gates/2\"><h3 class=\"font-bold mb-2 text-xs md:text-base\">BabyDan Premier True Pressure Fit Safety Gate Black</h3></a></div><span style=\"background-color: #683b1c\" class=\"p-4 z-10 text-white rounded text-xs md:text-base text-nowrap\">x 1</span></div></div><div class=\"px-2 mb-4 relative inline-block\"><div class=\"flex justify-between items-centerbg-white rounded-lg overflow-hidden shadow-md\"><a class=\"relative\" href=\"/extensions/8\"><img class=\"h-full aspect-square max-w-16\" src=\"https://cdn11.bigcommerce.com/s-egiahb/images/stencil/640w/products/347/1003/baby-dan-premier-60114-white-no-child__43601.1559815258.jpg?c=2\" alt=\"Baby Safety Gate\"></a><div class=\"p-4\"><a href=\"/gates/8\"><h3 class=\"font-bold mb-2 text-xs md:text-base\">BabyDan Premier Gate Extension Small Black</h3></a></div><span style=\"background-color: #683b1c\" class=\"p-4 z-10 text-white rounded text-xs md:text-base text-nowrap\">x 1</span></div></div></div><form hx-trigger=\"submit\" hx-target=\"#cart-modal\" hx-swap=\"outerHTML\" hx-post=\"/cart/add\" class=\"flex justify-end\"><input type=\"hidden\" name=\"data\" value='{\"product_id\":2,\"qty\":1}'> <input type=\"hidden\" name=\"data\" value='{\"product_id\":8,\"qty\":1}'> <button class=\"hover:bg-gray-700 text-white font-bold py-2 px-4 rounded\" style=\"background-color: #683b1c\">Add Bundle To Cart</button></form></div></div></section><section class=\"bg-gray-100 py-16 px-4\"><div class=\"max-w-4xl mx-auto text-center\"><h2 class=\"text-3xl font-bold text-gray-800 mb-6\">How It Works</h2><p class=\"text-lg text-gray-600 mb-10\">Get a perfectly fitted baby safety gate in three easy steps.</p><div class=\"grid md:grid-cols-3 gap-8\"><div class=\"flex flex-col items-center\"><div class=\"w-20 h-20 bg-blue-500 text-white flex items-center justify-center text-3xl font-bold rounded-full\">1</div><h3 class=\"text-xl font-semibold mt-4\">Enter Your Measurement</h3><p class=\"text-gray-600 text-center mt-2\">Input the width of your space, and we'll calculate the perfect fit.</p><img src=\"https://replicate.delivery/xezq/Q5uCuUmYh2JvDl6KXCueRAp4CDjKIX5bgQrw1sBf4z4eWG0oA/tmpn6999ybx.jpg\" alt=\"Measuring a doorway\" class=\"mt-4 rounded-lg shadow-md w-32 h-32 object-cover\"></div><div class=\"flex flex-col items-center\"><div class=\"w-20 h-20 bg-blue-500 text-white flex items-center justify-center text-3xl font-bold rounded-full\">2</div><h3 class=\"text-xl font-semibold mt-4\">Get Your Custom Bundle</h3><p class=\"text-gray-600 text-center mt-2\">We’ll generate the ideal gate and extensions for a secure fit.</p><img src=\"https://replicate.delivery/xezq/kG3iAT0X1w4pCJORffSlcQtQX5QBE8Q2ZhmpQgqSOxkIODaUA/tmpzvgndxub.jpg\" alt=\"Gate bundle preview\" class=\"mt-4 rounded-lg shadow-md w-32 h-32 object-cover\"></div><div class=\"flex flex-col items-center\"><div class=\"w-20 h-20 bg-blue-500 text-white flex items-center justify-center text-3xl font-bold rounded-full\">3</div><h3 class=\"text-xl font-semibold mt-4\">Install with Ease</h3><p class=\"text-gray-600 text-center mt-2\">Follow our simple guide to set up your baby gate in minutes.</p><img src=\"https://replicate.delivery/xezq/Yl2EHiDeOrTkH6iUEYVfzm4WM8ryDdyUL9siip9P13e4S0woA/tmpuy5bxwcv.jpg\" alt=\"Installing the gate\" class=\"mt-4 rounded-lg shadow-md w-32 h-32 object-cover\"></div></div></div></section><div class=\"container my-4 mx-auto px-4 flex flex-wrap md:flex-nowrap gap-4\"><div class=\"\"><h2 class=\"text-3xl font-bold mb-4\">Featured Gates</h2><div class=\"flex flex-wrap md:flex-nowrap gap-4\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<section id=\"build-results\" class=\"container mx-auto p-4\"><h2 class=\"text-4xl font-bold mb-4\">Bundles to fit: 80cm</h2><div id=\"build-results-content\" class=\"md:flex gap-4\"><div style=\"animation: fadeIn; border: 1px solid gray\" class=\"bg-white rounded-lg p-4 mb-8\"><h2 class=\"text-2xl font-bold mb-4\">BabyDan Premier True Pressure Fit Safety Gate and 1 extension. White</h2><ul><li>Total Bundle Price €83</li><li>Width: 77 - 83cm</li></ul><strong class=\"py-4 font-medium mt-4 block\">Bundle Includes:</strong><div class=\"flex flex-col\"><div class=\"px-2 mb-4 relative inline-block\"><div class=\"flex justify-between items-centerbg-white rounded-lg overflow-hidden shadow-md\"><a class=\"relative\" href=\"/gates/1\"><img class=\"h-full aspect-square max-w-16\" src=\"https://cdn11.bigcommerce.com/s-egiahb/images/stencil/640w/products/347/1003/baby-dan-premier-60114-white-no-child__43601.1559815258.jpg?c=2\" alt=\"Baby Safety Gate\"></a><div class=\"p-4\"><a href=\"/gates/1\"><h3 class=\"font-bold mb-2 text-xs md:text-base\">BabyDan Premier True Pressure Fit Safety Gate White</h3></a></div><span style=\"background-color: #683b1c\" class=\"p-4 z-10 text-white rounded text-xs md:text-base text-nowrap\">x 1</span></div></div><div class=\"px-2 mb-4 relative inline-block\"><div class=\"flex justify-between items-centerbg-white rounded-lg overflow-hidden shadow-md\"><a class=\"relative\" href=\"/extensions/5\"><img class=\"h-full aspect-square max-w-16\" src=\"https://cdn11.bigcommerce.com/s-egiahb/images/stencil/640w/products/347/1003/baby-dan-premier-60114-white-no-child__43601.1559815258.jpg?c=2\" alt=\"Baby Safety Gate\"></a><div class=\"p-4\"><a href=\"/gates/5\"><h3 class=\"font-bold mb-2 text-xs md:text-base\">BabyDan Premier Gate Extension Small White</h3></a></div><span style=\"background-color: #683b1c\" class=\"p-4 z-10 text-white rounded text-xs md:text-base text-nowrap\">x 1</span></div></div></div><form hx-trigger=\"submit\" hx-target=\"#cart-modal\" hx-swap=\"outerHTML\" hx-post=\"/cart/add\" class=\"flex justify-end\"><input type=\"hidden\" name=\"product_id\" value=\"1\"> <input type=\"hidden\" name=\"qty\" value=\"1\"> <input type=\"hidden\" name=\"product_id\" value=\"5\"> <input type=\"hidden\" name=\"qty\" value=\"1\"> <button class=\"hover:bg-gray-700 text-white font-bold py-2 px-4 rounded\" style=\"background-color: #683b1c\">Add Bundle To Cart</button></form></div><div style=\"animation: fadeIn; border: 1px solid gray\" class=\"bg-white rounded-lg p-4 mb-8\"><h2 class=\"text-2xl font-bold mb-4\">BabyDan Premier True Pressure Fit Safety Gate and 1 extension. Black</h2><ul><li>Total Bundle Price €83</li><li>Width: 77 - 83cm</li></ul><strong class=\"py-4 font-medium mt-4 block\">Bundle Includes:</strong><div class=\"flex flex-col\"><div class=\"px-2 mb-4 relative inline-block\"><div class=\"flex justify-between items-centerbg-white rounded-lg overflow-hidden shadow-md\"><a class=\"relative\" href=\"/gates/2\"><img class=\"h-full aspect-square max-w-16\" src=\"https://cdn11.bigcommerce.com/s-egiahb/images/stencil/640w/products/347/1003/baby-dan-premier-60114-white-no-child__43601.1559815258.jpg?c=2\" alt=\"Baby Safety Gate\"></a><div class=\"p-4\"><a href=\"/gates/2\"><h3 class=\"font-bold mb-2 text-xs md:text-base\">BabyDan Premier True Pressure Fit Safety Gate Black</h3></a></div><span style=\"background-color: #683b1c\" class=\"p-4 z-10 text-white rounded text-xs md:text-base text-nowrap\">x 1</span></div></div><div class=\"px-2 mb-4 relative inline-block\"><div class=\"flex justify-between items-centerbg-white rounded-lg overflow-hidden shadow-md\"><a class=\"relative\" href=\"/extensions/8\"><img class=\"h-full aspect-square max-w-16\" src=\"https://cdn11.bigcommerce.com/s-egiahb/images/stencil/640w/products/347/1003/baby-dan-premier-60114-white-no-child__43601.1559815258.jpg?c=2\" alt=\"Baby Safety Gate\"></a><div class=\"p-4\"><a href=\"/gates/8\"><h3 class=\"font-bold mb-2 text-xs md:text-base\">BabyDan Premier Gate Extension Small Black</h3></a></div><span style=\"background-color: #683b1c\" class=\"p-4 z-10 text-white rounded text-xs md:text-base text-nowrap\">x 1</span></div></div></div><form hx-trigger=\"submit\" hx-target=\"#cart-modal\" hx-swap=\"outerHTML\" hx-post=\"/cart/add\" class=\"flex justify-end\"><input type=\"hidden\" name=\"product_id\" value=\"2\"> <input type=\"hidden\" name=\"qty\" value=\"1\"> <input type=\"hidden\" name=\"product_id\" value=\"8\"> <input type=\"hidden\" name=\"qty\" value=\"1\"> <button class=\"hover:bg-gray-700 text-white font-bold py-2 px-4 rounded\" style=\"background-color: #683b1c\">Add Bundle To Cart</button></form></div></div></section><section class=\"bg-gray-100 py-16 px-4\"><div class=\"max-w-4xl mx-auto text-center\"><h2 class=\"text-3xl font-bold text-gray-800 mb-6\">How It Works</h2><p class=\"text-lg text-gray-600 mb-10\">Get a perfectly fitted baby safety gate in three easy steps.</p><div class=\"grid md:grid-cols-3 gap-8\"><div class=\"flex flex-col items-center\"><div class=\"w-20 h-20 bg-blue-500 text-white flex items-center justify-center text-3xl font-bold rounded-full\">1</div><h3 class=\"text-xl font-semibold mt-4\">Enter Your Measurement</h3><p class=\"text-gray-600 text-center mt-2\">Input the width of your space, and we'll calculate the perfect fit.</p><img src=\"https://replicate.delivery/xezq/Q5uCuUmYh2JvDl6KXCueRAp4CDjKIX5bgQrw1sBf4z4eWG0oA/tmpn6999ybx.jpg\" alt=\"Measuring a doorway\" class=\"mt-4 rounded-lg shadow-md w-32 h-32 object-cover\"></div><div class=\"flex flex-col items-center\"><div class=\"w-20 h-20 bg-blue-500 text-white flex items-center justify-center text-3xl font-bold rounded-full\">2</div><h3 class=\"text-xl font-semibold mt-4\">Get Your Custom Bundle</h3><p class=\"text-gray-600 text-center mt-2\">We’ll generate the ideal gate and extensions for a secure fit.</p><img src=\"https://replicate.delivery/xezq/kG3iAT0X1w4pCJORffSlcQtQX5QBE8Q2ZhmpQgqSOxkIODaUA/tmpzvgndxub.jpg\" alt=\"Gate bundle preview\" class=\"mt-4 rounded-lg shadow-md w-32 h-32 object-cover\"></div><div class=\"flex flex-col items-center\"><div class=\"w-20 h-20 bg-blue-500 text-white flex items-center justify-center text-3xl font-bold rounded-full\">3</div><h3 class=\"text-xl font-semibold mt-4\">Install with Ease</h3><p class=\"text-gray-600 text-center mt-2\">Follow our simple guide to set up your baby gate in minutes.</p><img src=\"https://replicate.delivery/xezq/Yl2EHiDeOrTkH6iUEYVfzm4WM8ryDdyUL9siip9P13e4S0woA/tmpuy5bxwcv.jpg\" alt=\"Installing the gate\" class=\"mt-4 rounded-lg shadow-md w-32 h-32 object-cover\"></div></div></div></section><div class=\"container my-4 mx-auto px-4 flex flex-wrap md:flex-nowrap gap-4\"><div class=\"\"><h2 class=\"text-3xl font-bold mb-4\">Featured Gates</h2><div class=\"flex flex-wrap md:flex-nowrap gap-4\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -65,7 +137,7 @@ func Home(props HomeProps) templ.Component {
 					return templ_7745c5c3_Err
 				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</div></div><div class=\"\"><h2 class=\"text-3xl font-bold mb-4\">Featured Extensions</h2><div class=\"flex flex-wrap md:flex-nowrap gap-4\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</div></div><div class=\"\"><h2 class=\"text-3xl font-bold mb-4\">Featured Extensions</h2><div class=\"flex flex-wrap md:flex-nowrap gap-4\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -75,7 +147,7 @@ func Home(props HomeProps) templ.Component {
 					return templ_7745c5c3_Err
 				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</div></div></div><section class=\"bg-white py-16 px-4\"><div class=\"max-w-5xl mx-auto text-center\"><h2 class=\"text-3xl font-bold text-gray-800 mb-6\">Why Choose Us?</h2><p class=\"text-lg text-gray-600 mb-10\">Our baby safety gates are designed for a perfect fit, built with premium materials, and easy to install.</p><div class=\"grid md:grid-cols-3 gap-12\"><!-- Precision Fit --><div class=\"flex flex-col items-center\"><div class=\"w-16 h-16 bg-blue-500 text-white flex items-center justify-center text-2xl font-bold rounded-full\">🎯</div><h3 class=\"text-xl font-semibold mt-4\">Precision Fit</h3><p class=\"text-gray-600 text-center mt-2\">Our custom bundles ensure a secure fit for any space.</p><img src=\"https://replicate.delivery/xezq/vypqRy3befoADke06YsloGezMfxuARIffMaA9JxeZp5FiTaYUA/tmp2jickw7t.jpg\" alt=\"Measuring for precision fit\" class=\"mt-4 w-40 h-40 object-cover rounded-lg shadow-md\"></div><!-- High-Quality Materials --><div class=\"flex flex-col items-center\"><div class=\"w-16 h-16 bg-blue-500 text-white flex items-center justify-center text-2xl font-bold rounded-full\">🏆</div><h3 class=\"text-xl font-semibold mt-4\">High-Quality Materials</h3><p class=\"text-gray-600 text-center mt-2\">Made from durable, non-toxic materials for long-lasting safety.</p><img src=\"https://replicate.delivery/xezq/Yl2EHiDeOrTkH6iUEYVfzm4WM8ryDdyUL9siip9P13e4S0woA/tmpuy5bxwcv.jpg\" alt=\"High-quality baby gate\" class=\"mt-4 w-40 h-40 object-cover rounded-lg shadow-md\"></div><!-- Hassle-Free Installation --><div class=\"flex flex-col items-center\"><div class=\"w-16 h-16 bg-blue-500 text-white flex items-center justify-center text-2xl font-bold rounded-full\">⚡</div><h3 class=\"text-xl font-semibold mt-4\">Hassle-Free Installation</h3><p class=\"text-gray-600 text-center mt-2\">Quick setup with no drilling required—safe and sturdy in minutes.</p><img src=\"https://replicate.delivery/xezq/6gHv2eIKpNxWSyITx0id7cclZC94TFNe7mPlPi0ufQUSB0woA/tmphvynxr0s.jpg\" alt=\"Installing baby gate\" class=\"mt-4 w-40 h-40 object-cover rounded-lg shadow-md\"></div></div></div></section><section class=\"container mx-auto py-8 px-4\"><h2 class=\"text-3xl font-bold mb-4\">Our Baby Safety Experts</h2><style>\r\n      .gallery {\r\ndisplay: flex;\r\noverflow: scroll;\r\ngap: 1rem;\r\n      }\r\n    .gallery img {\r\nwidth: 75vw;\r\n    }\r\n    @media (min-width: 500px) {\r\n      .gallery img {\r\nwidth: auto;\r\n      }\r\n      .gallery {\r\noverflow: auto;\r\ndisplay: grid;\r\ngap: 0.1rem;\r\n     grid-template-columns: repeat(auto-fit, minmax(250px, 1fr));\r\n      }\r\n    }\r\n    </style><div class=\"gallery\"><img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/Yl2EHiDeOrTkH6iUEYVfzm4WM8ryDdyUL9siip9P13e4S0woA/tmpuy5bxwcv.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/vypqRy3befoADke06YsloGezMfxuARIffMaA9JxeZp5FiTaYUA/tmp2jickw7t.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/wWXh8ldiPZYUGxUH1QcVFaoEX5OgvVkHT0ZTz4m4FAZPlGGF/tmphwvhvocg.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/6gHv2eIKpNxWSyITx0id7cclZC94TFNe7mPlPi0ufQUSB0woA/tmphvynxr0s.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/uVp3uk9wXfWFEqriJsHF0W9pfPGYdJrBzVpII3peE9qufnhRB/tmp1o0f8osk.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/tAFKyrOdeiwMSKNG7hfflJlOhsxR0OOJmxmHWpHifC0fsPDjC/tmpm9n_r7is.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/kjjoZwI3fLRFT6jCBbnHddypUv37AA65MMemgbQenoAZJ0woA/tmpyha43fdf.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/CnxgzEBdyPbOBNq56TSx7vEKryl9o2U4o5imWNNfqPtrLNMKA/tmpkg1zhnc4.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/DMJnVHfAe8p2pU5d5n8rFsF9vkjk0pNH2v2HgnHgvFLdZaYUA/tmp88dx7jdz.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/7Ch7Ve0iiwQvfEAQud1jWRqtXIBw7yzVc3e4Bnp54K07y0woA/tmpu85hqug1.jpg\" alt=\"baby fitting a babysafety gate\"></div></section><section class=\"bg-gray-100 py-16 px-4\"><div class=\"max-w-5xl mx-auto text-center\"><h2 class=\"text-3xl font-bold text-gray-800 mb-6\">Certified Safety Standards</h2><p class=\"text-lg text-gray-600 mb-10\">Our baby safety gates meet the highest European safety standards to ensure maximum protection for your child.</p><div class=\"grid md:grid-cols-2 gap-8 text-left\"><!-- Safety Certifications --><div class=\"flex flex-col items-center md:items-start\"><img src=\"https://replicate.delivery/xezq/uVp3uk9wXfWFEqriJsHF0W9pfPGYdJrBzVpII3peE9qufnhRB/tmp1o0f8osk.jpg\" alt=\"Safety certification badge\" class=\"w-40 h-40 object-cover rounded-lg shadow-md mb-4\"><h3 class=\"text-xl font-semibold\">Certified to EN 1930:2011 & EN 71</h3><p class=\"text-gray-600 mt-2\">Our safety gates comply with the strictest European safety standards, ensuring durability, reliability, and child safety.</p></div><!-- Rigorous Testing --><div class=\"flex flex-col items-center md:items-start\"><img src=\"https://replicate.delivery/xezq/kjjoZwI3fLRFT6jCBbnHddypUv37AA65MMemgbQenoAZJ0woA/tmpyha43fdf.jpg\" alt=\"Strength test for baby gate\" class=\"w-40 h-40 object-cover rounded-lg shadow-md mb-4\"><h3 class=\"text-xl font-semibold\">Rigorous Strength & Safety Tests</h3><p class=\"text-gray-600 mt-2\">Each gate undergoes extensive testing to ensure it can withstand impacts, prevent climbing, and eliminate risks like finger pinching or choking hazards.</p></div><!-- Child-Safe Materials --><div class=\"flex flex-col items-center md:items-start\"><img src=\"https://replicate.delivery/xezq/Yl2EHiDeOrTkH6iUEYVfzm4WM8ryDdyUL9siip9P13e4S0woA/tmpuy5bxwcv.jpg\" alt=\"Child touching a safety gate\" class=\"w-40 h-40 object-cover rounded-lg shadow-md mb-4\"><h3 class=\"text-xl font-semibold\">Non-Toxic, Child-Safe Materials</h3><p class=\"text-gray-600 mt-2\">Made from materials free of heavy metals and harmful chemicals, ensuring your child’s safety—even if they chew or suck on the gate.</p></div><!-- Secure Design --><div class=\"flex flex-col items-center md:items-start\"><img src=\"https://replicate.delivery/xezq/7Ch7Ve0iiwQvfEAQud1jWRqtXIBw7yzVc3e4Bnp54K07y0woA/tmpu85hqug1.jpg\" alt=\"Properly installed baby gate\" class=\"w-40 h-40 object-cover rounded-lg shadow-md mb-4\"><h3 class=\"text-xl font-semibold\">Stable & Secure Installation</h3><p class=\"text-gray-600 mt-2\">Designed to remain firmly in place, even when pushed or shaken, preventing accidental dislodging.</p></div></div></div></section><section class=\"bg-white py-16 px-4\"><div class=\"max-w-4xl mx-auto text-center\"><h2 class=\"text-3xl font-bold text-gray-800 mb-6\">Frequently Asked Questions</h2><p class=\"text-lg text-gray-600 mb-10\">Find answers to common questions about our baby safety gates.</p><div class=\"space-y-6 text-left\"><!-- Question 1 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">What size baby gate do I need? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Measure the width of your doorway, staircase, or opening. Our gates are adjustable and can be customized for a perfect fit.</p></div><!-- Question 2 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">Are pressure-mounted gates safe for stairs? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Pressure-mounted gates are great for doorways but not recommended for the top of stairs. Use hardware-mounted gates for staircases.</p></div><!-- Question 3 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">Can I install a baby gate without drilling? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Yes! Pressure-mounted gates require no drilling and are ideal for renters. However, for stairs, we recommend hardware-mounted gates for added security.</p></div><!-- Question 4 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">How do I clean and maintain my baby gate? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Wipe down with a damp cloth and mild detergent. Avoid harsh chemicals to keep materials safe for children.</p></div><!-- Question 5 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">What safety certifications do your baby gates have? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Our gates comply with EN 1930:2011 and EN 71, ensuring they meet the strictest safety standards for durability and child safety.</p></div><!-- Question 6 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">Do baby gates work for pets as well? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Yes! Our gates can be used for both babies and pets. We also offer pet-specific gates with added durability.</p></div><!-- Question 7 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">When should I stop using a baby gate? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Baby gates are generally used until a child is around 2 years old or tall enough to climb over them. Always follow manufacturer guidelines.</p></div></div></div></section><script>\r\n            document.querySelectorAll(\".faq-toggle\").forEach((button) => {\r\n                button.addEventListener(\"click\", () => {\r\n                    const answer = button.nextElementSibling;\r\n                    answer.classList.toggle(\"hidden\");\r\n                    button.querySelector(\"span\").textContent = answer.classList.contains(\r\n                        \"hidden\"\r\n                        )\r\n                    ? \"+\"\r\n                    : \"−\";\r\n                    });\r\n                });\r\n    </script> <!-- Structured Data for SEO --> <script type=\"application/ld+json\">\r\n      {\r\n        \"@context\": \"https://schema.org\",\r\n          \"@type\": \"FAQPage\",\r\n          \"mainEntity\": [\r\n          {\r\n            \"@type\": \"Question\",\r\n            \"name\": \"What size baby gate do I need?\",\r\n            \"acceptedAnswer\": {\r\n              \"@type\": \"Answer\",\r\n              \"text\": \"Measure the width of your doorway, staircase, or opening. Our gates are adjustable and can be customized for a perfect fit.\"\r\n            }\r\n          },\r\n          {\r\n            \"@type\": \"Question\",\r\n            \"name\": \"Are pressure-mounted gates safe for stairs?\",\r\n            \"acceptedAnswer\": {\r\n              \"@type\": \"Answer\",\r\n              \"text\": \"Pressure-mounted gates are great for doorways but not recommended for the top of stairs. Use hardware-mounted gates for staircases.\"\r\n            }\r\n          },\r\n          {\r\n            \"@type\": \"Question\",\r\n            \"name\": \"Can I install a baby gate without drilling?\",\r\n            \"acceptedAnswer\": {\r\n              \"@type\": \"Answer\",\r\n              \"text\": \"Yes! Pressure-mounted gates require no drilling and are ideal for renters. However, for stairs, we recommend hardware-mounted gates for added security.\"\r\n            }\r\n          },\r\n          {\r\n            \"@type\": \"Question\",\r\n            \"name\": \"How do I clean and maintain my baby gate?\",\r\n            \"acceptedAnswer\": {\r\n              \"@type\": \"Answer\",\r\n              \"text\": \"Wipe down with a damp cloth and mild detergent. Avoid harsh chemicals to keep materials safe for children.\"\r\n            }\r\n          },\r\n          {\r\n            \"@type\": \"Question\",\r\n            \"name\": \"What safety certifications do your baby gates have?\",\r\n            \"acceptedAnswer\": {\r\n              \"@type\": \"Answer\",\r\n              \"text\": \"Our gates comply with EN 1930:2011 and EN 71, ensuring they meet the strictest safety standards for durability and child safety.\"\r\n            }\r\n          },\r\n          {\r\n            \"@type\": \"Question\",\r\n            \"name\": \"Do baby gates work for pets as well?\",\r\n            \"acceptedAnswer\": {\r\n              \"@type\": \"Answer\",\r\n              \"text\": \"Yes! Our gates can be used for both babies and pets. We also offer pet-specific gates with added durability.\"\r\n            }\r\n          },\r\n          {\r\n            \"@type\": \"Question\",\r\n            \"name\": \"When should I stop using a baby gate?\",\r\n            \"acceptedAnswer\": {\r\n              \"@type\": \"Answer\",\r\n              \"text\": \"Baby gates are generally used until a child is around 2 years old or tall enough to climb over them. Always follow manufacturer guidelines.\"\r\n            }\r\n          }\r\n        ]\r\n      }\r\n    </script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</div></div></div><section class=\"bg-white py-16 px-4\"><div class=\"max-w-5xl mx-auto text-center\"><h2 class=\"text-3xl font-bold text-gray-800 mb-6\">Why Choose Us?</h2><p class=\"text-lg text-gray-600 mb-10\">Our baby safety gates are designed for a perfect fit, built with premium materials, and easy to install.</p><div class=\"grid md:grid-cols-3 gap-12\"><!-- Precision Fit --><div class=\"flex flex-col items-center\"><div class=\"w-16 h-16 bg-blue-500 text-white flex items-center justify-center text-2xl font-bold rounded-full\">🎯</div><h3 class=\"text-xl font-semibold mt-4\">Precision Fit</h3><p class=\"text-gray-600 text-center mt-2\">Our custom bundles ensure a secure fit for any space.</p><img src=\"https://replicate.delivery/xezq/vypqRy3befoADke06YsloGezMfxuARIffMaA9JxeZp5FiTaYUA/tmp2jickw7t.jpg\" alt=\"Measuring for precision fit\" class=\"mt-4 w-40 h-40 object-cover rounded-lg shadow-md\"></div><!-- High-Quality Materials --><div class=\"flex flex-col items-center\"><div class=\"w-16 h-16 bg-blue-500 text-white flex items-center justify-center text-2xl font-bold rounded-full\">🏆</div><h3 class=\"text-xl font-semibold mt-4\">High-Quality Materials</h3><p class=\"text-gray-600 text-center mt-2\">Made from durable, non-toxic materials for long-lasting safety.</p><img src=\"https://replicate.delivery/xezq/Yl2EHiDeOrTkH6iUEYVfzm4WM8ryDdyUL9siip9P13e4S0woA/tmpuy5bxwcv.jpg\" alt=\"High-quality baby gate\" class=\"mt-4 w-40 h-40 object-cover rounded-lg shadow-md\"></div><!-- Hassle-Free Installation --><div class=\"flex flex-col items-center\"><div class=\"w-16 h-16 bg-blue-500 text-white flex items-center justify-center text-2xl font-bold rounded-full\">⚡</div><h3 class=\"text-xl font-semibold mt-4\">Hassle-Free Installation</h3><p class=\"text-gray-600 text-center mt-2\">Quick setup with no drilling required—safe and sturdy in minutes.</p><img src=\"https://replicate.delivery/xezq/6gHv2eIKpNxWSyITx0id7cclZC94TFNe7mPlPi0ufQUSB0woA/tmphvynxr0s.jpg\" alt=\"Installing baby gate\" class=\"mt-4 w-40 h-40 object-cover rounded-lg shadow-md\"></div></div></div></section><section class=\"container mx-auto py-8 px-4\"><h2 class=\"text-3xl font-bold mb-4\">Our Baby Safety Experts</h2><style>\n      .gallery {\ndisplay: flex;\noverflow: scroll;\ngap: 1rem;\n      }\n    .gallery img {\nwidth: 75vw;\n    }\n    @media (min-width: 500px) {\n      .gallery img {\nwidth: auto;\n      }\n      .gallery {\noverflow: auto;\ndisplay: grid;\ngap: 0.1rem;\n     grid-template-columns: repeat(auto-fit, minmax(250px, 1fr));\n      }\n    }\n    </style><div class=\"gallery\"><img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/Yl2EHiDeOrTkH6iUEYVfzm4WM8ryDdyUL9siip9P13e4S0woA/tmpuy5bxwcv.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/vypqRy3befoADke06YsloGezMfxuARIffMaA9JxeZp5FiTaYUA/tmp2jickw7t.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/wWXh8ldiPZYUGxUH1QcVFaoEX5OgvVkHT0ZTz4m4FAZPlGGF/tmphwvhvocg.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/6gHv2eIKpNxWSyITx0id7cclZC94TFNe7mPlPi0ufQUSB0woA/tmphvynxr0s.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/uVp3uk9wXfWFEqriJsHF0W9pfPGYdJrBzVpII3peE9qufnhRB/tmp1o0f8osk.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/tAFKyrOdeiwMSKNG7hfflJlOhsxR0OOJmxmHWpHifC0fsPDjC/tmpm9n_r7is.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/kjjoZwI3fLRFT6jCBbnHddypUv37AA65MMemgbQenoAZJ0woA/tmpyha43fdf.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/CnxgzEBdyPbOBNq56TSx7vEKryl9o2U4o5imWNNfqPtrLNMKA/tmpkg1zhnc4.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/DMJnVHfAe8p2pU5d5n8rFsF9vkjk0pNH2v2HgnHgvFLdZaYUA/tmp88dx7jdz.jpg\" alt=\"baby fitting a babysafety gate\"> <img class=\"aspect-square object-cover object-center\" src=\"https://replicate.delivery/xezq/7Ch7Ve0iiwQvfEAQud1jWRqtXIBw7yzVc3e4Bnp54K07y0woA/tmpu85hqug1.jpg\" alt=\"baby fitting a babysafety gate\"></div></section><section class=\"bg-gray-100 py-16 px-4\"><div class=\"max-w-5xl mx-auto text-center\"><h2 class=\"text-3xl font-bold text-gray-800 mb-6\">Certified Safety Standards</h2><p class=\"text-lg text-gray-600 mb-10\">Our baby safety gates meet the highest European safety standards to ensure maximum protection for your child.</p><div class=\"grid md:grid-cols-2 gap-8 text-left\"><!-- Safety Certifications --><div class=\"flex flex-col items-center md:items-start\"><img src=\"https://replicate.delivery/xezq/uVp3uk9wXfWFEqriJsHF0W9pfPGYdJrBzVpII3peE9qufnhRB/tmp1o0f8osk.jpg\" alt=\"Safety certification badge\" class=\"w-40 h-40 object-cover rounded-lg shadow-md mb-4\"><h3 class=\"text-xl font-semibold\">Certified to EN 1930:2011 & EN 71</h3><p class=\"text-gray-600 mt-2\">Our safety gates comply with the strictest European safety standards, ensuring durability, reliability, and child safety.</p></div><!-- Rigorous Testing --><div class=\"flex flex-col items-center md:items-start\"><img src=\"https://replicate.delivery/xezq/kjjoZwI3fLRFT6jCBbnHddypUv37AA65MMemgbQenoAZJ0woA/tmpyha43fdf.jpg\" alt=\"Strength test for baby gate\" class=\"w-40 h-40 object-cover rounded-lg shadow-md mb-4\"><h3 class=\"text-xl font-semibold\">Rigorous Strength & Safety Tests</h3><p class=\"text-gray-600 mt-2\">Each gate undergoes extensive testing to ensure it can withstand impacts, prevent climbing, and eliminate risks like finger pinching or choking hazards.</p></div><!-- Child-Safe Materials --><div class=\"flex flex-col items-center md:items-start\"><img src=\"https://replicate.delivery/xezq/Yl2EHiDeOrTkH6iUEYVfzm4WM8ryDdyUL9siip9P13e4S0woA/tmpuy5bxwcv.jpg\" alt=\"Child touching a safety gate\" class=\"w-40 h-40 object-cover rounded-lg shadow-md mb-4\"><h3 class=\"text-xl font-semibold\">Non-Toxic, Child-Safe Materials</h3><p class=\"text-gray-600 mt-2\">Made from materials free of heavy metals and harmful chemicals, ensuring your child’s safety—even if they chew or suck on the gate.</p></div><!-- Secure Design --><div class=\"flex flex-col items-center md:items-start\"><img src=\"https://replicate.delivery/xezq/7Ch7Ve0iiwQvfEAQud1jWRqtXIBw7yzVc3e4Bnp54K07y0woA/tmpu85hqug1.jpg\" alt=\"Properly installed baby gate\" class=\"w-40 h-40 object-cover rounded-lg shadow-md mb-4\"><h3 class=\"text-xl font-semibold\">Stable & Secure Installation</h3><p class=\"text-gray-600 mt-2\">Designed to remain firmly in place, even when pushed or shaken, preventing accidental dislodging.</p></div></div></div></section><section class=\"bg-white py-16 px-4\"><div class=\"max-w-4xl mx-auto text-center\"><h2 class=\"text-3xl font-bold text-gray-800 mb-6\">Frequently Asked Questions</h2><p class=\"text-lg text-gray-600 mb-10\">Find answers to common questions about our baby safety gates.</p><div class=\"space-y-6 text-left\"><!-- Question 1 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">What size baby gate do I need? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Measure the width of your doorway, staircase, or opening. Our gates are adjustable and can be customized for a perfect fit.</p></div><!-- Question 2 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">Are pressure-mounted gates safe for stairs? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Pressure-mounted gates are great for doorways but not recommended for the top of stairs. Use hardware-mounted gates for staircases.</p></div><!-- Question 3 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">Can I install a baby gate without drilling? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Yes! Pressure-mounted gates require no drilling and are ideal for renters. However, for stairs, we recommend hardware-mounted gates for added security.</p></div><!-- Question 4 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">How do I clean and maintain my baby gate? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Wipe down with a damp cloth and mild detergent. Avoid harsh chemicals to keep materials safe for children.</p></div><!-- Question 5 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">What safety certifications do your baby gates have? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Our gates comply with EN 1930:2011 and EN 71, ensuring they meet the strictest safety standards for durability and child safety.</p></div><!-- Question 6 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">Do baby gates work for pets as well? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Yes! Our gates can be used for both babies and pets. We also offer pet-specific gates with added durability.</p></div><!-- Question 7 --><div class=\"border-b pb-4\"><button class=\"w-full flex justify-between items-center text-lg font-semibold text-gray-800 focus:outline-none faq-toggle\">When should I stop using a baby gate? <span class=\"text-blue-500\">+</span></button><p class=\"text-gray-600 mt-2 hidden\">Baby gates are generally used until a child is around 2 years old or tall enough to climb over them. Always follow manufacturer guidelines.</p></div></div></div></section><script>\n            document.querySelectorAll(\".faq-toggle\").forEach((button) => {\n                button.addEventListener(\"click\", () => {\n                    const answer = button.nextElementSibling;\n                    answer.classList.toggle(\"hidden\");\n                    button.querySelector(\"span\").textContent = answer.classList.contains(\n                        \"hidden\"\n                        )\n                    ? \"+\"\n                    : \"−\";\n                    });\n                });\n    </script> <!-- Structured Data for SEO --> <script type=\"application/ld+json\">\n      {\n        \"@context\": \"https://schema.org\",\n          \"@type\": \"FAQPage\",\n          \"mainEntity\": [\n          {\n            \"@type\": \"Question\",\n            \"name\": \"What size baby gate do I need?\",\n            \"acceptedAnswer\": {\n              \"@type\": \"Answer\",\n              \"text\": \"Measure the width of your doorway, staircase, or opening. Our gates are adjustable and can be customized for a perfect fit.\"\n            }\n          },\n          {\n            \"@type\": \"Question\",\n            \"name\": \"Are pressure-mounted gates safe for stairs?\",\n            \"acceptedAnswer\": {\n              \"@type\": \"Answer\",\n              \"text\": \"Pressure-mounted gates are great for doorways but not recommended for the top of stairs. Use hardware-mounted gates for staircases.\"\n            }\n          },\n          {\n            \"@type\": \"Question\",\n            \"name\": \"Can I install a baby gate without drilling?\",\n            \"acceptedAnswer\": {\n              \"@type\": \"Answer\",\n              \"text\": \"Yes! Pressure-mounted gates require no drilling and are ideal for renters. However, for stairs, we recommend hardware-mounted gates for added security.\"\n            }\n          },\n          {\n            \"@type\": \"Question\",\n            \"name\": \"How do I clean and maintain my baby gate?\",\n            \"acceptedAnswer\": {\n              \"@type\": \"Answer\",\n              \"text\": \"Wipe down with a damp cloth and mild detergent. Avoid harsh chemicals to keep materials safe for children.\"\n            }\n          },\n          {\n            \"@type\": \"Question\",\n            \"name\": \"What safety certifications do your baby gates have?\",\n            \"acceptedAnswer\": {\n              \"@type\": \"Answer\",\n              \"text\": \"Our gates comply with EN 1930:2011 and EN 71, ensuring they meet the strictest safety standards for durability and child safety.\"\n            }\n          },\n          {\n            \"@type\": \"Question\",\n            \"name\": \"Do baby gates work for pets as well?\",\n            \"acceptedAnswer\": {\n              \"@type\": \"Answer\",\n              \"text\": \"Yes! Our gates can be used for both babies and pets. We also offer pet-specific gates with added durability.\"\n            }\n          },\n          {\n            \"@type\": \"Question\",\n            \"name\": \"When should I stop using a baby gate?\",\n            \"acceptedAnswer\": {\n              \"@type\": \"Answer\",\n              \"text\": \"Baby gates are generally used until a child is around 2 years old or tall enough to climb over them. Always follow manufacturer guidelines.\"\n            }\n          }\n        ]\n      }\n    </script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}